@@ -32,8 +32,9 @@ func main() {
 	zerolog := config.NewZeroLog()
 	elasticsearch := config.NewElasticClient(koanf, &zerolog)
 	kafkaProducer := config.NewKafkaProducer(koanf, &zerolog)
-	kafkaConsumer := config.NewKafkaConsumer(koanf, &zerolog)
 	db := config.NewDB(koanf, &zerolog)
+	cache := config.NewCache(koanf, &zerolog)
+	storage := config.NewStorage(koanf, &zerolog)
 	validator := config.NewValidator()
 	userServiceUrl := koanf.String("USER_SERVICE_URL")
 
@@ -42,8 +43,9 @@ func main() {
 		Router:         router,
 		DB:             db,
 		ElasticSearch:  elasticsearch,
+		Cache:          cache,
+		Storage:        storage,
 		KafkaProducer:  kafkaProducer,
-		KafkaConsumer:  kafkaConsumer,
 		Config:         koanf,
 		Validate:       validator,
 		Log:            &zerolog,
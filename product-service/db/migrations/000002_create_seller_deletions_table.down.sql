@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS seller_deletion_products;
+DROP TABLE IF EXISTS seller_deletions;
\ No newline at end of file
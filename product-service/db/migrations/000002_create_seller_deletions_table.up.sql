@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS seller_deletions(
+    saga_id char(36) PRIMARY KEY,
+    seller_id char(36) NOT NULL,
+    status varchar(12) NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS seller_deletion_products(
+    saga_id char(36) NOT NULL,
+    product_id int NOT NULL,
+    previous_status varchar(9),
+    PRIMARY KEY (saga_id, product_id),
+    FOREIGN KEY (saga_id) REFERENCES seller_deletions(saga_id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS seller_stock_thresholds;
+
+ALTER TABLE products REPLICA IDENTITY DEFAULT;
\ No newline at end of file
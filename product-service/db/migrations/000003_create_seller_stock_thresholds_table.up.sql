@@ -0,0 +1,7 @@
+ALTER TABLE products REPLICA IDENTITY FULL;
+
+CREATE TABLE IF NOT EXISTS seller_stock_thresholds(
+    seller_id char(36) PRIMARY KEY,
+    low_stock_threshold int NOT NULL,
+    updated_at timestamp NOT NULL
+);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS seller_stats_products;
+DROP TABLE IF EXISTS seller_stats;
\ No newline at end of file
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS seller_stats(
+    seller_id char(36) PRIMARY KEY,
+    active_product int NOT NULL DEFAULT 0,
+    sold_out_product int NOT NULL DEFAULT 0,
+    total_product int NOT NULL DEFAULT 0,
+    inventory_value decimal(16,2) NOT NULL DEFAULT 0,
+    last_updated_at timestamp NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS seller_stats_products(
+    product_id int PRIMARY KEY,
+    seller_id char(36) NOT NULL,
+    quantity int NOT NULL,
+    price decimal(10,2) NOT NULL,
+    status varchar(9) NOT NULL DEFAULT '',
+    source_lsn bigint NOT NULL
+);
\ No newline at end of file
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS webhook_endpoints(
+    id char(36) PRIMARY KEY,
+    user_id char(36) NOT NULL,
+    url varchar(2048) NOT NULL,
+    secret char(64) NOT NULL,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_endpoints_user_id_idx ON webhook_endpoints(user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_endpoint_events(
+    endpoint_id char(36) NOT NULL,
+    event_type varchar(32) NOT NULL,
+    PRIMARY KEY (endpoint_id, event_type),
+    FOREIGN KEY (endpoint_id) REFERENCES webhook_endpoints(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries(
+    id char(36) PRIMARY KEY,
+    endpoint_id char(36) NOT NULL,
+    event_type varchar(32) NOT NULL,
+    payload text NOT NULL,
+    status varchar(12) NOT NULL DEFAULT 'Pending',
+    attempts int NOT NULL DEFAULT 0,
+    next_attempt_at timestamp NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    FOREIGN KEY (endpoint_id) REFERENCES webhook_endpoints(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries(next_attempt_at) WHERE status = 'Pending';
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts(
+    id serial PRIMARY KEY,
+    delivery_id char(36) NOT NULL,
+    attempt int NOT NULL,
+    status_code int NOT NULL,
+    error text NOT NULL DEFAULT '',
+    duration_ms int NOT NULL,
+    created_at timestamp NOT NULL,
+    FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries(id) ON DELETE CASCADE
+);
\ No newline at end of file
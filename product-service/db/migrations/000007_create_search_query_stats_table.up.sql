@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS search_query_stats(
+    bucket timestamp NOT NULL,
+    query varchar(200) NOT NULL,
+    searches bigint NOT NULL DEFAULT 0,
+    zero_results bigint NOT NULL DEFAULT 0,
+    total_results bigint NOT NULL DEFAULT 0,
+    total_latency_ms bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (bucket, query)
+);
\ No newline at end of file
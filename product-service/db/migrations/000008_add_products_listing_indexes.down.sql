@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS products_seller_id_created_at_id_idx;
+DROP INDEX IF EXISTS products_name_id_idx;
+DROP INDEX IF EXISTS products_price_id_idx;
+DROP INDEX IF EXISTS products_created_at_id_idx;
\ No newline at end of file
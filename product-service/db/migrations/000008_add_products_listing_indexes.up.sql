@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS products_created_at_id_idx ON products (created_at, id);
+CREATE INDEX IF NOT EXISTS products_price_id_idx ON products (price, id);
+CREATE INDEX IF NOT EXISTS products_name_id_idx ON products (name, id);
+CREATE INDEX IF NOT EXISTS products_seller_id_created_at_id_idx ON products (seller_id, created_at, id);
\ No newline at end of file
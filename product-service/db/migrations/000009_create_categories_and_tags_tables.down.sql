@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS product_tags;
+
+DROP TABLE IF EXISTS tags;
+
+DROP INDEX IF EXISTS products_category_id_idx;
+
+ALTER TABLE products DROP COLUMN IF EXISTS category_id;
+
+DROP TABLE IF EXISTS categories;
\ No newline at end of file
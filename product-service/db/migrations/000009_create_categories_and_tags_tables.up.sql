@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS categories(
+    id serial PRIMARY KEY,
+    parent_id int,
+    name varchar(50) NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    FOREIGN KEY (parent_id) REFERENCES categories(id)
+);
+
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories(parent_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS categories_parent_id_name_idx ON categories(COALESCE(parent_id, 0), lower(name));
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS category_id int REFERENCES categories(id);
+
+CREATE INDEX IF NOT EXISTS products_category_id_idx ON products(category_id);
+
+CREATE TABLE IF NOT EXISTS tags(
+    id serial PRIMARY KEY,
+    name varchar(30) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS product_tags(
+    product_id int NOT NULL,
+    tag_id int NOT NULL,
+    PRIMARY KEY (product_id, tag_id),
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS product_tags_tag_id_idx ON product_tags(tag_id);
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS product_images(
+    id char(36) PRIMARY KEY,
+    product_id int NOT NULL,
+    storage_key varchar(255) NOT NULL,
+    url varchar(2048) NOT NULL,
+    content_type varchar(32) NOT NULL,
+    size_bytes int NOT NULL,
+    position int NOT NULL,
+    created_at timestamp NOT NULL,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS product_images_product_id_position_idx ON product_images(product_id, position);
\ No newline at end of file
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS product_variants(
+    id serial PRIMARY KEY,
+    product_id int NOT NULL,
+    sku varchar(64) NOT NULL,
+    size varchar(4) NOT NULL,
+    attributes jsonb NOT NULL DEFAULT '{}',
+    price decimal(10,2) NOT NULL,
+    weight int NOT NULL,
+    quantity int NOT NULL CHECK (quantity >= 0),
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS product_variants_sku_idx ON product_variants(lower(sku));
+
+CREATE UNIQUE INDEX IF NOT EXISTS product_variants_options_idx ON product_variants(product_id, size, attributes);
\ No newline at end of file
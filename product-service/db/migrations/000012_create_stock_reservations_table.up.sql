@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS stock_reservations(
+    id varchar(64) PRIMARY KEY,
+    product_id int NOT NULL,
+    variant_id int,
+    buyer_id char(36) NOT NULL,
+    quantity int NOT NULL CHECK (quantity > 0),
+    status varchar(9) NOT NULL,
+    expires_at timestamp NOT NULL,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    FOREIGN KEY (variant_id) REFERENCES product_variants(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS stock_reservations_expires_at_idx ON stock_reservations(expires_at) WHERE status = 'Reserved';
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS product_status_history;
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_status_check;
+
+ALTER TABLE products ALTER COLUMN status DROP NOT NULL;
\ No newline at end of file
@@ -0,0 +1,24 @@
+UPDATE products SET status = 'Ready' WHERE status IS NULL OR status NOT IN ('Draft', 'Ready', 'SoldOut', 'Archived');
+
+UPDATE products SET status = 'SoldOut' WHERE status = 'Ready' AND quantity <= 0;
+
+UPDATE seller_deletion_products SET previous_status = 'Ready' WHERE previous_status IS NULL OR previous_status NOT IN ('Draft', 'Ready', 'SoldOut', 'Archived');
+
+ALTER TABLE products ALTER COLUMN status SET NOT NULL;
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_status_check;
+
+ALTER TABLE products ADD CONSTRAINT products_status_check CHECK (status IN ('Draft', 'Ready', 'SoldOut', 'Archived'));
+
+CREATE TABLE IF NOT EXISTS product_status_history(
+    id bigserial PRIMARY KEY,
+    product_id int NOT NULL,
+    from_status varchar(9) NOT NULL,
+    to_status varchar(9) NOT NULL,
+    reason varchar(16) NOT NULL,
+    actor_id char(36),
+    created_at timestamp NOT NULL,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS product_status_history_product_id_idx ON product_status_history(product_id, created_at);
\ No newline at end of file
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS product_history(
+    id bigserial PRIMARY KEY,
+    product_id int NOT NULL,
+    operation varchar(10) NOT NULL,
+    snapshot jsonb NOT NULL,
+    changes jsonb NOT NULL DEFAULT '{}',
+    source_lsn bigint NOT NULL,
+    changed_at timestamp NOT NULL
+);
+
+-- history outlives the product, so there is no foreign key to products
+CREATE UNIQUE INDEX IF NOT EXISTS product_history_lsn_idx ON product_history(product_id, source_lsn);
+CREATE INDEX IF NOT EXISTS product_history_changed_at_idx ON product_history(product_id, changed_at);
+
+-- baseline for products that existed before history was recorded, in the
+-- same shape as the change events
+INSERT INTO product_history (product_id,operation,snapshot,source_lsn,changed_at)
+SELECT id, 'snapshot', jsonb_build_object(
+    'id', id,
+    'seller_id', seller_id,
+    'name', name,
+    'product_picture', product_picture,
+    'quantity', quantity,
+    'price', price,
+    'weight', weight,
+    'size', size,
+    'status', status,
+    'description', description,
+    'category_id', category_id,
+    'created_at', (extract(epoch FROM created_at) * 1000000)::bigint,
+    'updated_at', (extract(epoch FROM updated_at) * 1000000)::bigint,
+    'deleted_at', (extract(epoch FROM deleted_at) * 1000000)::bigint
+), 0, updated_at
+FROM products
+ON CONFLICT (product_id, source_lsn) DO NOTHING;
\ No newline at end of file
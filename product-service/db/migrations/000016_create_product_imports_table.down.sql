@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS product_import_errors;
+
+DROP TABLE IF EXISTS product_imports;
\ No newline at end of file
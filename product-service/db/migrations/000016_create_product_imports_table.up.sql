@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS product_imports(
+    id char(36) PRIMARY KEY,
+    seller_id char(36) NOT NULL,
+    format varchar(6) NOT NULL,
+    status varchar(10) NOT NULL DEFAULT 'Pending',
+    data bytea,
+    total_rows int NOT NULL,
+    processed_rows int NOT NULL DEFAULT 0,
+    imported_rows int NOT NULL DEFAULT 0,
+    failed_rows int NOT NULL DEFAULT 0,
+    attempts int NOT NULL DEFAULT 0,
+    error text NOT NULL DEFAULT '',
+    lease_until timestamp,
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL,
+    finished_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS product_imports_queue_idx ON product_imports(created_at) WHERE status IN ('Pending', 'Running');
+
+CREATE TABLE IF NOT EXISTS product_import_errors(
+    id bigserial PRIMARY KEY,
+    import_id char(36) NOT NULL REFERENCES product_imports(id) ON DELETE CASCADE,
+    row_number int NOT NULL,
+    error text NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS product_import_errors_import_idx ON product_import_errors(import_id, row_number);
\ No newline at end of file
@@ -0,0 +1 @@
+-- the backfilled rows are projection state, the change stream keeps them up to date
\ No newline at end of file
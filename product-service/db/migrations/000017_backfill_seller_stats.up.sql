@@ -0,0 +1,15 @@
+INSERT INTO seller_stats_products (product_id,seller_id,quantity,price,status,source_lsn)
+SELECT id, seller_id, quantity, price, CASE WHEN deleted_at IS NOT NULL THEN 'Deleted' ELSE status END, 0
+FROM products
+ON CONFLICT (product_id) DO NOTHING;
+
+INSERT INTO seller_stats (seller_id,active_product,sold_out_product,total_product,inventory_value,last_updated_at)
+SELECT seller_id,
+    count(*) FILTER (WHERE status NOT IN ('Deleted', 'Archived') AND quantity > 0),
+    count(*) FILTER (WHERE status NOT IN ('Deleted', 'Archived') AND quantity <= 0),
+    count(*) FILTER (WHERE status <> 'Deleted'),
+    COALESCE(sum(quantity * price) FILTER (WHERE status NOT IN ('Deleted', 'Archived')), 0),
+    now()
+FROM seller_stats_products
+GROUP BY seller_id
+ON CONFLICT (seller_id) DO UPDATE SET active_product = EXCLUDED.active_product, sold_out_product = EXCLUDED.sold_out_product, total_product = EXCLUDED.total_product, inventory_value = EXCLUDED.inventory_value, last_updated_at = EXCLUDED.last_updated_at;
\ No newline at end of file
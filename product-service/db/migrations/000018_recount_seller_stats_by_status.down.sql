@@ -0,0 +1 @@
+-- the recount only reclassifies projection state, there is nothing to undo
\ No newline at end of file
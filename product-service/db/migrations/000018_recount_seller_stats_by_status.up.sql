@@ -0,0 +1,11 @@
+UPDATE seller_stats SET active_product = recount.active_product, sold_out_product = recount.sold_out_product, total_product = recount.total_product, inventory_value = recount.inventory_value
+FROM (
+    SELECT seller_id,
+        count(*) FILTER (WHERE status = 'Ready') AS active_product,
+        count(*) FILTER (WHERE status = 'SoldOut') AS sold_out_product,
+        count(*) FILTER (WHERE status <> 'Deleted') AS total_product,
+        COALESCE(sum(quantity * price) FILTER (WHERE status IN ('Ready', 'SoldOut')), 0) AS inventory_value
+    FROM seller_stats_products
+    GROUP BY seller_id
+) AS recount
+WHERE seller_stats.seller_id = recount.seller_id;
\ No newline at end of file
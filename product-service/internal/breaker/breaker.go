@@ -0,0 +1,79 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half-open"
+)
+
+// Breaker stops calls to a failing dependency. After Threshold consecutive
+// failures it opens for Cooldown, then lets a single trial call through and
+// closes again once that call succeeds.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+	mutex     sync.Mutex
+	state     string
+	failures  int
+	openedAt  time.Time
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		Threshold: threshold,
+		Cooldown:  cooldown,
+		state:     StateClosed,
+	}
+}
+
+func (breaker *Breaker) Allow() bool {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	switch breaker.state {
+	case StateOpen:
+		if time.Since(breaker.openedAt) < breaker.Cooldown {
+			return false
+		}
+
+		breaker.state = StateHalfOpen
+		return true
+	case StateHalfOpen:
+		// the trial call is still running
+		return false
+	default:
+		return true
+	}
+}
+
+func (breaker *Breaker) Success() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	breaker.state = StateClosed
+	breaker.failures = 0
+}
+
+func (breaker *Breaker) Failure() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	breaker.failures++
+
+	if breaker.state == StateHalfOpen || breaker.failures >= breaker.Threshold {
+		breaker.state = StateOpen
+		breaker.openedAt = time.Now()
+	}
+}
+
+func (breaker *Breaker) State() string {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	return breaker.state
+}
@@ -0,0 +1,26 @@
+package cache
+
+import (
+	"context"
+	"expvar"
+	"time"
+)
+
+// Cache is the storage behind read-through lookups. Backends treat their own
+// failures as misses so a cache outage only costs latency.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+	Delete(ctx context.Context, keys ...string)
+}
+
+// Metrics are published under "cache" on /debug/vars.
+var Metrics = expvar.NewMap("cache")
+
+func recordHit(hit bool) {
+	if hit {
+		Metrics.Add("hits", 1)
+	} else {
+		Metrics.Add("misses", 1)
+	}
+}
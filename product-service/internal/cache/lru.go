@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+type LRUCache struct {
+	capacity int
+	mutex    sync.Mutex
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (cache *LRUCache) Get(_ context.Context, key string) ([]byte, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		recordHit(false)
+		return nil, false
+	}
+
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		cache.removeElement(element)
+		recordHit(false)
+		return nil, false
+	}
+
+	cache.order.MoveToFront(element)
+	recordHit(true)
+
+	return entry.value, true
+}
+
+func (cache *LRUCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+
+	if element, ok := cache.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	element := cache.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	cache.entries[key] = element
+
+	for cache.order.Len() > cache.capacity {
+		cache.removeElement(cache.order.Back())
+		Metrics.Add("evictions", 1)
+	}
+}
+
+func (cache *LRUCache) Delete(_ context.Context, keys ...string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for _, key := range keys {
+		if element, ok := cache.entries[key]; ok {
+			cache.removeElement(element)
+		}
+	}
+}
+
+func (cache *LRUCache) removeElement(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.entries, element.Value.(*lruEntry).key)
+}
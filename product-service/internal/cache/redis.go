@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisCache talks the Redis protocol (RESP2) directly, so any compatible
+// server such as Redis, Valkey or KeyDB can back the product cache.
+type RedisCache struct {
+	Address  string
+	Password string
+	Timeout  time.Duration
+	Log      *zerolog.Logger
+	pool     chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func NewRedisCache(address string, password string, poolSize int, timeout time.Duration, log *zerolog.Logger) *RedisCache {
+	return &RedisCache{
+		Address:  address,
+		Password: password,
+		Timeout:  timeout,
+		Log:      log,
+		pool:     make(chan *redisConn, poolSize),
+	}
+}
+
+func (cache *RedisCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	reply, err := cache.do(ctx, "GET", key)
+	if err != nil {
+		cache.Log.Warn().Err(err).Msg("redis cache get failed")
+		recordHit(false)
+		return nil, false
+	}
+
+	value, ok := reply.([]byte)
+	recordHit(ok)
+
+	return value, ok
+}
+
+func (cache *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	_, err := cache.do(ctx, "SET", key, string(value), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		cache.Log.Warn().Err(err).Msg("redis cache set failed")
+	}
+}
+
+func (cache *RedisCache) Delete(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	_, err := cache.do(ctx, append([]string{"DEL"}, keys...)...)
+	if err != nil {
+		cache.Log.Warn().Err(err).Msg("redis cache delete failed")
+	}
+}
+
+func (cache *RedisCache) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := cache.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(cache.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	conn.conn.SetDeadline(deadline)
+
+	reply, err := conn.command(args...)
+	if err != nil {
+		conn.conn.Close()
+		return nil, err
+	}
+
+	cache.release(conn)
+
+	return reply, nil
+}
+
+func (cache *RedisCache) acquire() (*redisConn, error) {
+	select {
+	case conn := <-cache.pool:
+		return conn, nil
+	default:
+	}
+
+	netConn, err := net.DialTimeout("tcp", cache.Address, cache.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if cache.Password != "" {
+		netConn.SetDeadline(time.Now().Add(cache.Timeout))
+
+		_, err = conn.command("AUTH", cache.Password)
+		if err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+func (cache *RedisCache) release(conn *redisConn) {
+	select {
+	case cache.pool <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+func (conn *redisConn) command(args ...string) (interface{}, error) {
+	request := fmt.Sprintf("*%d\r\n", len(args))
+	for _, arg := range args {
+		request += fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	_, err := conn.conn.Write([]byte(request))
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.readReply()
+}
+
+func (conn *redisConn) readReply() (interface{}, error) {
+	line, err := conn.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 3 {
+		return nil, errors.New("malformed redis reply")
+	}
+
+	body := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, errors.New(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+
+		if size < 0 {
+			return nil, nil
+		}
+
+		value := make([]byte, size+2)
+		_, err = io.ReadFull(conn.reader, value)
+		if err != nil {
+			return nil, err
+		}
+
+		return value[:size], nil
+	case '*':
+		size, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+
+		if size < 0 {
+			return nil, nil
+		}
+
+		values := make([]interface{}, size)
+		for i := range values {
+			values[i], err = conn.readReply()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return values, nil
+	default:
+		return nil, errors.New("unknown redis reply type")
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"database/sql"
 	"github.com/IBM/sarama"
 	"github.com/elastic/go-elasticsearch/v8"
@@ -8,10 +9,13 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/knadh/koanf/v2"
 	"github.com/rs/zerolog"
+	"gocdc/internal/cache"
 	"gocdc/internal/delivery/http"
 	"gocdc/internal/delivery/http/middleware"
 	"gocdc/internal/delivery/http/route"
+	"gocdc/internal/delivery/messaging"
 	"gocdc/internal/repository"
+	"gocdc/internal/storage"
 	"gocdc/internal/usecase"
 )
 
@@ -20,8 +24,9 @@ type ServerConfig struct {
 	Router         *httprouter.Router
 	DB             *sql.DB
 	ElasticSearch  *elasticsearch.Client
+	Cache          cache.Cache
+	Storage        storage.Storage
 	KafkaProducer  sarama.SyncProducer
-	KafkaConsumer  sarama.Consumer
 	Log            *zerolog.Logger
 	Validate       *validator.Validate
 	Config         *koanf.Koanf
@@ -29,15 +34,134 @@ type ServerConfig struct {
 
 func Server(config *ServerConfig) {
 	productRepository := repository.NewProductRepository(config.Log, config.DB)
-	productUsecase := usecase.NewProductUsecase(config.UserServiceUrl, productRepository, config.KafkaProducer, config.DB, config.ElasticSearch, config.Validate, config.Log, config.Config)
+	categoryRepository := repository.NewCategoryRepository(config.Log, config.DB)
+	tagRepository := repository.NewTagRepository(config.Log, config.DB)
+	productVariantRepository := repository.NewProductVariantRepository(config.Log, config.DB)
+
+	// stock updates record the status changes they cause
+	productStatusRepository := repository.NewProductStatusRepository(config.Log, config.DB)
+	productStatusUsecase := usecase.NewProductStatusUsecase(productRepository, productStatusRepository, config.KafkaProducer, config.DB, config.Log)
+
+	productUsecase := usecase.NewProductUsecase(config.UserServiceUrl, productRepository, productVariantRepository, categoryRepository, tagRepository, productStatusUsecase, config.KafkaProducer, config.DB, config.ElasticSearch, config.Cache, config.Validate, config.Log, config.Config)
 	productController := http.NewProductController(productUsecase, config.Log)
 
+	productImportRepository := repository.NewProductImportRepository(config.Log, config.DB)
+	productImportUsecase := usecase.NewProductImportUsecase(productUsecase, productRepository, categoryRepository, tagRepository, productImportRepository, config.DB, config.Validate, config.Log, config.Config)
+	productImportController := http.NewProductImportController(productImportUsecase, config.Log)
+	go productImportUsecase.Run(context.Background())
+
+	productExportUsecase := usecase.NewProductExportUsecase(productRepository, config.DB, config.Validate, config.Log, config.Config)
+	productExportController := http.NewProductExportController(productExportUsecase, config.Log)
+
+	productVariantUsecase := usecase.NewProductVariantUsecase(productRepository, productVariantRepository, productStatusUsecase, config.DB, config.Validate, config.Log)
+	productVariantController := http.NewProductVariantController(productVariantUsecase, config.Log)
+
+	stockReservationRepository := repository.NewStockReservationRepository(config.Log, config.DB)
+	stockReservationUsecase := usecase.NewStockReservationUsecase(productRepository, productVariantRepository, stockReservationRepository, productStatusRepository, productStatusUsecase, config.KafkaProducer, config.DB, config.Validate, config.Log, config.Config)
+	stockReservationController := http.NewStockReservationController(stockReservationUsecase, config.Log)
+	go stockReservationUsecase.Run(context.Background())
+
+	productImageRepository := repository.NewProductImageRepository(config.Log, config.DB)
+	productImageUsecase := usecase.NewProductImageUsecase(productRepository, productImageRepository, config.Storage, config.DB, config.Validate, config.Log, config.Config)
+	productImageController := http.NewProductImageController(productImageUsecase, config.Log)
+
+	productPurgeUsecase := usecase.NewProductPurgeUsecase(productRepository, productImageRepository, config.Storage, config.DB, config.Log, config.Config)
+	go productPurgeUsecase.Run(context.Background())
+
+	categoryUsecase := usecase.NewCategoryUsecase(categoryRepository, config.DB, config.Cache, config.Validate, config.Log, config.Config)
+	categoryController := http.NewCategoryController(categoryUsecase, config.Log)
+
+	searchAnalyticsRepository := repository.NewSearchAnalyticsRepository(config.Log, config.DB)
+	searchAnalyticsUsecase := usecase.NewSearchAnalyticsUsecase(searchAnalyticsRepository, config.KafkaProducer, config.Log, config.Config)
+	searchAnalyticsController := http.NewSearchAnalyticsController(searchAnalyticsUsecase, config.Log)
+	searchAnalyticsConsumer := messaging.NewSearchAnalyticsConsumer(searchAnalyticsUsecase, config.Log)
+	messaging.ConsumeTopic(context.Background(), NewKafkaConsumerGroup(config.Config, config.Log, "search-analytics", sarama.OffsetOldest), "search-analytics", "product.search", config.KafkaProducer, config.Log, searchAnalyticsConsumer.ConsumeProductSearch)
+	go searchAnalyticsUsecase.RunPublisher(context.Background())
+
+	productSearchRepository := repository.NewProductSearchRepository(config.Log, config.ElasticSearch, config.Config.String("ELASTICSEARCH_PRODUCT_INDEX"), config.Config.String("ELASTICSEARCH_SUGGEST_INDEX"))
+	productTextSearchRepository := repository.NewProductTextSearchRepository(config.Log, config.DB)
+	productSuggestionRepository := repository.NewProductSuggestionRepository(config.Log, config.DB)
+	productSearchUsecase := usecase.NewProductSearchUsecase(config.UserServiceUrl, productSearchRepository, productTextSearchRepository, productSuggestionRepository, searchAnalyticsUsecase, config.Cache, config.Validate, config.Log, config.Config)
+	productSearchController := http.NewProductSearchController(productSearchUsecase, config.Log)
+
+	searchIndexRepository := repository.NewSearchIndexRepository(config.Log, config.ElasticSearch)
+	searchIndexUsecase := usecase.NewSearchIndexUsecase(searchIndexRepository, config.Log, config.Config)
+	searchIndexUsecase.EnsureIndex(context.Background(), "products", productSearchRepository.IndexName)
+	searchIndexUsecase.EnsureIndex(context.Background(), "product_suggestions", productSearchRepository.SuggestIndexName)
+	go productSearchUsecase.RunHealthCheck(context.Background())
+
+	productStatusController := http.NewProductStatusController(productStatusUsecase, config.Log)
+
+	productHistoryRepository := repository.NewProductHistoryRepository(config.Log, config.DB)
+	productHistoryUsecase := usecase.NewProductHistoryUsecase(productRepository, productHistoryRepository, config.DB, config.Validate, config.Log)
+	productHistoryController := http.NewProductHistoryController(productHistoryUsecase, config.Log)
+
+	sellerDeletionRepository := repository.NewSellerDeletionRepository(config.Log, config.DB)
+	userDeletionUsecase := usecase.NewUserDeletionUsecase(productRepository, productStatusRepository, sellerDeletionRepository, productStatusUsecase, config.KafkaProducer, config.DB, config.Log)
+	userDeletionConsumer := messaging.NewUserDeletionConsumer(userDeletionUsecase, config.Log)
+	messaging.ConsumeTopic(context.Background(), NewKafkaConsumerGroup(config.Config, config.Log, "user-deletion", sarama.OffsetOldest), "user-deletion", "user.deleted", config.KafkaProducer, config.Log, userDeletionConsumer.ConsumeUserDeleted)
+	messaging.ConsumeTopic(context.Background(), NewKafkaConsumerGroup(config.Config, config.Log, "user-deletion-compensate", sarama.OffsetOldest), "user-deletion-compensate", "user.deletion.compensate", config.KafkaProducer, config.Log, userDeletionConsumer.ConsumeCompensate)
+
 	authMiddleware := middleware.NewAuthMiddleware(config.Router, config.Log, config.Config, productUsecase)
 
+	stockThresholdRepository := repository.NewStockThresholdRepository(config.Log, config.DB)
+	stockUsecase := usecase.NewStockUsecase(stockThresholdRepository, config.KafkaProducer, config.Validate, config.Log, config.Config)
+	stockController := http.NewStockController(stockUsecase, config.Log)
+
+	sellerStatsRepository := repository.NewSellerStatsRepository(config.Log, config.DB)
+	sellerStatsUsecase := usecase.NewSellerStatsUsecase(sellerStatsRepository, config.DB, config.Log)
+	sellerStatsController := http.NewSellerStatsController(sellerStatsUsecase, config.Log)
+
+	webhookRepository := repository.NewWebhookRepository(config.Log, config.DB)
+	webhookUsecase := usecase.NewWebhookUsecase(webhookRepository, config.DB, config.Validate, config.Log, config.Config)
+	webhookController := http.NewWebhookController(webhookUsecase, config.Log)
+	webhookConsumer := messaging.NewWebhookConsumer(webhookUsecase, config.Log)
+	messaging.ConsumeTopic(context.Background(), NewKafkaConsumerGroup(config.Config, config.Log, "webhook-activity", sarama.OffsetNewest), "webhook-activity", "product.activity", config.KafkaProducer, config.Log, webhookConsumer.ConsumeProductActivity)
+	go webhookUsecase.RunDispatcher(context.Background())
+
+	replicationRepository := repository.NewReplicationRepository(config.Log, config.DB)
+	cdcMonitorUsecase := usecase.NewCDCMonitorUsecase(replicationRepository, config.Log, config.Config)
+	healthController := http.NewHealthController(cdcMonitorUsecase, config.Log)
+	go cdcMonitorUsecase.Run(context.Background())
+
+	productCDCConsumer := messaging.NewProductCDCConsumer(productUsecase, productSearchUsecase, stockUsecase, productStatusUsecase, productHistoryUsecase, sellerStatsUsecase, webhookUsecase, config.Log)
+
+	// a shared cache is invalidated once for every instance, a cache in memory
+	// by each process on its own
+	if config.Config.String("CACHE_BACKEND") == "redis" {
+		messaging.ConsumeTopic(context.Background(), NewKafkaConsumerGroup(config.Config, config.Log, "cdc.product-cache", sarama.OffsetNewest), "cdc.product-cache", "dbz.public.products", config.KafkaProducer, config.Log, productCDCConsumer.Project(productUsecase.InvalidateProductCache))
+	} else {
+		messaging.ConsumeTopicBroadcast(context.Background(), NewKafkaConsumer(config.Config, config.Log), "cdc.product-cache", "dbz.public.products", config.KafkaProducer, config.Log, productCDCConsumer.Project(productUsecase.InvalidateProductCache))
+	}
+
+	for _, projection := range productCDCConsumer.Projections() {
+		messaging.ConsumeTopic(context.Background(), NewKafkaConsumerGroup(config.Config, config.Log, projection.Name, projection.InitialOffset), projection.Name, "dbz.public.products", config.KafkaProducer, config.Log, projection.Handler)
+	}
+
+	searchIndexerGroup := NewKafkaConsumerGroup(config.Config, config.Log, "cdc.search-index", sarama.OffsetOldest)
+	searchIndexerUsecase := usecase.NewSearchIndexerUsecase(productSearchRepository, categoryRepository, tagRepository, cdcMonitorUsecase, config.KafkaProducer, searchIndexerGroup, config.Log, config.Config)
+	messaging.ConsumeTopicAcked(context.Background(), searchIndexerGroup, "cdc.search-index", "dbz.public.products", config.KafkaProducer, config.Log, productCDCConsumer.ProjectAcked(searchIndexerUsecase.HandleProductChange))
+	go searchIndexerUsecase.Run(context.Background())
+
 	routeConfig := route.RouteConfig{
-		Router:            config.Router,
-		ProductController: productController,
-		AuthMiddleware:    authMiddleware,
+		Router:                     config.Router,
+		Storage:                    config.Storage,
+		ProductController:          productController,
+		ProductSearchController:    productSearchController,
+		ProductImageController:     productImageController,
+		ProductVariantController:   productVariantController,
+		ProductStatusController:    productStatusController,
+		ProductHistoryController:   productHistoryController,
+		ProductImportController:    productImportController,
+		ProductExportController:    productExportController,
+		CategoryController:         categoryController,
+		SearchAnalyticsController:  searchAnalyticsController,
+		StockController:            stockController,
+		StockReservationController: stockReservationController,
+		SellerStatsController:      sellerStatsController,
+		WebhookController:          webhookController,
+		HealthController:           healthController,
+		AuthMiddleware:             authMiddleware,
 	}
 
 	routeConfig.SetupRoute()
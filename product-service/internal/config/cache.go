@@ -0,0 +1,22 @@
+package config
+
+import (
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/cache"
+	"time"
+)
+
+func NewCache(config *koanf.Koanf, log *zerolog.Logger) cache.Cache {
+	if config.String("CACHE_BACKEND") == "redis" {
+		log.Info().Msg("Using redis product cache at " + config.String("REDIS_ADDRESS"))
+		return cache.NewRedisCache(config.String("REDIS_ADDRESS"), config.String("REDIS_PASSWORD"), 10, 2*time.Second, log)
+	}
+
+	capacity := config.Int("CACHE_CAPACITY")
+	if capacity <= 0 {
+		capacity = 10000
+	}
+
+	return cache.NewLRUCache(capacity)
+}
@@ -30,3 +30,27 @@ func NewKafkaConsumer(config *koanf.Koanf, log *zerolog.Logger) sarama.Consumer
 
 	return consumer
 }
+
+// NewKafkaConsumerGroup joins the group KAFKA_CONSUMER_GROUP.name. A group
+// without committed offsets starts at initialOffset, sarama.OffsetOldest for
+// projections that must see every change and sarama.OffsetNewest for
+// notifications that are pointless to replay.
+func NewKafkaConsumerGroup(config *koanf.Koanf, log *zerolog.Logger, name string, initialOffset int64) sarama.ConsumerGroup {
+	kafka_port := config.String("KAFKA_BROKER_PORT")
+	kafka_array_port := strings.Split(kafka_port, ";")
+
+	groupPrefix := config.String("KAFKA_CONSUMER_GROUP")
+	if groupPrefix == "" {
+		groupPrefix = "product-service"
+	}
+
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Consumer.Offsets.Initial = initialOffset
+
+	group, err := sarama.NewConsumerGroup(kafka_array_port, groupPrefix+"."+name, saramaConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create kafka consumer group " + name)
+	}
+
+	return group
+}
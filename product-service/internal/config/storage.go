@@ -0,0 +1,32 @@
+package config
+
+import (
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/storage"
+	"net/url"
+)
+
+func NewStorage(config *koanf.Koanf, log *zerolog.Logger) storage.Storage {
+	if config.String("STORAGE_BACKEND") == "s3" {
+		endpoint, err := url.Parse(config.String("S3_ENDPOINT"))
+		if err != nil || endpoint.Host == "" || config.String("S3_BUCKET") == "" {
+			log.Fatal().Msg("S3_ENDPOINT and S3_BUCKET must be set for the s3 storage backend")
+		}
+
+		log.Info().Msg("Using s3 storage bucket " + config.String("S3_BUCKET") + " at " + endpoint.Host)
+		return storage.NewS3Storage(config.String("S3_ENDPOINT"), config.String("S3_REGION"), config.String("S3_BUCKET"), config.String("S3_ACCESS_KEY"), config.String("S3_SECRET_KEY"), config.String("STORAGE_PUBLIC_URL"), config.Bool("S3_PATH_STYLE"))
+	}
+
+	root := config.String("STORAGE_PATH")
+	if root == "" {
+		root = "./uploads"
+	}
+
+	publicUrl := config.String("STORAGE_PUBLIC_URL")
+	if publicUrl == "" {
+		publicUrl = "/media"
+	}
+
+	return storage.NewFilesystemStorage(root, publicUrl)
+}
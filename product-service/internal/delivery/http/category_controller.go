@@ -0,0 +1,158 @@
+package http
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/web"
+	"gocdc/internal/model/web/category"
+	"gocdc/internal/usecase"
+	"net/http"
+)
+
+type CategoryController struct {
+	CategoryUsecase *usecase.CategoryUsecase
+	Log             *zerolog.Logger
+}
+
+func NewCategoryController(categoryUsecase *usecase.CategoryUsecase, zerolog *zerolog.Logger) *CategoryController {
+	return &CategoryController{
+		CategoryUsecase: categoryUsecase,
+		Log:             zerolog,
+	}
+}
+
+func (controller CategoryController) FindTree(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	categoryResponses := controller.CategoryUsecase.FindTree(request.Context())
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   categoryResponses,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller CategoryController) FindById(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	categoryResponse, err := controller.CategoryUsecase.FindById(request.Context(), queryInt(params.ByName("categoryID"), 0))
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   categoryResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller CategoryController) Create(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	categoryCreateRequest := category.CategoryCreateRequest{}
+	helper.ReadFromRequestBody(request, &categoryCreateRequest)
+
+	categoryResponse, err := controller.CategoryUsecase.Create(request.Context(), categoryCreateRequest)
+	if err != nil {
+		if err.Error() == "category already exists" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusConflict)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusConflict,
+				Status: "Conflict",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   categoryResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller CategoryController) Update(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	categoryUpdateRequest := category.CategoryUpdateRequest{}
+	helper.ReadFromRequestBody(request, &categoryUpdateRequest)
+
+	categoryResponse, err := controller.CategoryUsecase.Update(request.Context(), queryInt(params.ByName("categoryID"), 0), categoryUpdateRequest)
+	if err != nil {
+		if err.Error() == "category not found" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusNotFound)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusNotFound,
+				Status: "Not Found",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		if err.Error() == "category already exists" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusConflict)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusConflict,
+				Status: "Conflict",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   categoryResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/web"
+	"gocdc/internal/usecase"
+	"net/http"
+)
+
+type HealthController struct {
+	CDCMonitorUsecase *usecase.CDCMonitorUsecase
+	Log               *zerolog.Logger
+}
+
+func NewHealthController(cdcMonitorUsecase *usecase.CDCMonitorUsecase, zerolog *zerolog.Logger) *HealthController {
+	return &HealthController{
+		CDCMonitorUsecase: cdcMonitorUsecase,
+		Log:               zerolog,
+	}
+}
+
+// CDC reports the result of the last periodic check, so probes polling it do
+// not add load on Postgres.
+func (controller HealthController) CDC(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	healthResponse := controller.CDCMonitorUsecase.Health()
+
+	if healthResponse.Status != "Healthy" {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusServiceUnavailable)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusServiceUnavailable,
+			Status: "Service Unavailable",
+			Data:   healthResponse,
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   healthResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
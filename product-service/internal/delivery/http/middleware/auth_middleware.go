@@ -298,3 +298,72 @@ func (middleware *AuthMiddleware) ServeExternalService(next httprouter.Handle) h
 		next(writer, request.WithContext(ctx), p)
 	}
 }
+
+// ServeOptional identifies the caller when a valid token is sent and serves
+// anonymous requests as they are, for public endpoints that personalise or
+// attribute when they can.
+func (middleware *AuthMiddleware) ServeOptional(next httprouter.Handle) httprouter.Handle {
+	return func(writer http.ResponseWriter, request *http.Request, p httprouter.Params) {
+		splitToken := strings.Split(request.Header.Get("Authorization"), "Bearer ")
+		if len(splitToken) != 2 {
+			next(writer, request, p)
+			return
+		}
+
+		secretKeyByte := []byte(middleware.Config.String("SECRET_KEY_ACCESS_TOKEN"))
+
+		token, err := jwt.Parse(splitToken[1], func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, http.ErrNotSupported
+			}
+			return secretKeyByte, nil
+		})
+
+		if err != nil {
+			next(writer, request, p)
+			return
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok || !token.Valid {
+			next(writer, request, p)
+			return
+		}
+
+		id, _ := claims["id"].(string)
+		if id == "" {
+			next(writer, request, p)
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), userUUIDkey, id)
+		next(writer, request.WithContext(ctx), p)
+	}
+}
+
+// ServeAdmin only lets through users listed in ADMIN_USER_IDS, separated by
+// semicolons like the other list settings.
+func (middleware *AuthMiddleware) ServeAdmin(next httprouter.Handle) httprouter.Handle {
+	return middleware.ServeHTTP(func(writer http.ResponseWriter, request *http.Request, p httprouter.Params) {
+		userUUID, _ := request.Context().Value(userUUIDkey).(string)
+
+		for _, adminID := range strings.Split(middleware.Config.String("ADMIN_USER_IDS"), ";") {
+			if adminID != "" && adminID == userUUID {
+				next(writer, request, p)
+				return
+			}
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusForbidden)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusForbidden,
+			Status: "Forbidden",
+			Data:   "Admin only",
+		}
+
+		middleware.Log.Warn().Msg("Forbidden, user is not an admin")
+		helper.WriteToResponseBody(writer, webResponse)
+	})
+}
@@ -139,6 +139,39 @@ func (controller ProductController) Delete(writer http.ResponseWriter, request *
 	helper.WriteToResponseBody(writer, webResponse)
 }
 
+func (controller ProductController) Restore(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	productID := params.ByName("productID")
+	fixProductID, err := strconv.Atoi(productID)
+	if err != nil {
+		respErr := errors.New("error converting string to int")
+		controller.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	err = controller.ProductUsecase.Restore(request.Context(), userUUID, fixProductID)
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
 func (controller ProductController) FindProductInfo(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
 	productID := params.ByName("productID")
 	fixProductID, err := strconv.Atoi(productID)
@@ -172,14 +205,71 @@ func (controller ProductController) FindProductInfo(writer http.ResponseWriter,
 }
 
 func (controller ProductController) FindAllProduct(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
-	productResponse, err := controller.ProductUsecase.FindAllProduct(request.Context())
+	query := request.URL.Query()
+
+	productListRequest := product.ProductListRequest{
+		Limit:       queryInt(query.Get("limit"), 20),
+		Cursor:      query.Get("cursor"),
+		Seller_id:   query.Get("seller_id"),
+		Category_id: queryInt(query.Get("category_id"), 0),
+		Status:      queryList(query["status"]),
+		Size:        queryList(query["size"]),
+		Min_price:   queryFloat(query.Get("min_price")),
+		Max_price:   queryFloat(query.Get("max_price")),
+		Sort:        query.Get("sort"),
+	}
+
+	controller.findProducts(writer, request, productListRequest)
+}
+
+// FindCategoryProducts lists the products of a category and its descendants,
+// with the same paging and filters as FindAllProduct.
+func (controller ProductController) FindCategoryProducts(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	query := request.URL.Query()
+
+	productListRequest := product.ProductListRequest{
+		Limit:       queryInt(query.Get("limit"), 20),
+		Cursor:      query.Get("cursor"),
+		Seller_id:   query.Get("seller_id"),
+		Category_id: queryInt(params.ByName("categoryID"), 0),
+		Status:      queryList(query["status"]),
+		Size:        queryList(query["size"]),
+		Min_price:   queryFloat(query.Get("min_price")),
+		Max_price:   queryFloat(query.Get("max_price")),
+		Sort:        query.Get("sort"),
+	}
+
+	// 0 means no category filter on /product, here it is just a bad id
+	if productListRequest.Category_id == 0 {
+		productListRequest.Category_id = -1
+	}
+
+	controller.findProducts(writer, request, productListRequest)
+}
+
+func (controller ProductController) findProducts(writer http.ResponseWriter, request *http.Request, productListRequest product.ProductListRequest) {
+	productResponse, err := controller.ProductUsecase.FindAllProduct(request.Context(), productListRequest)
 	if err != nil {
+		if err.Error() == "category not found" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusNotFound)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusNotFound,
+				Status: "Not Found",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
 		writer.Header().Set("Content-Type", "application/json")
-		writer.WriteHeader(http.StatusNotFound)
+		writer.WriteHeader(http.StatusBadRequest)
 
 		webResponse := web.WebResponse{
-			Code:   http.StatusNotFound,
-			Status: "Not Found",
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
 			Data:   err.Error(),
 		}
 
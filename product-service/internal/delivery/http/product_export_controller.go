@@ -0,0 +1,97 @@
+package http
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/web"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/usecase"
+	"net/http"
+)
+
+type ProductExportController struct {
+	ProductExportUsecase *usecase.ProductExportUsecase
+	Log                  *zerolog.Logger
+}
+
+func NewProductExportController(productExportUsecase *usecase.ProductExportUsecase, zerolog *zerolog.Logger) *ProductExportController {
+	return &ProductExportController{
+		ProductExportUsecase: productExportUsecase,
+		Log:                  zerolog,
+	}
+}
+
+// Export streams the seller's own products.
+func (controller ProductExportController) Export(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	controller.export(writer, request, userUUID)
+}
+
+// AdminExport streams the products of every seller, or of the one given by
+// seller_id.
+func (controller ProductExportController) AdminExport(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	controller.export(writer, request, "")
+}
+
+func (controller ProductExportController) export(writer http.ResponseWriter, request *http.Request, sellerUUID string) {
+	query := request.URL.Query()
+
+	productExportRequest := product.ProductExportRequest{
+		Format:      query.Get("format"),
+		Seller_id:   query.Get("seller_id"),
+		Category_id: queryInt(query.Get("category_id"), 0),
+		Status:      queryList(query["status"]),
+		Size:        queryList(query["size"]),
+		Min_price:   queryFloat(query.Get("min_price")),
+		Max_price:   queryFloat(query.Get("max_price")),
+	}
+
+	// headers are only sent with the first row, an invalid request still gets
+	// a json error
+	if productExportRequest.Format == "csv" {
+		writer.Header().Set("Content-Type", "text/csv")
+		writer.Header().Set("Content-Disposition", `attachment; filename="products.csv"`)
+	} else {
+		writer.Header().Set("Content-Type", "application/x-ndjson")
+		writer.Header().Set("Content-Disposition", `attachment; filename="products.ndjson"`)
+	}
+
+	err := controller.ProductExportUsecase.Export(request.Context(), sellerUUID, productExportRequest, writer)
+	if err != nil {
+		// rows already sent cannot be taken back, dropping the connection
+		// tells the client its file is incomplete
+		if err.Error() == "product export failed" {
+			panic(http.ErrAbortHandler)
+		}
+
+		if err.Error() == "too many exports are running, try again later" {
+			writer.Header().Del("Content-Disposition")
+			writer.Header().Set("Content-Type", "application/json")
+			writer.Header().Set("Retry-After", "30")
+			writer.WriteHeader(http.StatusServiceUnavailable)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusServiceUnavailable,
+				Status: "Service Unavailable",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Del("Content-Disposition")
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+	}
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/web"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/usecase"
+	"net/http"
+)
+
+type ProductHistoryController struct {
+	ProductHistoryUsecase *usecase.ProductHistoryUsecase
+	Log                   *zerolog.Logger
+}
+
+func NewProductHistoryController(productHistoryUsecase *usecase.ProductHistoryUsecase, zerolog *zerolog.Logger) *ProductHistoryController {
+	return &ProductHistoryController{
+		ProductHistoryUsecase: productHistoryUsecase,
+		Log:                   zerolog,
+	}
+}
+
+func (controller ProductHistoryController) FindChanges(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	controller.findChanges(writer, request, params, userUUID)
+}
+
+func (controller ProductHistoryController) FindAsOf(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	controller.findAsOf(writer, request, params, userUUID)
+}
+
+// AdminFindChanges and AdminFindAsOf serve support staff, who can look at any
+// product.
+func (controller ProductHistoryController) AdminFindChanges(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	controller.findChanges(writer, request, params, "")
+}
+
+func (controller ProductHistoryController) AdminFindAsOf(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	controller.findAsOf(writer, request, params, "")
+}
+
+func (controller ProductHistoryController) findChanges(writer http.ResponseWriter, request *http.Request, params httprouter.Params, sellerUUID string) {
+	query := request.URL.Query()
+
+	productHistoryRequest := product.ProductHistoryRequest{
+		Limit:     queryInt(query.Get("limit"), 20),
+		Before_id: int64(queryInt(query.Get("before_id"), 0)),
+	}
+
+	listResponse, err := controller.ProductHistoryUsecase.FindChanges(request.Context(), sellerUUID, queryInt(params.ByName("productID"), 0), productHistoryRequest)
+	if err != nil {
+		if err.Error() == "invalid request body" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusBadRequest)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusBadRequest,
+				Status: "Bad Request",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   listResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller ProductHistoryController) findAsOf(writer http.ResponseWriter, request *http.Request, params httprouter.Params, sellerUUID string) {
+	asOfResponse, err := controller.ProductHistoryUsecase.FindAsOf(request.Context(), sellerUUID, queryInt(params.ByName("productID"), 0), request.URL.Query().Get("at"))
+	if err != nil {
+		if err.Error() == "at must be an RFC 3339 timestamp" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusBadRequest)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusBadRequest,
+				Status: "Bad Request",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   asOfResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
@@ -0,0 +1,230 @@
+package http
+
+import (
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/usecase"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type ProductImageController struct {
+	ProductImageUsecase *usecase.ProductImageUsecase
+	Log                 *zerolog.Logger
+}
+
+func NewProductImageController(productImageUsecase *usecase.ProductImageUsecase, zerolog *zerolog.Logger) *ProductImageController {
+	return &ProductImageController{
+		ProductImageUsecase: productImageUsecase,
+		Log:                 zerolog,
+	}
+}
+
+// Upload takes one or more files in the "image" field of a multipart form.
+func (controller ProductImageController) Upload(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	maxImageBytes := controller.ProductImageUsecase.MaxImageBytes
+	request.Body = http.MaxBytesReader(writer, request.Body, maxImageBytes*int64(controller.ProductImageUsecase.MaxImages)+1<<20)
+
+	uploads, err := readImageUploads(request, maxImageBytes)
+	if err != nil {
+		maxBytesError := &http.MaxBytesError{}
+		if errors.As(err, &maxBytesError) {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusRequestEntityTooLarge)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusRequestEntityTooLarge,
+				Status: "Request Entity Too Large",
+				Data:   "request body is too large",
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   "invalid multipart form",
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	imageResponses, err := controller.ProductImageUsecase.Upload(request.Context(), userUUID, queryInt(params.ByName("productID"), 0), uploads)
+	if err != nil {
+		if err.Error() == "product not found" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusNotFound)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusNotFound,
+				Status: "Not Found",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		if err.Error() == "image storage is unavailable" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusServiceUnavailable)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusServiceUnavailable,
+				Status: "Service Unavailable",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   imageResponses,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller ProductImageController) FindAll(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	imageResponses := controller.ProductImageUsecase.FindByProductId(request.Context(), queryInt(params.ByName("productID"), 0))
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   imageResponses,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller ProductImageController) Reorder(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	productImageOrderRequest := product.ProductImageOrderRequest{}
+	helper.ReadFromRequestBody(request, &productImageOrderRequest)
+
+	imageResponses, err := controller.ProductImageUsecase.Reorder(request.Context(), userUUID, queryInt(params.ByName("productID"), 0), productImageOrderRequest)
+	if err != nil {
+		if err.Error() == "product not found" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusNotFound)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusNotFound,
+				Status: "Not Found",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   imageResponses,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller ProductImageController) Delete(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	err := controller.ProductImageUsecase.Delete(request.Context(), userUUID, queryInt(params.ByName("productID"), 0), params.ByName("imageID"))
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+// readImageUploads reads the "image" files of a multipart form. A file is read
+// one byte past maxImageBytes, enough for the usecase to reject it as too
+// large without holding all of it.
+func readImageUploads(request *http.Request, maxImageBytes int64) ([]domain.ProductImageUpload, error) {
+	err := request.ParseMultipartForm(8 << 20)
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := []domain.ProductImageUpload{}
+
+	for _, header := range request.MultipartForm.File["image"] {
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := io.ReadAll(io.LimitReader(file, maxImageBytes+1))
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		uploads = append(uploads, domain.ProductImageUpload{
+			Filename: strings.TrimSpace(header.Filename),
+			Data:     data,
+		})
+	}
+
+	return uploads, nil
+}
@@ -0,0 +1,170 @@
+package http
+
+import (
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/web"
+	"gocdc/internal/usecase"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type ProductImportController struct {
+	ProductImportUsecase *usecase.ProductImportUsecase
+	Log                  *zerolog.Logger
+}
+
+func NewProductImportController(productImportUsecase *usecase.ProductImportUsecase, zerolog *zerolog.Logger) *ProductImportController {
+	return &ProductImportController{
+		ProductImportUsecase: productImportUsecase,
+		Log:                  zerolog,
+	}
+}
+
+// Create takes the file from the "file" field of a multipart form, with an
+// optional "format" field of csv or ndjson.
+func (controller ProductImportController) Create(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+	userAuthToken, _ := request.Context().Value("user_auth_token").(string)
+
+	maxBytes := controller.ProductImportUsecase.MaxBytes
+	request.Body = http.MaxBytesReader(writer, request.Body, maxBytes+1<<20)
+
+	filename, data, err := readImportUpload(request, maxBytes)
+	if err != nil {
+		maxBytesError := &http.MaxBytesError{}
+		if errors.As(err, &maxBytesError) {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusRequestEntityTooLarge)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusRequestEntityTooLarge,
+				Status: "Request Entity Too Large",
+				Data:   "request body is too large",
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   "invalid multipart form",
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	importResponse, err := controller.ProductImportUsecase.Create(request.Context(), userUUID, userAuthToken, filename, strings.ToLower(request.FormValue("format")), data)
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusAccepted)
+
+	webResponse := web.WebResponse{
+		Code:   http.StatusAccepted,
+		Status: "Accepted",
+		Data:   importResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller ProductImportController) FindById(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	importResponse, err := controller.ProductImportUsecase.FindById(request.Context(), userUUID, params.ByName("importID"))
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   importResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+// FindErrors downloads the rows that were not imported as a CSV file.
+func (controller ProductImportController) FindErrors(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+	importID := params.ByName("importID")
+
+	_, err := controller.ProductImportUsecase.FindById(request.Context(), userUUID, importID)
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/csv")
+	writer.Header().Set("Content-Disposition", `attachment; filename="import-`+importID+`-errors.csv"`)
+
+	err = controller.ProductImportUsecase.FindErrorReport(request.Context(), userUUID, importID, writer)
+	if err != nil {
+		controller.Log.Warn().Err(err).Msg("failed to write import error report")
+	}
+}
+
+// readImportUpload reads the uploaded file up to one byte past maxBytes,
+// enough for the usecase to reject it as too large.
+func readImportUpload(request *http.Request, maxBytes int64) (string, []byte, error) {
+	err := request.ParseMultipartForm(8 << 20)
+	if err != nil {
+		return "", nil, err
+	}
+
+	file, header, err := request.FormFile("file")
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return strings.TrimSpace(header.Filename), data, nil
+}
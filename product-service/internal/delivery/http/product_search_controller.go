@@ -0,0 +1,231 @@
+package http
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/web"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/usecase"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type ProductSearchController struct {
+	ProductSearchUsecase *usecase.ProductSearchUsecase
+	Log                  *zerolog.Logger
+}
+
+func NewProductSearchController(productSearchUsecase *usecase.ProductSearchUsecase, zerolog *zerolog.Logger) *ProductSearchController {
+	return &ProductSearchController{
+		ProductSearchUsecase: productSearchUsecase,
+		Log:                  zerolog,
+	}
+}
+
+func (controller ProductSearchController) Search(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+	query := request.URL.Query()
+
+	productSearchRequest := product.ProductSearchRequest{
+		Q:          query.Get("q"),
+		Sort:       query.Get("sort"),
+		Category:   queryInt(query.Get("category"), 0),
+		Page:       queryInt(query.Get("page"), 1),
+		Per_page:   queryInt(query.Get("per_page"), 20),
+		Size:       queryList(query["size"]),
+		Status:     queryList(query["status"]),
+		Min_price:  queryFloat(query.Get("min_price")),
+		Max_price:  queryFloat(query.Get("max_price")),
+		Min_weight: queryInt(query.Get("min_weight"), 0),
+		Max_weight: queryInt(query.Get("max_weight"), 0),
+	}
+
+	searchResponse, err := controller.ProductSearchUsecase.Search(request.Context(), productSearchRequest, userUUID)
+	if err != nil {
+		if err.Error() == "search is unavailable" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusServiceUnavailable)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusServiceUnavailable,
+				Status: "Service Unavailable",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   searchResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller ProductSearchController) Suggest(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	query := request.URL.Query()
+
+	productSuggestRequest := product.ProductSuggestRequest{
+		Prefix: query.Get("prefix"),
+		Limit:  queryInt(query.Get("limit"), 5),
+	}
+
+	suggestResponse, err := controller.ProductSearchUsecase.Suggest(request.Context(), productSuggestRequest)
+	if err != nil {
+		if err.Error() == "search is unavailable" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusServiceUnavailable)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusServiceUnavailable,
+				Status: "Service Unavailable",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   suggestResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller ProductSearchController) Similar(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	productSimilarRequest := product.ProductSimilarRequest{
+		Product_id: queryInt(params.ByName("productID"), 0),
+		Limit:      queryInt(request.URL.Query().Get("limit"), 12),
+	}
+
+	similarResponse, err := controller.ProductSearchUsecase.Similar(request.Context(), productSimilarRequest)
+	if err != nil {
+		if err.Error() == "product not found" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusNotFound)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusNotFound,
+				Status: "Not Found",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		if err.Error() == "search is unavailable" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusServiceUnavailable)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusServiceUnavailable,
+				Status: "Service Unavailable",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   similarResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+// queryInt reads an optional integer query parameter. A malformed value is
+// passed on as -1 so request validation rejects it.
+func queryInt(value string, fallback int) int {
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return -1
+	}
+
+	return number
+}
+
+// queryFloat reads an optional number, malformed values become -1 and fail
+// validation.
+func queryFloat(value string) float64 {
+	if value == "" {
+		return 0
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return -1
+	}
+
+	return number
+}
+
+// queryList accepts both repeated parameters (size=S&size=M) and comma
+// separated values (size=S,M).
+func queryList(values []string) []string {
+	list := []string{}
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+
+	return list
+}
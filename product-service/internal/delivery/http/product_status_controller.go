@@ -0,0 +1,102 @@
+package http
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/web"
+	"gocdc/internal/usecase"
+	"net/http"
+)
+
+type ProductStatusController struct {
+	ProductStatusUsecase *usecase.ProductStatusUsecase
+	Log                  *zerolog.Logger
+}
+
+func NewProductStatusController(productStatusUsecase *usecase.ProductStatusUsecase, zerolog *zerolog.Logger) *ProductStatusController {
+	return &ProductStatusController{
+		ProductStatusUsecase: productStatusUsecase,
+		Log:                  zerolog,
+	}
+}
+
+func (controller ProductStatusController) Publish(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	controller.transition(writer, request, params, "Ready")
+}
+
+func (controller ProductStatusController) Unpublish(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	controller.transition(writer, request, params, "Draft")
+}
+
+func (controller ProductStatusController) Archive(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	controller.transition(writer, request, params, "Archived")
+}
+
+func (controller ProductStatusController) transition(writer http.ResponseWriter, request *http.Request, params httprouter.Params, status string) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	changeResponse, err := controller.ProductStatusUsecase.Transition(request.Context(), userUUID, queryInt(params.ByName("productID"), 0), status)
+	if err != nil {
+		if err.Error() == "product not found" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusNotFound)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusNotFound,
+				Status: "Not Found",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusConflict)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusConflict,
+			Status: "Conflict",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   changeResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller ProductStatusController) FindHistory(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	changeResponses, err := controller.ProductStatusUsecase.FindHistory(request.Context(), userUUID, queryInt(params.ByName("productID"), 0))
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   changeResponses,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/web"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/usecase"
+	"net/http"
+)
+
+type ProductVariantController struct {
+	ProductVariantUsecase *usecase.ProductVariantUsecase
+	Log                   *zerolog.Logger
+}
+
+func NewProductVariantController(productVariantUsecase *usecase.ProductVariantUsecase, zerolog *zerolog.Logger) *ProductVariantController {
+	return &ProductVariantController{
+		ProductVariantUsecase: productVariantUsecase,
+		Log:                   zerolog,
+	}
+}
+
+func (controller ProductVariantController) Create(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	productVariantCreateRequest := product.ProductVariantCreateRequest{}
+	helper.ReadFromRequestBody(request, &productVariantCreateRequest)
+
+	variantResponse, err := controller.ProductVariantUsecase.Create(request.Context(), userUUID, queryInt(params.ByName("productID"), 0), productVariantCreateRequest)
+	if err != nil {
+		if err.Error() == "product not found" || err.Error() == "variant not found" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusNotFound)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusNotFound,
+				Status: "Not Found",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		if err.Error() == "sku already exists" || err.Error() == "variant already exists" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusConflict)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusConflict,
+				Status: "Conflict",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   variantResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller ProductVariantController) Update(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	productVariantUpdateRequest := product.ProductVariantUpdateRequest{}
+	helper.ReadFromRequestBody(request, &productVariantUpdateRequest)
+
+	variantResponse, err := controller.ProductVariantUsecase.Update(request.Context(), userUUID, queryInt(params.ByName("productID"), 0), queryInt(params.ByName("variantID"), 0), productVariantUpdateRequest)
+	if err != nil {
+		if err.Error() == "product not found" || err.Error() == "variant not found" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusNotFound)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusNotFound,
+				Status: "Not Found",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		if err.Error() == "sku already exists" || err.Error() == "variant already exists" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusConflict)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusConflict,
+				Status: "Conflict",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   variantResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller ProductVariantController) Delete(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	err := controller.ProductVariantUsecase.Delete(request.Context(), userUUID, queryInt(params.ByName("productID"), 0), queryInt(params.ByName("variantID"), 0))
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller ProductVariantController) FindAll(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	variantResponses := controller.ProductVariantUsecase.FindByProductId(request.Context(), queryInt(params.ByName("productID"), 0))
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   variantResponses,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
@@ -1,22 +1,113 @@
 package route
 
 import (
+	"expvar"
 	"github.com/julienschmidt/httprouter"
 	"gocdc/internal/delivery/http"
 	"gocdc/internal/delivery/http/middleware"
+	"gocdc/internal/storage"
+	nethttp "net/http"
+	"strings"
 )
 
 type RouteConfig struct {
-	Router            *httprouter.Router
-	ProductController *http.ProductController
-	AuthMiddleware    *middleware.AuthMiddleware
+	Router                     *httprouter.Router
+	Storage                    storage.Storage
+	ProductController          *http.ProductController
+	ProductSearchController    *http.ProductSearchController
+	ProductImageController     *http.ProductImageController
+	ProductVariantController   *http.ProductVariantController
+	ProductStatusController    *http.ProductStatusController
+	ProductHistoryController   *http.ProductHistoryController
+	ProductImportController    *http.ProductImportController
+	ProductExportController    *http.ProductExportController
+	CategoryController         *http.CategoryController
+	SearchAnalyticsController  *http.SearchAnalyticsController
+	StockController            *http.StockController
+	StockReservationController *http.StockReservationController
+	SellerStatsController      *http.SellerStatsController
+	WebhookController          *http.WebhookController
+	HealthController           *http.HealthController
+	AuthMiddleware             *middleware.AuthMiddleware
 }
 
 func (c *RouteConfig) SetupRoute() {
+	// runtime and pipeline counters are for operators only
+	c.Router.GET("/debug/vars", c.AuthMiddleware.ServeAdmin(func(writer nethttp.ResponseWriter, request *nethttp.Request, _ httprouter.Params) {
+		expvar.Handler().ServeHTTP(writer, request)
+	}))
+	c.Router.GET("/health/cdc", c.HealthController.CDC)
+
+	// files stored on disk are served by the service itself, s3 urls point at the bucket
+	if filesystemStorage, ok := c.Storage.(*storage.FilesystemStorage); ok && strings.HasPrefix(filesystemStorage.PublicUrl, "/") {
+		c.Router.ServeFiles(filesystemStorage.PublicUrl+"/*filepath", nethttp.Dir(filesystemStorage.Root))
+	}
+
+	c.Router.GET("/admin/search/analytics", c.AuthMiddleware.ServeAdmin(c.SearchAnalyticsController.FindReport))
+	c.Router.POST("/admin/category", c.AuthMiddleware.ServeAdmin(c.CategoryController.Create))
+	c.Router.PATCH("/admin/category/:categoryID", c.AuthMiddleware.ServeAdmin(c.CategoryController.Update))
+	c.Router.GET("/admin/export", c.AuthMiddleware.ServeAdmin(c.ProductExportController.AdminExport))
+	c.Router.GET("/admin/product/:productID/history", c.AuthMiddleware.ServeAdmin(c.ProductHistoryController.AdminFindChanges))
+	c.Router.GET("/admin/product/:productID/as-of", c.AuthMiddleware.ServeAdmin(c.ProductHistoryController.AdminFindAsOf))
+	c.Router.GET("/category", c.CategoryController.FindTree)
+	c.Router.GET("/category/:categoryID", c.CategoryController.FindById)
+	c.Router.GET("/category/:categoryID/products", c.ProductController.FindCategoryProducts)
 	c.Router.GET("/producthomepage", c.ProductController.FindProductHomePage)
 	c.Router.GET("/product", c.ProductController.FindAllProduct)
-	c.Router.GET("/product/:productID", c.ProductController.FindProductInfo)
+	c.Router.GET("/product/:productID", productIDOr(map[string]httprouter.Handle{
+		"search":  c.AuthMiddleware.ServeOptional(c.ProductSearchController.Search),
+		"suggest": c.ProductSearchController.Suggest,
+		"export":  c.AuthMiddleware.ServeHTTP(c.ProductExportController.Export),
+	}, c.ProductController.FindProductInfo))
+	c.Router.GET("/product/:productID/similar", c.ProductSearchController.Similar)
+	c.Router.GET("/product/:productID/images", c.ProductImageController.FindAll)
+	c.Router.POST("/product/:productID/images", c.AuthMiddleware.ServeHTTP(c.ProductImageController.Upload))
+	c.Router.PUT("/product/:productID/images/order", c.AuthMiddleware.ServeHTTP(c.ProductImageController.Reorder))
+	c.Router.DELETE("/product/:productID/images/:imageID", c.AuthMiddleware.ServeHTTP(c.ProductImageController.Delete))
+	c.Router.GET("/product/:productID/variants", c.ProductVariantController.FindAll)
+	c.Router.POST("/product/:productID/variants", c.AuthMiddleware.ServeHTTP(c.ProductVariantController.Create))
+	c.Router.PATCH("/product/:productID/variants/:variantID", c.AuthMiddleware.ServeHTTP(c.ProductVariantController.Update))
+	c.Router.DELETE("/product/:productID/variants/:variantID", c.AuthMiddleware.ServeHTTP(c.ProductVariantController.Delete))
+	c.Router.POST("/product/:productID/publish", c.AuthMiddleware.ServeHTTP(c.ProductStatusController.Publish))
+	c.Router.POST("/product/:productID/unpublish", c.AuthMiddleware.ServeHTTP(c.ProductStatusController.Unpublish))
+	c.Router.POST("/product/:productID/archive", c.AuthMiddleware.ServeHTTP(c.ProductStatusController.Archive))
+	c.Router.GET("/product/:productID/status-history", c.AuthMiddleware.ServeHTTP(c.ProductStatusController.FindHistory))
+	c.Router.GET("/product/:productID/history", c.AuthMiddleware.ServeHTTP(c.ProductHistoryController.FindChanges))
+	c.Router.GET("/product/:productID/as-of", c.AuthMiddleware.ServeHTTP(c.ProductHistoryController.FindAsOf))
+	c.Router.POST("/product/:productID/reservations", c.AuthMiddleware.ServeHTTP(c.StockReservationController.Reserve))
 	c.Router.POST("/product", c.AuthMiddleware.ServeExternalService(c.ProductController.Create))
+	c.Router.POST("/import", c.AuthMiddleware.ServeExternalService(c.ProductImportController.Create))
+	c.Router.GET("/import/:importID", c.AuthMiddleware.ServeHTTP(c.ProductImportController.FindById))
+	c.Router.GET("/import/:importID/errors", c.AuthMiddleware.ServeHTTP(c.ProductImportController.FindErrors))
 	c.Router.PATCH("/product/:productID", c.AuthMiddleware.ServeHTTP(c.ProductController.Update))
 	c.Router.DELETE("/product/:productID", c.AuthMiddleware.ServeHTTP(c.ProductController.Delete))
+	c.Router.POST("/product/:productID/restore", c.AuthMiddleware.ServeHTTP(c.ProductController.Restore))
+	c.Router.GET("/reservation/:reservationID", c.AuthMiddleware.ServeHTTP(c.StockReservationController.FindById))
+	c.Router.POST("/reservation/:reservationID/commit", c.AuthMiddleware.ServeHTTP(c.StockReservationController.Commit))
+	c.Router.POST("/reservation/:reservationID/release", c.AuthMiddleware.ServeHTTP(c.StockReservationController.Release))
+	c.Router.GET("/seller/:sellerID/stock-threshold", c.AuthMiddleware.ServeHTTP(c.StockController.FindThreshold))
+	c.Router.PUT("/seller/:sellerID/stock-threshold", c.AuthMiddleware.ServeHTTP(c.StockController.UpdateThreshold))
+	c.Router.GET("/seller/:sellerID/stats", c.AuthMiddleware.ServeHTTP(c.SellerStatsController.FindSellerStats))
+	c.Router.POST("/webhook", c.AuthMiddleware.ServeHTTP(c.WebhookController.Create))
+	c.Router.GET("/webhook", c.AuthMiddleware.ServeHTTP(c.WebhookController.FindAll))
+	c.Router.DELETE("/webhook/:webhookID", c.AuthMiddleware.ServeHTTP(c.WebhookController.Delete))
+	c.Router.POST("/webhook/:webhookID/enable", c.AuthMiddleware.ServeHTTP(c.WebhookController.Enable))
+	c.Router.POST("/webhook/:webhookID/disable", c.AuthMiddleware.ServeHTTP(c.WebhookController.Disable))
+	c.Router.GET("/webhook/:webhookID/deliveries", c.AuthMiddleware.ServeHTTP(c.WebhookController.FindDeliveries))
+	c.Router.GET("/webhook/:webhookID/deliveries/:deliveryID", c.AuthMiddleware.ServeHTTP(c.WebhookController.FindDelivery))
+	c.Router.POST("/webhook/:webhookID/deliveries/:deliveryID/replay", c.AuthMiddleware.ServeHTTP(c.WebhookController.Replay))
+}
+
+// productIDOr serves fixed paths such as /product/search. httprouter does not
+// allow a static segment next to the :productID wildcard, so these are looked
+// up by the wildcard value before falling back to handle.
+func productIDOr(static map[string]httprouter.Handle, handle httprouter.Handle) httprouter.Handle {
+	return func(writer nethttp.ResponseWriter, request *nethttp.Request, params httprouter.Params) {
+		if staticHandle, ok := static[params.ByName("productID")]; ok {
+			staticHandle(writer, request, params)
+			return
+		}
+
+		handle(writer, request, params)
+	}
 }
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/web"
+	"gocdc/internal/usecase"
+	"net/http"
+)
+
+type SearchAnalyticsController struct {
+	SearchAnalyticsUsecase *usecase.SearchAnalyticsUsecase
+	Log                    *zerolog.Logger
+}
+
+func NewSearchAnalyticsController(searchAnalyticsUsecase *usecase.SearchAnalyticsUsecase, zerolog *zerolog.Logger) *SearchAnalyticsController {
+	return &SearchAnalyticsController{
+		SearchAnalyticsUsecase: searchAnalyticsUsecase,
+		Log:                    zerolog,
+	}
+}
+
+func (controller SearchAnalyticsController) FindReport(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	query := request.URL.Query()
+
+	reportResponse, err := controller.SearchAnalyticsUsecase.FindReport(request.Context(), query.Get("period"), queryInt(query.Get("limit"), 20))
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   reportResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/web"
+	"gocdc/internal/usecase"
+	"net/http"
+)
+
+type SellerStatsController struct {
+	SellerStatsUsecase *usecase.SellerStatsUsecase
+	Log                *zerolog.Logger
+}
+
+func NewSellerStatsController(sellerStatsUsecase *usecase.SellerStatsUsecase, zerolog *zerolog.Logger) *SellerStatsController {
+	return &SellerStatsController{
+		SellerStatsUsecase: sellerStatsUsecase,
+		Log:                zerolog,
+	}
+}
+
+func (controller SellerStatsController) FindSellerStats(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	sellerID := params.ByName("sellerID")
+	if sellerID != userUUID {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusForbidden)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusForbidden,
+			Status: "Forbidden",
+			Data:   "not allowed to access other seller stats",
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	statsResponse, err := controller.SellerStatsUsecase.FindSellerStats(request.Context(), sellerID)
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   statsResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/web"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/usecase"
+	"net/http"
+)
+
+type StockController struct {
+	StockUsecase *usecase.StockUsecase
+	Log          *zerolog.Logger
+}
+
+func NewStockController(stockUsecase *usecase.StockUsecase, zerolog *zerolog.Logger) *StockController {
+	return &StockController{
+		StockUsecase: stockUsecase,
+		Log:          zerolog,
+	}
+}
+
+func (controller StockController) FindThreshold(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	sellerID := params.ByName("sellerID")
+	if sellerID != userUUID {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusForbidden)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusForbidden,
+			Status: "Forbidden",
+			Data:   "not allowed to access other seller settings",
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	thresholdResponse := controller.StockUsecase.FindThreshold(request.Context(), sellerID)
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   thresholdResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller StockController) UpdateThreshold(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	sellerID := params.ByName("sellerID")
+	if sellerID != userUUID {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusForbidden)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusForbidden,
+			Status: "Forbidden",
+			Data:   "not allowed to access other seller settings",
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	thresholdUpdateRequest := product.StockThresholdUpdateRequest{}
+	helper.ReadFromRequestBody(request, &thresholdUpdateRequest)
+
+	thresholdResponse, err := controller.StockUsecase.UpdateThreshold(request.Context(), thresholdUpdateRequest, sellerID)
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   thresholdResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
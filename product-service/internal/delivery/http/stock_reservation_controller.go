@@ -0,0 +1,190 @@
+package http
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/web"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/usecase"
+	"net/http"
+)
+
+type StockReservationController struct {
+	StockReservationUsecase *usecase.StockReservationUsecase
+	Log                     *zerolog.Logger
+}
+
+func NewStockReservationController(stockReservationUsecase *usecase.StockReservationUsecase, zerolog *zerolog.Logger) *StockReservationController {
+	return &StockReservationController{
+		StockReservationUsecase: stockReservationUsecase,
+		Log:                     zerolog,
+	}
+}
+
+func (controller StockReservationController) Reserve(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	stockReservationRequest := product.StockReservationRequest{}
+	helper.ReadFromRequestBody(request, &stockReservationRequest)
+
+	reservationResponse, err := controller.StockReservationUsecase.Reserve(request.Context(), userUUID, queryInt(params.ByName("productID"), 0), stockReservationRequest)
+	if err != nil {
+		if err.Error() == "product not found" || err.Error() == "variant not found" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusNotFound)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusNotFound,
+				Status: "Not Found",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		if err.Error() == "insufficient stock" || err.Error() == "reservation id is already used" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusConflict)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusConflict,
+				Status: "Conflict",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   reservationResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller StockReservationController) Commit(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	reservationResponse, err := controller.StockReservationUsecase.Commit(request.Context(), userUUID, params.ByName("reservationID"))
+	if err != nil {
+		if err.Error() == "reservation not found" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusNotFound)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusNotFound,
+				Status: "Not Found",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusConflict)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusConflict,
+			Status: "Conflict",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   reservationResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller StockReservationController) Release(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	reservationResponse, err := controller.StockReservationUsecase.Release(request.Context(), userUUID, params.ByName("reservationID"))
+	if err != nil {
+		if err.Error() == "reservation not found" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusNotFound)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusNotFound,
+				Status: "Not Found",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusConflict)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusConflict,
+			Status: "Conflict",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   reservationResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller StockReservationController) FindById(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	reservationResponse, err := controller.StockReservationUsecase.FindById(request.Context(), userUUID, params.ByName("reservationID"))
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   reservationResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
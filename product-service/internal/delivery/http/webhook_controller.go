@@ -0,0 +1,222 @@
+package http
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/web"
+	"gocdc/internal/model/web/webhook"
+	"gocdc/internal/usecase"
+	"net/http"
+)
+
+type WebhookController struct {
+	WebhookUsecase *usecase.WebhookUsecase
+	Log            *zerolog.Logger
+}
+
+func NewWebhookController(webhookUsecase *usecase.WebhookUsecase, zerolog *zerolog.Logger) *WebhookController {
+	return &WebhookController{
+		WebhookUsecase: webhookUsecase,
+		Log:            zerolog,
+	}
+}
+
+func (controller WebhookController) Create(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	webhookCreateRequest := webhook.WebhookCreateRequest{}
+	helper.ReadFromRequestBody(request, &webhookCreateRequest)
+
+	webhookResponse, err := controller.WebhookUsecase.Create(request.Context(), webhookCreateRequest, userUUID)
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   webhookResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller WebhookController) FindAll(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	webhookResponses := controller.WebhookUsecase.FindAll(request.Context(), userUUID)
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   webhookResponses,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller WebhookController) Enable(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	controller.setActive(writer, request, params, true)
+}
+
+func (controller WebhookController) Disable(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	controller.setActive(writer, request, params, false)
+}
+
+func (controller WebhookController) setActive(writer http.ResponseWriter, request *http.Request, params httprouter.Params, active bool) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	err := controller.WebhookUsecase.SetActive(request.Context(), userUUID, params.ByName("webhookID"), active)
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller WebhookController) Delete(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	err := controller.WebhookUsecase.Delete(request.Context(), userUUID, params.ByName("webhookID"))
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller WebhookController) FindDeliveries(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	deliveryResponses, err := controller.WebhookUsecase.FindDeliveries(request.Context(), userUUID, params.ByName("webhookID"))
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   deliveryResponses,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller WebhookController) FindDelivery(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	deliveryResponse, err := controller.WebhookUsecase.FindDelivery(request.Context(), userUUID, params.ByName("webhookID"), params.ByName("deliveryID"))
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   deliveryResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller WebhookController) Replay(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+
+	deliveryResponse, err := controller.WebhookUsecase.Replay(request.Context(), userUUID, params.ByName("webhookID"), params.ByName("deliveryID"))
+	if err != nil {
+		if err.Error() == "webhook is disabled" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusBadRequest)
+
+			webResponse := web.WebResponse{
+				Code:   http.StatusBadRequest,
+				Status: "Bad Request",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   deliveryResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
@@ -0,0 +1,277 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/rs/zerolog"
+	"sync"
+	"time"
+)
+
+const consumerMaxAttempts = 5
+
+type ConsumerHandler func(message *sarama.ConsumerMessage) error
+
+// AckedConsumerHandler hands a message on to work that finishes later. A
+// handler that returns nil must call ack exactly once, when the message's
+// effect is durable.
+type AckedConsumerHandler func(message *sarama.ConsumerMessage, ack func()) error
+
+// ConsumeTopic feeds every message of topic to handler as a member of group.
+// The offset of a message is committed once it is handled, so a restart picks
+// up where the group stopped. A handler that returns an error or panics is
+// retried with backoff, a message that keeps failing is copied to <topic>.dlq,
+// with name and the error in its headers, and then skipped.
+func ConsumeTopic(ctx context.Context, group sarama.ConsumerGroup, name string, topic string, producer sarama.SyncProducer, log *zerolog.Logger, handler ConsumerHandler) {
+	consume(ctx, group, topic, consumerGroupHandler{
+		Name:     name,
+		Producer: producer,
+		Log:      log,
+		Handler:  handler,
+	})
+}
+
+// ConsumeTopicAcked is ConsumeTopic for handlers that finish a message after
+// they return. Offsets are committed in the order the messages arrived, up to
+// the first one that is not acked yet, so a restart replays everything whose
+// work had not landed.
+func ConsumeTopicAcked(ctx context.Context, group sarama.ConsumerGroup, name string, topic string, producer sarama.SyncProducer, log *zerolog.Logger, handler AckedConsumerHandler) {
+	consume(ctx, group, topic, consumerGroupHandler{
+		Name:         name,
+		Producer:     producer,
+		Log:          log,
+		AckedHandler: handler,
+	})
+}
+
+// ConsumeTopicBroadcast feeds every message of topic to handler without a
+// consumer group, from the newest offset of every partition. Each process sees
+// every message and nothing is kept on the broker, which suits state that lives
+// in the process and starts out empty. Failures are retried and dead-lettered
+// as in ConsumeTopic.
+func ConsumeTopicBroadcast(ctx context.Context, consumer sarama.Consumer, name string, topic string, producer sarama.SyncProducer, log *zerolog.Logger, handler ConsumerHandler) {
+	partitions, err := consumer.Partitions(topic)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to get partitions of topic " + topic)
+	}
+
+	groupHandler := consumerGroupHandler{
+		Name:     name,
+		Producer: producer,
+		Log:      log,
+		Handler:  handler,
+	}
+
+	for _, partition := range partitions {
+		partitionConsumer, err := consumer.ConsumePartition(topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to consume topic " + topic)
+		}
+
+		go func() {
+			defer partitionConsumer.Close()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case message, ok := <-partitionConsumer.Messages():
+					if !ok {
+						return
+					}
+
+					_, err := groupHandler.handle(ctx, message, handler)
+					if err != nil {
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	log.Info().Msg("Consuming topic " + topic + " as " + name)
+}
+
+func consume(ctx context.Context, group sarama.ConsumerGroup, topic string, groupHandler consumerGroupHandler) {
+	name, log := groupHandler.Name, groupHandler.Log
+
+	go func() {
+		defer group.Close()
+
+		for {
+			// returns on every rebalance, the group is joined again
+			err := group.Consume(ctx, []string{topic}, groupHandler)
+			if err != nil {
+				log.Error().Err(err).Msg("Kafka consumer group " + name + " failed on topic " + topic)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			if err != nil {
+				time.Sleep(consumerBackoff(1))
+			}
+		}
+	}()
+
+	log.Info().Msg("Consuming topic " + topic + " as " + name)
+}
+
+type consumerGroupHandler struct {
+	Name         string
+	Producer     sarama.SyncProducer
+	Log          *zerolog.Logger
+	Handler      ConsumerHandler
+	AckedHandler AckedConsumerHandler
+}
+
+func (groupHandler consumerGroupHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (groupHandler consumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (groupHandler consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	offsets := &offsetTracker{Session: session, done: map[int64]bool{}}
+
+	for message := range claim.Messages() {
+		offsets.add(message)
+		ack := func() { offsets.ack(message) }
+
+		handler := groupHandler.Handler
+		if groupHandler.AckedHandler != nil {
+			handler = func(message *sarama.ConsumerMessage) error {
+				return groupHandler.AckedHandler(message, ack)
+			}
+		}
+
+		handled, err := groupHandler.handle(session.Context(), message, handler)
+		if err != nil {
+			// the partition was revoked mid retry, its next owner handles the message
+			return nil
+		}
+
+		// an acked handler owns the ack of a message it took, a dead-lettered
+		// message is done here
+		if groupHandler.AckedHandler == nil || !handled {
+			ack()
+		}
+	}
+
+	return nil
+}
+
+// handle reports whether handler took the message, false means it was
+// dead-lettered. It only returns an error when ctx ends before either.
+func (groupHandler consumerGroupHandler) handle(ctx context.Context, message *sarama.ConsumerMessage, handler ConsumerHandler) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		err := handleMessage(message, handler)
+		if err == nil {
+			return true, nil
+		}
+
+		if attempt >= consumerMaxAttempts {
+			return false, groupHandler.deadLetter(ctx, message, err)
+		}
+
+		groupHandler.Log.Warn().Err(err).Msg(fmt.Sprintf("Failed to handle message from %s partition %d offset %d as %s, attempt %d", message.Topic, message.Partition, message.Offset, groupHandler.Name, attempt))
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(consumerBackoff(attempt)):
+		}
+	}
+}
+
+// deadLetter keeps trying until the message is in the DLQ, it is never
+// skipped without a copy.
+func (groupHandler consumerGroupHandler) deadLetter(ctx context.Context, message *sarama.ConsumerMessage, reason error) error {
+	groupHandler.Log.Error().Err(reason).Msg(fmt.Sprintf("Dead-lettering message from %s partition %d offset %d as %s", message.Topic, message.Partition, message.Offset, groupHandler.Name))
+
+	for attempt := 1; ; attempt++ {
+		_, _, err := groupHandler.Producer.SendMessage(&sarama.ProducerMessage{
+			Topic: message.Topic + ".dlq",
+			Key:   sarama.ByteEncoder(message.Key),
+			Value: sarama.ByteEncoder(message.Value),
+			Headers: []sarama.RecordHeader{
+				{Key: []byte("consumer"), Value: []byte(groupHandler.Name)},
+				{Key: []byte("error"), Value: []byte(reason.Error())},
+				{Key: []byte("partition"), Value: []byte(fmt.Sprint(message.Partition))},
+				{Key: []byte("offset"), Value: []byte(fmt.Sprint(message.Offset))},
+			},
+		})
+
+		if err == nil {
+			return nil
+		}
+
+		groupHandler.Log.Error().Err(err).Msg("failed to produce an event to kafka broker")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(consumerBackoff(attempt)):
+		}
+	}
+}
+
+func handleMessage(message *sarama.ConsumerMessage, handler ConsumerHandler) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("%v", recovered)
+		}
+	}()
+
+	return handler(message)
+}
+
+// offsetTracker marks the messages of one claim in the order they arrived,
+// whatever order they are acked in, so the committed offset never passes a
+// message that is still in flight.
+type offsetTracker struct {
+	Session sarama.ConsumerGroupSession
+	mutex   sync.Mutex
+	pending []*sarama.ConsumerMessage
+	done    map[int64]bool
+}
+
+func (tracker *offsetTracker) add(message *sarama.ConsumerMessage) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	tracker.pending = append(tracker.pending, message)
+}
+
+func (tracker *offsetTracker) ack(message *sarama.ConsumerMessage) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	tracker.done[message.Offset] = true
+
+	for len(tracker.pending) > 0 && tracker.done[tracker.pending[0].Offset] {
+		tracker.Session.MarkMessage(tracker.pending[0], "")
+		delete(tracker.done, tracker.pending[0].Offset)
+		tracker.pending = tracker.pending[1:]
+	}
+}
+
+// consumerBackoff waits 1s before the first retry, doubling up to 30s.
+func consumerBackoff(attempt int) time.Duration {
+	backoff := time.Second
+	for i := 1; i < attempt && backoff < 30*time.Second; i++ {
+		backoff *= 2
+	}
+
+	if backoff > 30*time.Second {
+		backoff = 30 * time.Second
+	}
+
+	return backoff
+}
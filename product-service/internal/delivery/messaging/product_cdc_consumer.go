@@ -0,0 +1,121 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/IBM/sarama"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/usecase"
+)
+
+// ProductCDCConsumer reads dbz.public.products. Every projection fed by the
+// product change stream consumes it in a consumer group of its own, so each
+// keeps its own offsets and a failing one is retried without holding back or
+// replaying the others. The product cache is not among them, whether it is
+// shared decides how it is fed.
+type ProductCDCConsumer struct {
+	ProductUsecase     *usecase.ProductUsecase
+	SearchUsecase      *usecase.ProductSearchUsecase
+	StockUsecase       *usecase.StockUsecase
+	StatusUsecase      *usecase.ProductStatusUsecase
+	HistoryUsecase     *usecase.ProductHistoryUsecase
+	SellerStatsUsecase *usecase.SellerStatsUsecase
+	WebhookUsecase     *usecase.WebhookUsecase
+	Log                *zerolog.Logger
+}
+
+// ProductCDCProjection is a consumer group of dbz.public.products. A group
+// without committed offsets starts at InitialOffset.
+type ProductCDCProjection struct {
+	Name          string
+	InitialOffset int64
+	Handler       ConsumerHandler
+}
+
+func NewProductCDCConsumer(productUsecase *usecase.ProductUsecase, searchUsecase *usecase.ProductSearchUsecase, stockUsecase *usecase.StockUsecase, statusUsecase *usecase.ProductStatusUsecase, historyUsecase *usecase.ProductHistoryUsecase, sellerStatsUsecase *usecase.SellerStatsUsecase, webhookUsecase *usecase.WebhookUsecase, zerolog *zerolog.Logger) *ProductCDCConsumer {
+	return &ProductCDCConsumer{
+		ProductUsecase:     productUsecase,
+		SearchUsecase:      searchUsecase,
+		StockUsecase:       stockUsecase,
+		StatusUsecase:      statusUsecase,
+		HistoryUsecase:     historyUsecase,
+		SellerStatsUsecase: sellerStatsUsecase,
+		WebhookUsecase:     webhookUsecase,
+		Log:                zerolog,
+	}
+}
+
+// Projections lists the projections of the change stream. Those that keep
+// state replay the retained stream when they start for the first time,
+// stock and webhook notifications only go out for new changes.
+func (consumer ProductCDCConsumer) Projections() []ProductCDCProjection {
+	return []ProductCDCProjection{
+		{Name: "cdc.search-suggestions", InitialOffset: sarama.OffsetOldest, Handler: consumer.Project(consumer.SearchUsecase.HandleProductChange)},
+		{Name: "cdc.stock", InitialOffset: sarama.OffsetNewest, Handler: consumer.Project(consumer.StockUsecase.HandleProductChange)},
+		{Name: "cdc.product-status", InitialOffset: sarama.OffsetOldest, Handler: consumer.Project(consumer.StatusUsecase.HandleProductChange)},
+		{Name: "cdc.product-history", InitialOffset: sarama.OffsetOldest, Handler: consumer.Project(consumer.HistoryUsecase.HandleProductChange)},
+		{Name: "cdc.seller-stats", InitialOffset: sarama.OffsetOldest, Handler: consumer.Project(consumer.SellerStatsUsecase.HandleProductChange)},
+		{Name: "cdc.webhook", InitialOffset: sarama.OffsetNewest, Handler: consumer.Project(consumer.WebhookUsecase.HandleProductChange)},
+	}
+}
+
+// Project decodes change events for handle. A panic in handle is left to
+// ConsumeTopic, which retries the event.
+func (consumer ProductCDCConsumer) Project(handle func(ctx context.Context, payload product.ProductCDCPayload)) ConsumerHandler {
+	return func(message *sarama.ConsumerMessage) error {
+		// tombstone following a delete
+		if message.Value == nil {
+			return nil
+		}
+
+		payload, err := DecodeProductCDC(message.Value)
+		if err != nil {
+			consumer.Log.Warn().Err(err).Msg("failed to unmarshal product change event")
+			return err
+		}
+
+		handle(context.Background(), payload)
+
+		return nil
+	}
+}
+
+// ProjectAcked is Project for handlers that finish an event later and call
+// ack once it has landed. Tombstones are acked right away.
+func (consumer ProductCDCConsumer) ProjectAcked(handle func(ctx context.Context, payload product.ProductCDCPayload, ack func())) AckedConsumerHandler {
+	return func(message *sarama.ConsumerMessage, ack func()) error {
+		// tombstone following a delete
+		if message.Value == nil {
+			ack()
+			return nil
+		}
+
+		payload, err := DecodeProductCDC(message.Value)
+		if err != nil {
+			consumer.Log.Warn().Err(err).Msg("failed to unmarshal product change event")
+			return err
+		}
+
+		handle(context.Background(), payload, ack)
+
+		return nil
+	}
+}
+
+func DecodeProductCDC(value []byte) (product.ProductCDCPayload, error) {
+	envelope := product.ProductCDCEnvelope{}
+	err := json.Unmarshal(value, &envelope)
+	if err != nil {
+		return product.ProductCDCPayload{}, err
+	}
+
+	if envelope.Payload != nil {
+		return *envelope.Payload, nil
+	}
+
+	payload := product.ProductCDCPayload{}
+	err = json.Unmarshal(value, &payload)
+
+	return payload, err
+}
@@ -0,0 +1,35 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/IBM/sarama"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/usecase"
+)
+
+type SearchAnalyticsConsumer struct {
+	SearchAnalyticsUsecase *usecase.SearchAnalyticsUsecase
+	Log                    *zerolog.Logger
+}
+
+func NewSearchAnalyticsConsumer(searchAnalyticsUsecase *usecase.SearchAnalyticsUsecase, zerolog *zerolog.Logger) *SearchAnalyticsConsumer {
+	return &SearchAnalyticsConsumer{
+		SearchAnalyticsUsecase: searchAnalyticsUsecase,
+		Log:                    zerolog,
+	}
+}
+
+func (consumer SearchAnalyticsConsumer) ConsumeProductSearch(message *sarama.ConsumerMessage) error {
+	searchEvent := product.ProductSearchEvent{}
+	err := json.Unmarshal(message.Value, &searchEvent)
+	if err != nil {
+		consumer.Log.Warn().Err(err).Msg("failed to unmarshal product search event")
+		return err
+	}
+
+	consumer.SearchAnalyticsUsecase.HandleSearchEvent(context.Background(), searchEvent)
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/IBM/sarama"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/web/user"
+	"gocdc/internal/usecase"
+)
+
+type UserDeletionConsumer struct {
+	UserDeletionUsecase *usecase.UserDeletionUsecase
+	Log                 *zerolog.Logger
+}
+
+func NewUserDeletionConsumer(userDeletionUsecase *usecase.UserDeletionUsecase, zerolog *zerolog.Logger) *UserDeletionConsumer {
+	return &UserDeletionConsumer{
+		UserDeletionUsecase: userDeletionUsecase,
+		Log:                 zerolog,
+	}
+}
+
+func (consumer UserDeletionConsumer) ConsumeUserDeleted(message *sarama.ConsumerMessage) error {
+	userDeletedEvent := user.UserDeletedEvent{}
+	err := json.Unmarshal(message.Value, &userDeletedEvent)
+	if err != nil {
+		consumer.Log.Warn().Err(err).Msg("failed to unmarshal user deleted event")
+		return err
+	}
+
+	return consumer.UserDeletionUsecase.ArchiveSellerProducts(context.Background(), userDeletedEvent)
+}
+
+func (consumer UserDeletionConsumer) ConsumeCompensate(message *sarama.ConsumerMessage) error {
+	userDeletedEvent := user.UserDeletedEvent{}
+	err := json.Unmarshal(message.Value, &userDeletedEvent)
+	if err != nil {
+		consumer.Log.Warn().Err(err).Msg("failed to unmarshal user deletion compensate event")
+		return err
+	}
+
+	return consumer.UserDeletionUsecase.RestoreSellerProducts(context.Background(), userDeletedEvent)
+}
@@ -0,0 +1,37 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/IBM/sarama"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/usecase"
+)
+
+type WebhookConsumer struct {
+	WebhookUsecase *usecase.WebhookUsecase
+	Log            *zerolog.Logger
+}
+
+func NewWebhookConsumer(webhookUsecase *usecase.WebhookUsecase, zerolog *zerolog.Logger) *WebhookConsumer {
+	return &WebhookConsumer{
+		WebhookUsecase: webhookUsecase,
+		Log:            zerolog,
+	}
+}
+
+// ConsumeProductActivity forwards product.activity to the acting user's own
+// endpoints only, the event carries their email address.
+func (consumer WebhookConsumer) ConsumeProductActivity(message *sarama.ConsumerMessage) error {
+	productEvent := product.ProductEvent{}
+	err := json.Unmarshal(message.Value, &productEvent)
+	if err != nil {
+		consumer.Log.Warn().Err(err).Msg("failed to unmarshal product activity event")
+		return err
+	}
+
+	consumer.WebhookUsecase.Enqueue(context.Background(), "product.activity", productEvent.Id, message.Value)
+
+	return nil
+}
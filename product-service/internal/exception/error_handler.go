@@ -7,6 +7,12 @@ import (
 )
 
 func ErrorHandler(writer http.ResponseWriter, request *http.Request, err interface{}) {
+	// a handler that already sent part of its response gives up on it, the
+	// server then drops the connection without answering
+	if err == http.ErrAbortHandler {
+		panic(err)
+	}
+
 	writer.Header().Set("Content-Type", "application/json")
 	writer.WriteHeader(http.StatusInternalServerError)
 
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// Category is a node of the category tree, Parent_id is nil for a root.
+type Category struct {
+	Id         int
+	Parent_id  *int
+	Name       string
+	Created_at *time.Time
+	Updated_at *time.Time
+}
@@ -13,6 +13,7 @@ type Product struct {
 	Size            string
 	Status          string
 	Description     string
+	Category_id     *int
 	Created_at      *time.Time
 	Updated_at      *time.Time
 }
@@ -0,0 +1,88 @@
+package domain
+
+import "time"
+
+// ProductDocument is a product as stored in the Elasticsearch product index.
+// Category_ids holds the product's category and all its ancestors, so a
+// category filter also matches products in descendant categories.
+type ProductDocument struct {
+	Id            int        `json:"id"`
+	Seller_id     string     `json:"seller_id"`
+	Name          string     `json:"name"`
+	Quantity      int        `json:"quantity"`
+	Price         float64    `json:"price"`
+	Weight        int        `json:"weight"`
+	Size          string     `json:"size"`
+	Status        string     `json:"status"`
+	Description   string     `json:"description"`
+	Category_id   *int       `json:"category_id"`
+	Category_ids  []int      `json:"category_ids"`
+	Category_path []string   `json:"category_path"`
+	Tags          []string   `json:"tags"`
+	Created_at    *time.Time `json:"created_at"`
+	Updated_at    *time.Time `json:"updated_at"`
+}
+
+// ProductSearchQuery is a product search as any search backend receives it.
+type ProductSearchQuery struct {
+	Text        string
+	Category_id int
+	Sort        string
+	Offset      int
+	Limit       int
+	Sizes       []string
+	Statuses    []string
+	Min_price   float64
+	Max_price   float64
+	Min_weight  int
+	Max_weight  int
+}
+
+type ProductSearchResult struct {
+	Backend    string
+	Total      int64
+	Documents  []ProductDocument
+	Highlights map[int]ProductSearchHighlight
+	Facets     map[string][]ProductSearchFacetBucket
+}
+
+// ProductSearchHighlight holds the fragments of a hit that matched the search
+// text, keyed in ProductSearchResult by product id. Fragments are HTML escaped
+// with the matched terms wrapped in <em>.
+type ProductSearchHighlight struct {
+	Name        []string
+	Description []string
+}
+
+// ProductSearchRange is a facet bucket boundary, a zero bound is open.
+type ProductSearchRange struct {
+	Key  string
+	From float64
+	To   float64
+}
+
+var ProductFacetDimensions = []string{"size", "status", "price", "weight"}
+
+// price buckets are in rupiah, weight buckets in grams
+var ProductPriceRanges = []ProductSearchRange{
+	{Key: "0-50000", To: 50000},
+	{Key: "50000-100000", From: 50000, To: 100000},
+	{Key: "100000-250000", From: 100000, To: 250000},
+	{Key: "250000-500000", From: 250000, To: 500000},
+	{Key: "500000-1000000", From: 500000, To: 1000000},
+	{Key: "1000000+", From: 1000000},
+}
+
+var ProductWeightRanges = []ProductSearchRange{
+	{Key: "0-250", To: 250},
+	{Key: "250-1000", From: 250, To: 1000},
+	{Key: "1000-5000", From: 1000, To: 5000},
+	{Key: "5000+", From: 5000},
+}
+
+type ProductSearchFacetBucket struct {
+	Key       string   `json:"key"`
+	From      *float64 `json:"from"`
+	To        *float64 `json:"to"`
+	Doc_count int64    `json:"doc_count"`
+}
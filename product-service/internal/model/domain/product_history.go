@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// ProductHistory is one version of a product taken from the change stream.
+// Snapshot holds the row after the change as JSON, Changes the fields that
+// differ from the previous version. Operation is snapshot, created, updated,
+// deleted, restored or purged.
+type ProductHistory struct {
+	Id         int64
+	Product_id int
+	Operation  string
+	Snapshot   string
+	Changes    string
+	Source_lsn int64
+	Changed_at *time.Time
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// ProductImage is one picture of a product gallery, position 0 is the cover.
+type ProductImage struct {
+	Id           string
+	Product_id   int
+	Storage_key  string
+	Url          string
+	Content_type string
+	Size_bytes   int
+	Position     int
+	Created_at   *time.Time
+}
+
+// ProductImageUpload is an uploaded file before it is checked and stored.
+type ProductImageUpload struct {
+	Filename string
+	Data     []byte
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// ProductImport is a bulk import job. Data holds the uploaded file until the
+// job finishes, Processed_rows how far the worker got, so a job picked up
+// again after its lease ran out continues where it stopped.
+type ProductImport struct {
+	Id             string
+	Seller_id      string
+	Format         string
+	Status         string
+	Data           []byte
+	Total_rows     int
+	Processed_rows int
+	Imported_rows  int
+	Failed_rows    int
+	Attempts       int
+	Error          string
+	Lease_until    *time.Time
+	Created_at     *time.Time
+	Updated_at     *time.Time
+	Finished_at    *time.Time
+}
+
+type ProductImportError struct {
+	Row_number int
+	Error      string
+}
@@ -0,0 +1,23 @@
+package domain
+
+// ProductListQuery is one page of the product listing. After is the position
+// of the last product of the previous page, nil on the first page.
+type ProductListQuery struct {
+	Seller_id   string
+	Category_id int
+	Statuses    []string
+	Sizes       []string
+	Min_price   float64
+	Max_price   float64
+	Sort        string
+	Limit       int
+	After       *ProductCursor
+}
+
+// ProductCursor is a position in the listing order. Value is the sort column
+// of the product as text, Id breaks ties between equal values.
+type ProductCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	Id    int    `json:"i"`
+}
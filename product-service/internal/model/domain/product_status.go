@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// ProductStatusTransitions lists where a product may go from each status. A
+// new product starts as Draft or Ready. Ready and SoldOut switch over by
+// themselves when stock runs out or comes back, Draft and Archived products
+// are hidden from listings and search.
+var ProductStatusTransitions = map[string][]string{
+	"Draft":    {"Ready", "Archived"},
+	"Ready":    {"Draft", "SoldOut", "Archived"},
+	"SoldOut":  {"Ready", "Draft", "Archived"},
+	"Archived": {"Draft"},
+}
+
+func CanTransitionProductStatus(from string, to string) bool {
+	for _, status := range ProductStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ProductStatusChange is one row of a product's status history. Actor_id is
+// nil for changes the service made itself, Reason tells which: manual, stock,
+// seller_deleted or seller_restored.
+type ProductStatusChange struct {
+	Id          int64
+	Product_id  int
+	Seller_id   string
+	From_status string
+	To_status   string
+	Reason      string
+	Actor_id    *string
+	Created_at  *time.Time
+}
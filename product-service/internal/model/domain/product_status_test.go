@@ -0,0 +1,36 @@
+package domain
+
+import "testing"
+
+func TestCanTransitionProductStatus(t *testing.T) {
+	statuses := []string{"Draft", "Ready", "SoldOut", "Archived"}
+
+	allowed := map[[2]string]bool{
+		{"Draft", "Ready"}:      true,
+		{"Draft", "Archived"}:   true,
+		{"Ready", "Draft"}:      true,
+		{"Ready", "SoldOut"}:    true,
+		{"Ready", "Archived"}:   true,
+		{"SoldOut", "Ready"}:    true,
+		{"SoldOut", "Draft"}:    true,
+		{"SoldOut", "Archived"}: true,
+		{"Archived", "Draft"}:   true,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			t.Run(from+" to "+to, func(t *testing.T) {
+				want := allowed[[2]string{from, to}]
+				if got := CanTransitionProductStatus(from, to); got != want {
+					t.Errorf("CanTransitionProductStatus(%q, %q) = %v, want %v", from, to, got, want)
+				}
+			})
+		}
+	}
+
+	for _, test := range [][2]string{{"", "Ready"}, {"Ready", ""}, {"Deleted", "Draft"}, {"ready", "soldout"}} {
+		if CanTransitionProductStatus(test[0], test[1]) {
+			t.Errorf("CanTransitionProductStatus(%q, %q) = true for an unknown status", test[0], test[1])
+		}
+	}
+}
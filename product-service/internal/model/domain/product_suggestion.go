@@ -0,0 +1,9 @@
+package domain
+
+// ProductSuggestion is one entry of the autocomplete index, either a product
+// name or a seller name. Popularity becomes the completion weight.
+type ProductSuggestion struct {
+	Type       string
+	Text       string
+	Popularity int
+}
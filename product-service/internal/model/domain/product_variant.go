@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// ProductVariant is one SKU of a product, a size and attribute combination
+// such as {"color": "red"} with its own price, weight and stock.
+type ProductVariant struct {
+	Id         int
+	Product_id int
+	Sku        string
+	Size       string
+	Attributes map[string]string
+	Price      float64
+	Weight     int
+	Quantity   int
+	Created_at *time.Time
+	Updated_at *time.Time
+}
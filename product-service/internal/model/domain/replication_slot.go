@@ -0,0 +1,10 @@
+package domain
+
+type ReplicationSlot struct {
+	Slot_name                 string
+	Active                    bool
+	Retained_wal_bytes        int64
+	Confirmed_flush_lag_bytes int64
+	Replay_lag_seconds        float64
+	Sender_state              string
+}
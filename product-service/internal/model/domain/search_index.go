@@ -0,0 +1,17 @@
+package domain
+
+// SearchIndexDefinition is a versioned index body kept in the repository. A
+// version bump means the live index has to be rebuilt.
+type SearchIndexDefinition struct {
+	Name       string
+	Version    int
+	Body       []byte
+	Properties map[string]interface{}
+}
+
+// SearchIndex is the concrete index an alias currently points at.
+type SearchIndex struct {
+	Name       string
+	Version    int
+	Properties map[string]interface{}
+}
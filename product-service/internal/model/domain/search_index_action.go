@@ -0,0 +1,22 @@
+package domain
+
+// SearchIndexAction is one product write waiting in the bulk indexer. Version
+// is the source LSN, Elasticsearch keeps the highest one it has seen so a
+// retried action never overwrites a newer write. Ack commits the change event
+// the action came from, it is called once the action is indexed or
+// dead-lettered.
+type SearchIndexAction struct {
+	Operation    string
+	Product_id   int
+	Version      int64
+	Body         []byte
+	Source_ts_ms int64
+	Attempts     int
+	Ack          func()
+}
+
+// SearchBulkItem is the outcome of one action of a bulk request.
+type SearchBulkItem struct {
+	Status int
+	Error  string
+}
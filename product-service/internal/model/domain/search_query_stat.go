@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// SearchQueryStat aggregates the searches for one normalised query within an
+// hour bucket.
+type SearchQueryStat struct {
+	Bucket           *time.Time
+	Query            string
+	Searches         int64
+	Zero_results     int64
+	Total_results    int64
+	Total_latency_ms int64
+}
@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+type SellerDeletion struct {
+	Saga_id    string
+	Seller_id  string
+	Status     string
+	Created_at *time.Time
+	Updated_at *time.Time
+}
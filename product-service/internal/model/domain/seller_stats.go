@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+type SellerStats struct {
+	Seller_id        string
+	Active_product   int
+	Sold_out_product int
+	Total_product    int
+	Inventory_value  float64
+	Last_updated_at  *time.Time
+}
+
+// SellerStatsProduct is the last contribution of a product to its seller's
+// stats, kept so replayed change events can be detected by LSN.
+type SellerStatsProduct struct {
+	Product_id int
+	Seller_id  string
+	Quantity   int
+	Price      float64
+	Status     string
+	Source_lsn int64
+}
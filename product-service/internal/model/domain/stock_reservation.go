@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// StockReservation holds Quantity units of a product, or of one of its
+// variants, for a buyer. The units leave the available stock when reserved,
+// stay out once Committed and go back when Released or Expired.
+type StockReservation struct {
+	Id         string
+	Product_id int
+	Variant_id *int
+	Buyer_id   string
+	Quantity   int
+	Status     string
+	Expires_at *time.Time
+	Created_at *time.Time
+	Updated_at *time.Time
+}
@@ -0,0 +1,9 @@
+package domain
+
+import "time"
+
+type StockThreshold struct {
+	Seller_id           string
+	Low_stock_threshold int
+	Updated_at          *time.Time
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+type WebhookEndpoint struct {
+	Id          string
+	User_id     string
+	Url         string
+	Secret      string
+	Active      bool
+	Event_types []string
+	Created_at  *time.Time
+	Updated_at  *time.Time
+}
+
+type WebhookDelivery struct {
+	Id              string
+	Endpoint_id     string
+	Event_type      string
+	Payload         string
+	Status          string
+	Attempts        int
+	Next_attempt_at *time.Time
+	Created_at      *time.Time
+	Updated_at      *time.Time
+}
+
+type WebhookDeliveryAttempt struct {
+	Id          int
+	Delivery_id string
+	Attempt     int
+	Status_code int
+	Error       string
+	Duration_ms int
+	Created_at  *time.Time
+}
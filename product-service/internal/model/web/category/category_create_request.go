@@ -0,0 +1,6 @@
+package category
+
+type CategoryCreateRequest struct {
+	Parent_id int    `validate:"min=0" json:"parent_id"`
+	Name      string `validate:"required,min=2,max=50" json:"name"`
+}
@@ -0,0 +1,16 @@
+package category
+
+import "time"
+
+// CategoryResponse lists Path from the root down to the category's parent.
+// The tree endpoint nests Children all the way down, a single category only
+// lists its direct children.
+type CategoryResponse struct {
+	Id         int                `json:"id"`
+	Parent_id  *int               `json:"parent_id"`
+	Name       string             `json:"name"`
+	Path       []CategoryResponse `json:"path,omitempty"`
+	Children   []CategoryResponse `json:"children"`
+	Created_at *time.Time         `json:"created_at"`
+	Updated_at *time.Time         `json:"updated_at"`
+}
@@ -0,0 +1,8 @@
+package category
+
+// CategoryUpdateRequest renames or moves a category. Parent_id -1 moves it to
+// the root, 0 leaves the parent as it is.
+type CategoryUpdateRequest struct {
+	Parent_id int    `validate:"min=-1" json:"parent_id,omitempty"`
+	Name      string `validate:"omitempty,min=2,max=50" json:"name,omitempty"`
+}
@@ -0,0 +1,17 @@
+package web
+
+import "time"
+
+type CDCHealthResponse struct {
+	Status                    string     `json:"status"`
+	Problems                  []string   `json:"problems"`
+	Slot_name                 string     `json:"slot_name"`
+	Slot_active               bool       `json:"slot_active"`
+	Sender_state              string     `json:"sender_state"`
+	Retained_wal_bytes        int64      `json:"retained_wal_bytes"`
+	Confirmed_flush_lag_bytes int64      `json:"confirmed_flush_lag_bytes"`
+	Replay_lag_seconds        float64    `json:"replay_lag_seconds"`
+	End_to_end_lag_ms         int64      `json:"end_to_end_lag_ms"`
+	Last_event_at             *time.Time `json:"last_event_at"`
+	Checked_at                *time.Time `json:"checked_at"`
+}
@@ -0,0 +1,42 @@
+package product
+
+// ProductCDCEnvelope is a Debezium change event from dbz.public.products. The
+// connector wraps the payload in a schema envelope unless schemas are disabled
+// on the converter, both shapes are accepted.
+type ProductCDCEnvelope struct {
+	Payload *ProductCDCPayload `json:"payload"`
+}
+
+type ProductCDCPayload struct {
+	Before *ProductCDCRow   `json:"before"`
+	After  *ProductCDCRow   `json:"after"`
+	Source ProductCDCSource `json:"source"`
+	Op     string           `json:"op"`
+	Ts_ms  int64            `json:"ts_ms"`
+}
+
+type ProductCDCSource struct {
+	Ts_ms    int64  `json:"ts_ms"`
+	Snapshot string `json:"snapshot"`
+	Lsn      int64  `json:"lsn"`
+	TxId     int64  `json:"txId"`
+}
+
+// ProductCDCRow mirrors the products table. Timestamps arrive as microseconds
+// since epoch (io.debezium.time.MicroTimestamp).
+type ProductCDCRow struct {
+	Id              int     `json:"id"`
+	Seller_id       string  `json:"seller_id"`
+	Name            string  `json:"name"`
+	Product_picture string  `json:"product_picture"`
+	Quantity        int     `json:"quantity"`
+	Price           float64 `json:"price"`
+	Weight          int     `json:"weight"`
+	Size            string  `json:"size"`
+	Status          string  `json:"status"`
+	Description     string  `json:"description"`
+	Category_id     *int    `json:"category_id"`
+	Created_at      int64   `json:"created_at"`
+	Updated_at      int64   `json:"updated_at"`
+	Deleted_at      *int64  `json:"deleted_at"`
+}
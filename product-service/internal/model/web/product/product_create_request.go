@@ -1,11 +1,14 @@
 package product
 
 type ProductCreateRequest struct {
-	Name            string  `validate:"required,min=5,max=20" json:"name"`
-	Product_picture string  `validate:"required,min=20,max=255" json:"product_picture"`
-	Quantity        int     `validate:"required,min=1" json:"quantity"`
-	Price           float64 `validate:"required,min=1" json:"price"`
-	Weight          int     `validate:"required,min=1" json:"weight"`
-	Size            string  `validate:"required,min=1,max=4" json:"size"`
-	Description     string  `validate:"required,min=10" json:"description"`
+	Name            string   `validate:"required,min=5,max=20" json:"name"`
+	Product_picture string   `validate:"required,min=20,max=255" json:"product_picture"`
+	Quantity        int      `validate:"required,min=1" json:"quantity"`
+	Price           float64  `validate:"required,min=1" json:"price"`
+	Weight          int      `validate:"required,min=1" json:"weight"`
+	Size            string   `validate:"required,min=1,max=4" json:"size"`
+	Status          string   `validate:"omitempty,oneof=Draft Ready" json:"status"`
+	Description     string   `validate:"required,min=10" json:"description"`
+	Category_id     int      `validate:"min=0" json:"category_id"`
+	Tags            []string `validate:"max=10,dive,min=1,max=30" json:"tags"`
 }
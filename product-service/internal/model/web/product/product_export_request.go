@@ -0,0 +1,15 @@
+package product
+
+// ProductExportRequest selects the products of an export. Seller_id is only
+// honoured on the admin export, a seller always exports their own products.
+// Min_price and Max_price are inclusive, a Max_price below Min_price is
+// rejected.
+type ProductExportRequest struct {
+	Format      string   `validate:"required,oneof=csv ndjson" json:"format"`
+	Seller_id   string   `validate:"omitempty,len=36" json:"seller_id"`
+	Category_id int      `validate:"min=0" json:"category_id"`
+	Status      []string `validate:"max=4,dive,oneof=Draft Ready SoldOut Archived" json:"status"`
+	Size        []string `validate:"max=20,dive,min=1,max=4" json:"size"`
+	Min_price   float64  `validate:"min=0" json:"min_price"`
+	Max_price   float64  `validate:"omitempty,min=0,gtefield=Min_price" json:"max_price"`
+}
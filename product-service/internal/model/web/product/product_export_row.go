@@ -0,0 +1,22 @@
+package product
+
+import "time"
+
+// ProductExportRow is one product of an export, a line of NDJSON or a CSV
+// record with the same fields in the same order.
+type ProductExportRow struct {
+	Id              int        `json:"id"`
+	Seller_id       string     `json:"seller_id"`
+	Name            string     `json:"name"`
+	Product_picture string     `json:"product_picture"`
+	Quantity        int        `json:"quantity"`
+	Price           float64    `json:"price"`
+	Weight          int        `json:"weight"`
+	Size            string     `json:"size"`
+	Status          string     `json:"status"`
+	Description     string     `json:"description"`
+	Category_id     *int       `json:"category_id"`
+	Tags            []string   `json:"tags"`
+	Created_at      *time.Time `json:"created_at"`
+	Updated_at      *time.Time `json:"updated_at"`
+}
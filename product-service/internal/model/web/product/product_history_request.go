@@ -0,0 +1,8 @@
+package product
+
+// ProductHistoryRequest pages back through a product's change log, newest
+// first. Before_id is the Next_before_id of the previous page.
+type ProductHistoryRequest struct {
+	Limit     int   `validate:"min=1,max=100" json:"limit"`
+	Before_id int64 `validate:"min=0" json:"before_id"`
+}
@@ -0,0 +1,46 @@
+package product
+
+import "time"
+
+type ProductFieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+type ProductHistoryResponse struct {
+	Id         int64                         `json:"id"`
+	Product_id int                           `json:"product_id"`
+	Operation  string                        `json:"operation"`
+	Changes    map[string]ProductFieldChange `json:"changes"`
+	Changed_at *time.Time                    `json:"changed_at"`
+}
+
+type ProductHistoryListResponse struct {
+	History        []ProductHistoryResponse `json:"history"`
+	Next_before_id *int64                   `json:"next_before_id"`
+}
+
+// ProductSnapshotResponse is a product as it was at some point in time.
+type ProductSnapshotResponse struct {
+	Id              int        `json:"id"`
+	Seller_id       string     `json:"seller_id"`
+	Name            string     `json:"name"`
+	Product_picture string     `json:"product_picture"`
+	Quantity        int        `json:"quantity"`
+	Price           float64    `json:"price"`
+	Weight          int        `json:"weight"`
+	Size            string     `json:"size"`
+	Status          string     `json:"status"`
+	Description     string     `json:"description"`
+	Category_id     *int       `json:"category_id"`
+	Created_at      *time.Time `json:"created_at"`
+	Updated_at      *time.Time `json:"updated_at"`
+	Deleted_at      *time.Time `json:"deleted_at"`
+}
+
+type ProductAsOfResponse struct {
+	Product    ProductSnapshotResponse `json:"product"`
+	Operation  string                  `json:"operation"`
+	Changed_at *time.Time              `json:"changed_at"`
+	As_of      *time.Time              `json:"as_of"`
+}
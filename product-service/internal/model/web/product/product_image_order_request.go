@@ -0,0 +1,7 @@
+package product
+
+// ProductImageOrderRequest lists every image of the gallery in its new order,
+// the first one becomes the cover.
+type ProductImageOrderRequest struct {
+	Image_ids []string `validate:"required,min=1,max=20,dive,len=36" json:"image_ids"`
+}
@@ -0,0 +1,13 @@
+package product
+
+import "time"
+
+type ProductImageResponse struct {
+	Id           string     `json:"id"`
+	Product_id   int        `json:"product_id"`
+	Url          string     `json:"url"`
+	Content_type string     `json:"content_type"`
+	Size_bytes   int        `json:"size_bytes"`
+	Position     int        `json:"position"`
+	Created_at   *time.Time `json:"created_at"`
+}
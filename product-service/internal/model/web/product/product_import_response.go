@@ -0,0 +1,17 @@
+package product
+
+import "time"
+
+type ProductImportResponse struct {
+	Id             string     `json:"id"`
+	Format         string     `json:"format"`
+	Status         string     `json:"status"`
+	Total_rows     int        `json:"total_rows"`
+	Processed_rows int        `json:"processed_rows"`
+	Imported_rows  int        `json:"imported_rows"`
+	Failed_rows    int        `json:"failed_rows"`
+	Error          string     `json:"error"`
+	Created_at     *time.Time `json:"created_at"`
+	Updated_at     *time.Time `json:"updated_at"`
+	Finished_at    *time.Time `json:"finished_at"`
+}
@@ -0,0 +1,19 @@
+package product
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProductIndexFailureEvent is published to product.index.dlq for a write the
+// product index rejected or kept throttling. Document is empty for deletes.
+type ProductIndexFailureEvent struct {
+	Product_id int             `json:"product_id"`
+	Operation  string          `json:"operation"`
+	Version    int64           `json:"version"`
+	Status     int             `json:"status"`
+	Error      string          `json:"error"`
+	Attempts   int             `json:"attempts"`
+	Document   json.RawMessage `json:"document,omitempty"`
+	Failed_at  *time.Time      `json:"failed_at"`
+}
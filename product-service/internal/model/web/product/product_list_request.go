@@ -0,0 +1,18 @@
+package product
+
+// ProductListRequest pages through products in a stable order. Cursor is the
+// Next_cursor of the previous page and only continues the Sort it came from.
+// Category_id also matches products in its descendant categories.
+// Min_price and Max_price are inclusive, a Max_price below Min_price is
+// rejected.
+type ProductListRequest struct {
+	Limit       int      `validate:"min=1,max=100" json:"limit"`
+	Cursor      string   `validate:"max=512" json:"cursor"`
+	Seller_id   string   `validate:"omitempty,len=36" json:"seller_id"`
+	Category_id int      `validate:"min=0" json:"category_id"`
+	Status      []string `validate:"max=10,dive,min=1,max=9" json:"status"`
+	Size        []string `validate:"max=20,dive,min=1,max=4" json:"size"`
+	Min_price   float64  `validate:"min=0" json:"min_price"`
+	Max_price   float64  `validate:"omitempty,min=0,gtefield=Min_price" json:"max_price"`
+	Sort        string   `validate:"omitempty,oneof=created_at_desc created_at_asc price_asc price_desc name_asc name_desc" json:"sort"`
+}
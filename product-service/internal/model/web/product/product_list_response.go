@@ -0,0 +1,10 @@
+package product
+
+// ProductListResponse has an empty Next_cursor on the last page.
+type ProductListResponse struct {
+	Products    []ProductResponse `json:"products"`
+	Limit       int               `json:"limit"`
+	Sort        string            `json:"sort"`
+	Has_more    bool              `json:"has_more"`
+	Next_cursor string            `json:"next_cursor"`
+}
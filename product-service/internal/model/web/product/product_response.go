@@ -2,18 +2,30 @@ package product
 
 import "time"
 
+// ProductResponse aggregates the variants of a product into Price_range and
+// Total_stock. A product without variants reports its own price and quantity.
 type ProductResponse struct {
-	Id          int        `json:"id"`
-	Seller_id   string     `json:"seller_id"`
-	Name        string     `json:"name"`
-	Quantity    int        `json:"quantity"`
-	Price       float64    `json:"price"`
-	Weight      int        `json:"weight"`
-	Size        string     `json:"size"`
-	Status      string     `json:"status"`
-	Description string     `json:"description"`
-	Created_at  *time.Time `json:"created_at"`
-	Updated_at  *time.Time `json:"updated_at"`
+	Id          int                      `json:"id"`
+	Seller_id   string                   `json:"seller_id"`
+	Name        string                   `json:"name"`
+	Quantity    int                      `json:"quantity"`
+	Price       float64                  `json:"price"`
+	Price_range ProductPriceRange        `json:"price_range"`
+	Total_stock int                      `json:"total_stock"`
+	Weight      int                      `json:"weight"`
+	Size        string                   `json:"size"`
+	Status      string                   `json:"status"`
+	Description string                   `json:"description"`
+	Category_id *int                     `json:"category_id"`
+	Tags        []string                 `json:"tags,omitempty"`
+	Variants    []ProductVariantResponse `json:"variants,omitempty"`
+	Created_at  *time.Time               `json:"created_at"`
+	Updated_at  *time.Time               `json:"updated_at"`
+}
+
+type ProductPriceRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
 }
 
 type ProductHomePageResponse struct {
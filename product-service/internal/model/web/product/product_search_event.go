@@ -0,0 +1,27 @@
+package product
+
+import "time"
+
+// ProductSearchEvent is published to product.search for every answered search.
+// User_id is empty for anonymous searches.
+type ProductSearchEvent struct {
+	Query        string               `json:"query"`
+	Filters      ProductSearchFilters `json:"filters"`
+	Backend      string               `json:"backend"`
+	Result_count int64                `json:"result_count"`
+	Latency_ms   int64                `json:"latency_ms"`
+	User_id      string               `json:"user_id"`
+	Created_at   *time.Time           `json:"created_at"`
+}
+
+type ProductSearchFilters struct {
+	Sort       string   `json:"sort,omitempty"`
+	Category   int      `json:"category,omitempty"`
+	Page       int      `json:"page"`
+	Size       []string `json:"size,omitempty"`
+	Status     []string `json:"status,omitempty"`
+	Min_price  float64  `json:"min_price,omitempty"`
+	Max_price  float64  `json:"max_price,omitempty"`
+	Min_weight int      `json:"min_weight,omitempty"`
+	Max_weight int      `json:"max_weight,omitempty"`
+}
@@ -0,0 +1,20 @@
+package product
+
+// ProductSearchRequest filters combine with AND across dimensions and OR within
+// one, so Size ["S","M"] with Status ["Ready"] matches S or M items that are
+// Ready. Max_price and Max_weight are exclusive like the facet buckets, zero
+// leaves a bound open. Category also matches products in its descendants.
+// Page times Per_page may not exceed 10000, the result window of the index.
+type ProductSearchRequest struct {
+	Q          string   `validate:"max=200" json:"q"`
+	Sort       string   `validate:"omitempty,oneof=relevance price_asc price_desc newest" json:"sort"`
+	Category   int      `validate:"min=0" json:"category"`
+	Page       int      `validate:"min=1,max=500" json:"page"`
+	Per_page   int      `validate:"min=1,max=100" json:"per_page"`
+	Size       []string `validate:"max=20,dive,min=1,max=4" json:"size"`
+	Status     []string `validate:"max=10,dive,min=1,max=9" json:"status"`
+	Min_price  float64  `validate:"min=0" json:"min_price"`
+	Max_price  float64  `validate:"min=0" json:"max_price"`
+	Min_weight int      `validate:"min=0" json:"min_weight"`
+	Max_weight int      `validate:"min=0" json:"max_weight"`
+}
@@ -0,0 +1,54 @@
+package product
+
+import "time"
+
+// ProductSearchResponse names the backend that answered, "postgres" means
+// search runs degraded on the Postgres full-text fallback.
+type ProductSearchResponse struct {
+	Backend  string              `json:"backend"`
+	Total    int64               `json:"total"`
+	Page     int                 `json:"page"`
+	Per_page int                 `json:"per_page"`
+	Products []ProductSearchHit  `json:"products"`
+	Facets   ProductSearchFacets `json:"facets"`
+}
+
+// ProductSearchHit carries a description snippet instead of the full
+// description. Highlights are HTML escaped with matched terms in <em>, and are
+// left out when nothing matched, as on a search without text.
+type ProductSearchHit struct {
+	Id         int                        `json:"id"`
+	Seller_id  string                     `json:"seller_id"`
+	Name       string                     `json:"name"`
+	Quantity   int                        `json:"quantity"`
+	Price      float64                    `json:"price"`
+	Weight     int                        `json:"weight"`
+	Size       string                     `json:"size"`
+	Status     string                     `json:"status"`
+	Snippet    string                     `json:"snippet"`
+	Highlights *ProductSearchHitHighlight `json:"highlights,omitempty"`
+	Created_at *time.Time                 `json:"created_at"`
+	Updated_at *time.Time                 `json:"updated_at"`
+}
+
+type ProductSearchHitHighlight struct {
+	Name        []string `json:"name,omitempty"`
+	Description []string `json:"description,omitempty"`
+}
+
+// ProductSearchFacets counts for one dimension apply every active filter except
+// the dimension's own, so the other options of a selected facet keep their
+// counts.
+type ProductSearchFacets struct {
+	Size   []ProductSearchFacetBucket `json:"size"`
+	Status []ProductSearchFacetBucket `json:"status"`
+	Price  []ProductSearchFacetBucket `json:"price"`
+	Weight []ProductSearchFacetBucket `json:"weight"`
+}
+
+type ProductSearchFacetBucket struct {
+	Key   string   `json:"key"`
+	From  *float64 `json:"from,omitempty"`
+	To    *float64 `json:"to,omitempty"`
+	Count int64    `json:"count"`
+}
@@ -0,0 +1,6 @@
+package product
+
+type ProductSimilarRequest struct {
+	Product_id int `validate:"min=1" json:"product_id"`
+	Limit      int `validate:"min=1,max=24" json:"limit"`
+}
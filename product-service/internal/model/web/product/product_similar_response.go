@@ -0,0 +1,6 @@
+package product
+
+type ProductSimilarResponse struct {
+	Product_id int                `json:"product_id"`
+	Products   []ProductSearchHit `json:"products"`
+}
@@ -0,0 +1,15 @@
+package product
+
+import "time"
+
+// ProductStatusEvent is published to product.status_changed for every status
+// transition, whether a seller or the service made it.
+type ProductStatusEvent struct {
+	Product_id  int        `json:"product_id"`
+	Seller_id   string     `json:"seller_id"`
+	From_status string     `json:"from_status"`
+	To_status   string     `json:"to_status"`
+	Reason      string     `json:"reason"`
+	Actor_id    *string    `json:"actor_id"`
+	Created_at  *time.Time `json:"created_at"`
+}
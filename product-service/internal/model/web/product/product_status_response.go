@@ -0,0 +1,13 @@
+package product
+
+import "time"
+
+type ProductStatusChangeResponse struct {
+	Id          int64      `json:"id"`
+	Product_id  int        `json:"product_id"`
+	From_status string     `json:"from_status"`
+	To_status   string     `json:"to_status"`
+	Reason      string     `json:"reason"`
+	Actor_id    *string    `json:"actor_id"`
+	Created_at  *time.Time `json:"created_at"`
+}
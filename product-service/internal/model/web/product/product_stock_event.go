@@ -0,0 +1,23 @@
+package product
+
+import "time"
+
+type StockEvent struct {
+	Product_id        int        `json:"product_id"`
+	Seller_id         string     `json:"seller_id"`
+	Event             string     `json:"event"`
+	Previous_quantity int        `json:"previous_quantity"`
+	Quantity          int        `json:"quantity"`
+	Delta             int        `json:"delta"`
+	Threshold         int        `json:"threshold"`
+	Created_at        *time.Time `json:"created_at"`
+}
+
+type StockNotificationEvent struct {
+	Id           string     `json:"id"`
+	Product_id   int        `json:"product_id"`
+	Product_name string     `json:"product_name"`
+	Event        string     `json:"event"`
+	Quantity     int        `json:"quantity"`
+	Created_at   *time.Time `json:"created_at"`
+}
@@ -0,0 +1,6 @@
+package product
+
+type ProductSuggestRequest struct {
+	Prefix string `validate:"required,min=1,max=50" json:"prefix"`
+	Limit  int    `validate:"min=1,max=10" json:"limit"`
+}
@@ -0,0 +1,11 @@
+package product
+
+type ProductSuggestResponse struct {
+	Products []ProductSuggestion `json:"products"`
+	Sellers  []ProductSuggestion `json:"sellers"`
+}
+
+type ProductSuggestion struct {
+	Text       string `json:"text"`
+	Popularity int    `json:"popularity"`
+}
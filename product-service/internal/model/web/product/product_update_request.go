@@ -1,11 +1,15 @@
 package product
 
+// ProductUpdateRequest leaves out fields that are not changing. Tags left out
+// keep the product's tags, an empty list removes them. Quantity is a pointer
+// so that 0 sets the stock to zero. Status has its own transition endpoints.
 type ProductUpdateRequest struct {
-	Name        string  `validate:"omitempty,min=5,max=20" json:"name,omitempty"`
-	Quantity    int     `validate:"omitempty,min=1" json:"quantity,omitempty"`
-	Price       float64 `validate:"omitempty,min=1" json:"price,omitempty"`
-	Weight      int     `validate:"omitempty,min=1" json:"weight,omitempty"`
-	Size        string  `validate:"omitempty,min=1,max=4" json:"size,omitempty"`
-	Status      string  `validate:"omitempty,min=5,max=9" json:"status,omitempty"`
-	Description string  `validate:"omitempty,min=10" json:"description,omitempty"`
+	Name        string   `validate:"omitempty,min=5,max=20" json:"name,omitempty"`
+	Quantity    *int     `validate:"omitempty,min=0" json:"quantity,omitempty"`
+	Price       float64  `validate:"omitempty,min=1" json:"price,omitempty"`
+	Weight      int      `validate:"omitempty,min=1" json:"weight,omitempty"`
+	Size        string   `validate:"omitempty,min=1,max=4" json:"size,omitempty"`
+	Description string   `validate:"omitempty,min=10" json:"description,omitempty"`
+	Category_id int      `validate:"omitempty,min=1" json:"category_id,omitempty"`
+	Tags        []string `validate:"omitempty,max=10,dive,min=1,max=30" json:"tags,omitempty"`
 }
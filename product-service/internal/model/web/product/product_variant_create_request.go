@@ -0,0 +1,10 @@
+package product
+
+type ProductVariantCreateRequest struct {
+	Sku        string            `validate:"required,min=1,max=64" json:"sku"`
+	Size       string            `validate:"required,min=1,max=4" json:"size"`
+	Attributes map[string]string `validate:"max=10,dive,keys,min=1,max=30,endkeys,min=1,max=50" json:"attributes"`
+	Price      float64           `validate:"required,min=1" json:"price"`
+	Weight     int               `validate:"required,min=1" json:"weight"`
+	Quantity   int               `validate:"min=0" json:"quantity"`
+}
@@ -0,0 +1,16 @@
+package product
+
+import "time"
+
+type ProductVariantResponse struct {
+	Id         int               `json:"id"`
+	Product_id int               `json:"product_id"`
+	Sku        string            `json:"sku"`
+	Size       string            `json:"size"`
+	Attributes map[string]string `json:"attributes"`
+	Price      float64           `json:"price"`
+	Weight     int               `json:"weight"`
+	Quantity   int               `json:"quantity"`
+	Created_at *time.Time        `json:"created_at"`
+	Updated_at *time.Time        `json:"updated_at"`
+}
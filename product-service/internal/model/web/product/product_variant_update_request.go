@@ -0,0 +1,13 @@
+package product
+
+// ProductVariantUpdateRequest leaves out fields that are not changing like
+// ProductUpdateRequest. Quantity is a pointer because 0 is a valid stock, and
+// Attributes left out keep the variant's attributes.
+type ProductVariantUpdateRequest struct {
+	Sku        string            `validate:"omitempty,min=1,max=64" json:"sku,omitempty"`
+	Size       string            `validate:"omitempty,min=1,max=4" json:"size,omitempty"`
+	Attributes map[string]string `validate:"omitempty,max=10,dive,keys,min=1,max=30,endkeys,min=1,max=50" json:"attributes,omitempty"`
+	Price      float64           `validate:"omitempty,min=1" json:"price,omitempty"`
+	Weight     int               `validate:"omitempty,min=1" json:"weight,omitempty"`
+	Quantity   *int              `validate:"omitempty,min=0" json:"quantity,omitempty"`
+}
@@ -0,0 +1,19 @@
+package product
+
+import "time"
+
+type SearchAnalyticsResponse struct {
+	Period              string                 `json:"period"`
+	From                *time.Time             `json:"from"`
+	To                  *time.Time             `json:"to"`
+	Top_queries         []SearchQueryStatistic `json:"top_queries"`
+	Zero_result_queries []SearchQueryStatistic `json:"zero_result_queries"`
+}
+
+type SearchQueryStatistic struct {
+	Query          string  `json:"query"`
+	Searches       int64   `json:"searches"`
+	Zero_results   int64   `json:"zero_results"`
+	Avg_results    float64 `json:"avg_results"`
+	Avg_latency_ms float64 `json:"avg_latency_ms"`
+}
@@ -0,0 +1,12 @@
+package product
+
+import "time"
+
+type SellerStatsResponse struct {
+	Seller_id        string     `json:"seller_id"`
+	Active_product   int        `json:"active_product"`
+	Sold_out_product int        `json:"sold_out_product"`
+	Total_product    int        `json:"total_product"`
+	Inventory_value  float64    `json:"inventory_value"`
+	Last_updated_at  *time.Time `json:"last_updated_at"`
+}
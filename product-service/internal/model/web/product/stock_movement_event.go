@@ -0,0 +1,18 @@
+package product
+
+import "time"
+
+// StockMovementEvent is published to product.stock_movement whenever a
+// reservation takes units out of the available stock or puts them back. Delta
+// is negative for a reservation and positive for a release or expiry, a commit
+// moves nothing and has a Delta of 0.
+type StockMovementEvent struct {
+	Reservation_id string     `json:"reservation_id"`
+	Product_id     int        `json:"product_id"`
+	Variant_id     *int       `json:"variant_id"`
+	Buyer_id       string     `json:"buyer_id"`
+	Event          string     `json:"event"`
+	Quantity       int        `json:"quantity"`
+	Delta          int        `json:"delta"`
+	Created_at     *time.Time `json:"created_at"`
+}
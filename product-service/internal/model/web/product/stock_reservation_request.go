@@ -0,0 +1,11 @@
+package product
+
+// StockReservationRequest is sent with a reservation id chosen by the caller,
+// so a retried request finds the reservation it already made. Variant_id is
+// required for a product with variants. Ttl_seconds of 0 uses the default.
+type StockReservationRequest struct {
+	Reservation_id string `validate:"required,min=1,max=64" json:"reservation_id"`
+	Variant_id     int    `validate:"min=0" json:"variant_id"`
+	Quantity       int    `validate:"required,min=1" json:"quantity"`
+	Ttl_seconds    int    `validate:"min=0,max=86400" json:"ttl_seconds"`
+}
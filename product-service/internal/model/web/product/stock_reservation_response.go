@@ -0,0 +1,14 @@
+package product
+
+import "time"
+
+type StockReservationResponse struct {
+	Id         string     `json:"id"`
+	Product_id int        `json:"product_id"`
+	Variant_id *int       `json:"variant_id"`
+	Quantity   int        `json:"quantity"`
+	Status     string     `json:"status"`
+	Expires_at *time.Time `json:"expires_at"`
+	Created_at *time.Time `json:"created_at"`
+	Updated_at *time.Time `json:"updated_at"`
+}
@@ -0,0 +1,5 @@
+package product
+
+type StockThresholdUpdateRequest struct {
+	Low_stock_threshold int `validate:"min=0,max=100000" json:"low_stock_threshold"`
+}
@@ -0,0 +1,9 @@
+package product
+
+import "time"
+
+type StockThresholdResponse struct {
+	Seller_id           string     `json:"seller_id"`
+	Low_stock_threshold int        `json:"low_stock_threshold"`
+	Updated_at          *time.Time `json:"updated_at"`
+}
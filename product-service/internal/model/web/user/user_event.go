@@ -0,0 +1,18 @@
+package user
+
+import "time"
+
+type UserDeletedEvent struct {
+	Saga_id    string     `json:"saga_id"`
+	Id         string     `json:"id"`
+	Created_at *time.Time `json:"created_at"`
+}
+
+type UserDeletionResultEvent struct {
+	Saga_id          string     `json:"saga_id"`
+	Id               string     `json:"id"`
+	Status           string     `json:"status"`
+	Reason           string     `json:"reason"`
+	Archived_product int64      `json:"archived_product"`
+	Created_at       *time.Time `json:"created_at"`
+}
@@ -30,3 +30,14 @@ type EmailApiResponse struct {
 type EmailDataField struct {
 	Email string `json:"status"`
 }
+
+type SellerApiResponse struct {
+	Code   int             `json:"code"`
+	Status string          `json:"status"`
+	Data   SellerDataField `json:"data"`
+}
+
+type SellerDataField struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
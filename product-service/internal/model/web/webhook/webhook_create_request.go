@@ -0,0 +1,6 @@
+package webhook
+
+type WebhookCreateRequest struct {
+	Url         string   `validate:"required,url,max=2048" json:"url"`
+	Event_types []string `validate:"required,min=1,dive,oneof=product.created product.updated product.deleted product.restored product.purged product.activity" json:"event_types"`
+}
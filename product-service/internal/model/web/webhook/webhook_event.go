@@ -0,0 +1,14 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookEvent is the body posted to subscriber endpoints.
+type WebhookEvent struct {
+	Id         string          `json:"id"`
+	Type       string          `json:"type"`
+	Data       json.RawMessage `json:"data"`
+	Created_at *time.Time      `json:"created_at"`
+}
@@ -0,0 +1,33 @@
+package webhook
+
+import "time"
+
+type WebhookResponse struct {
+	Id          string     `json:"id"`
+	Url         string     `json:"url"`
+	Secret      string     `json:"secret,omitempty"`
+	Active      bool       `json:"active"`
+	Event_types []string   `json:"event_types"`
+	Created_at  *time.Time `json:"created_at"`
+	Updated_at  *time.Time `json:"updated_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	Id              string                           `json:"id"`
+	Event_type      string                           `json:"event_type"`
+	Payload         string                           `json:"payload"`
+	Status          string                           `json:"status"`
+	Attempts        int                              `json:"attempts"`
+	Next_attempt_at *time.Time                       `json:"next_attempt_at"`
+	Created_at      *time.Time                       `json:"created_at"`
+	Updated_at      *time.Time                       `json:"updated_at"`
+	Attempt_logs    []WebhookDeliveryAttemptResponse `json:"attempt_logs,omitempty"`
+}
+
+type WebhookDeliveryAttemptResponse struct {
+	Attempt     int        `json:"attempt"`
+	Status_code int        `json:"status_code"`
+	Error       string     `json:"error"`
+	Duration_ms int        `json:"duration_ms"`
+	Created_at  *time.Time `json:"created_at"`
+}
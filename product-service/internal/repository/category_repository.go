@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"time"
+)
+
+type CategoryRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewCategoryRepository(zerolog *zerolog.Logger, db *sql.DB) *CategoryRepository {
+	return &CategoryRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+// categorySubtree selects the id of the category at placeholder and of all its
+// descendants.
+func categorySubtree(placeholder string) string {
+	return "WITH RECURSIVE subtree AS (SELECT id FROM categories WHERE id = " + placeholder + " UNION ALL SELECT categories.id FROM categories JOIN subtree ON categories.parent_id = subtree.id) SELECT id FROM subtree"
+}
+
+func (repository *CategoryRepository) Create(ctx context.Context, category domain.Category) int {
+	query := "INSERT INTO categories (parent_id,name,created_at,updated_at) VALUES ($1,$2,$3,$4) RETURNING id"
+
+	var id int
+	err := repository.DB.QueryRowContext(ctx, query, category.Parent_id, category.Name, category.Created_at, category.Updated_at).Scan(&id)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return id
+}
+
+func (repository *CategoryRepository) UpdateWithTx(ctx context.Context, tx *sql.Tx, category domain.Category) {
+	query := "UPDATE categories SET parent_id = $1, name = $2, updated_at = $3 WHERE id = $4"
+	_, err := tx.ExecContext(ctx, query, category.Parent_id, category.Name, category.Updated_at, category.Id)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *CategoryRepository) FindById(ctx context.Context, categoryID int) (domain.Category, error) {
+	query := "SELECT id,parent_id,name,created_at,updated_at FROM categories WHERE id=$1"
+	row, err := repository.DB.QueryContext(ctx, query, categoryID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	category := domain.Category{}
+
+	if row.Next() {
+		err = row.Scan(&category.Id, &category.Parent_id, &category.Name, &category.Created_at, &category.Updated_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return category, nil
+	} else {
+		return category, errors.New("category not found")
+	}
+}
+
+func (repository *CategoryRepository) FindAll(ctx context.Context) []domain.Category {
+	query := "SELECT id,parent_id,name,created_at,updated_at FROM categories ORDER BY lower(name), id"
+	return repository.findMany(ctx, query)
+}
+
+func (repository *CategoryRepository) FindChildren(ctx context.Context, categoryID int) []domain.Category {
+	query := "SELECT id,parent_id,name,created_at,updated_at FROM categories WHERE parent_id=$1 ORDER BY lower(name), id"
+	return repository.findMany(ctx, query, categoryID)
+}
+
+// FindPath returns the category and its ancestors, root first.
+func (repository *CategoryRepository) FindPath(ctx context.Context, categoryID int) []domain.Category {
+	query := "WITH RECURSIVE path AS (SELECT id,parent_id,name,created_at,updated_at,0 AS depth FROM categories WHERE id = $1 UNION ALL SELECT categories.id,categories.parent_id,categories.name,categories.created_at,categories.updated_at,path.depth + 1 FROM categories JOIN path ON categories.id = path.parent_id) SELECT id,parent_id,name,created_at,updated_at FROM path ORDER BY depth DESC"
+	return repository.findMany(ctx, query, categoryID)
+}
+
+// ExistsByName reports whether parentID (nil for the root) already has a child
+// called name, other than the category excludeID.
+func (repository *CategoryRepository) ExistsByName(ctx context.Context, parentID *int, name string, excludeID int) bool {
+	query := "SELECT EXISTS (SELECT 1 FROM categories WHERE COALESCE(parent_id, 0) = COALESCE($1::int, 0) AND lower(name) = lower($2) AND id <> $3)"
+
+	var exists bool
+	err := repository.DB.QueryRowContext(ctx, query, parentID, name, excludeID).Scan(&exists)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return exists
+}
+
+// IsInSubtree reports whether categoryID is rootID or one of its descendants.
+func (repository *CategoryRepository) IsInSubtree(ctx context.Context, rootID int, categoryID int) bool {
+	query := "SELECT EXISTS (SELECT 1 FROM (" + categorySubtree("$1") + ") AS subtree WHERE id = $2)"
+
+	var exists bool
+	err := repository.DB.QueryRowContext(ctx, query, rootID, categoryID).Scan(&exists)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return exists
+}
+
+// TouchProductsWithTx bumps updated_at of every product in the subtree of
+// categoryID. The resulting change events reindex them with the new category
+// path.
+func (repository *CategoryRepository) TouchProductsWithTx(ctx context.Context, tx *sql.Tx, categoryID int, updatedAt *time.Time) int64 {
+	query := "UPDATE products SET updated_at = $2 WHERE category_id IN (" + categorySubtree("$1") + ")"
+	result, err := tx.ExecContext(ctx, query, categoryID, updatedAt)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	touched, err := result.RowsAffected()
+	if err != nil {
+		respErr := errors.New("failed to get affected rows")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return touched
+}
+
+func (repository *CategoryRepository) findMany(ctx context.Context, query string, args ...interface{}) []domain.Category {
+	row, err := repository.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	categories := []domain.Category{}
+
+	for row.Next() {
+		category := domain.Category{}
+		err = row.Scan(&category.Id, &category.Parent_id, &category.Name, &category.Created_at, &category.Updated_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		categories = append(categories, category)
+	}
+
+	return categories
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"time"
+)
+
+type ProductHistoryRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewProductHistoryRepository(zerolog *zerolog.Logger, db *sql.DB) *ProductHistoryRepository {
+	return &ProductHistoryRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+// CreateWithTx records a version once, a change event delivered again for
+// the same LSN is ignored.
+func (repository *ProductHistoryRepository) CreateWithTx(ctx context.Context, tx *sql.Tx, history domain.ProductHistory) {
+	query := "INSERT INTO product_history (product_id,operation,snapshot,changes,source_lsn,changed_at) VALUES ($1,$2,$3,$4,$5,$6) ON CONFLICT (product_id, source_lsn) DO NOTHING"
+	_, err := tx.ExecContext(ctx, query, history.Product_id, history.Operation, history.Snapshot, history.Changes, history.Source_lsn, history.Changed_at)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+// FindPreviousWithTx reads the version recorded right before sourceLsn.
+func (repository *ProductHistoryRepository) FindPreviousWithTx(ctx context.Context, tx *sql.Tx, productID int, sourceLsn int64) (domain.ProductHistory, error) {
+	query := "SELECT id,product_id,operation,snapshot,changes,source_lsn,changed_at FROM product_history WHERE product_id=$1 AND source_lsn < $2 ORDER BY source_lsn DESC LIMIT 1"
+	row, err := tx.QueryContext(ctx, query, productID, sourceLsn)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	if row.Next() {
+		return repository.scan(row), nil
+	} else {
+		return domain.ProductHistory{}, errors.New("product history not found")
+	}
+}
+
+func (repository *ProductHistoryRepository) FindByProductId(ctx context.Context, productID int, beforeID int64, limit int) []domain.ProductHistory {
+	query := "SELECT id,product_id,operation,snapshot,changes,source_lsn,changed_at FROM product_history WHERE product_id=$1 AND ($2::bigint = 0 OR id < $2) ORDER BY id DESC LIMIT $3"
+	row, err := repository.DB.QueryContext(ctx, query, productID, beforeID, limit)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	histories := []domain.ProductHistory{}
+
+	for row.Next() {
+		histories = append(histories, repository.scan(row))
+	}
+
+	return histories
+}
+
+// FindAsOf reads the last version recorded at or before asOf.
+func (repository *ProductHistoryRepository) FindAsOf(ctx context.Context, productID int, asOf *time.Time) (domain.ProductHistory, error) {
+	query := "SELECT id,product_id,operation,snapshot,changes,source_lsn,changed_at FROM product_history WHERE product_id=$1 AND changed_at <= $2 ORDER BY changed_at DESC, source_lsn DESC LIMIT 1"
+	row, err := repository.DB.QueryContext(ctx, query, productID, asOf)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	if row.Next() {
+		return repository.scan(row), nil
+	} else {
+		return domain.ProductHistory{}, errors.New("product not found")
+	}
+}
+
+func (repository *ProductHistoryRepository) scan(row *sql.Rows) domain.ProductHistory {
+	history := domain.ProductHistory{}
+
+	err := row.Scan(&history.Id, &history.Product_id, &history.Operation, &history.Snapshot, &history.Changes, &history.Source_lsn, &history.Changed_at)
+	if err != nil {
+		respErr := errors.New("failed to scan query result")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return history
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"time"
+)
+
+type ProductImageRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewProductImageRepository(zerolog *zerolog.Logger, db *sql.DB) *ProductImageRepository {
+	return &ProductImageRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+func (repository *ProductImageRepository) CreateWithTx(ctx context.Context, tx *sql.Tx, image domain.ProductImage) {
+	query := "INSERT INTO product_images (id,product_id,storage_key,url,content_type,size_bytes,position,created_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)"
+	_, err := tx.ExecContext(ctx, query, image.Id, image.Product_id, image.Storage_key, image.Url, image.Content_type, image.Size_bytes, image.Position, image.Created_at)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *ProductImageRepository) DeleteWithTx(ctx context.Context, tx *sql.Tx, imageID string) {
+	query := "DELETE FROM product_images WHERE id=$1"
+	_, err := tx.ExecContext(ctx, query, imageID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *ProductImageRepository) UpdatePositionWithTx(ctx context.Context, tx *sql.Tx, imageID string, position int) {
+	query := "UPDATE product_images SET position=$1 WHERE id=$2"
+	_, err := tx.ExecContext(ctx, query, position, imageID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+// LockProductWithTx locks the product row, so concurrent changes to one gallery
+// do not hand out the same position.
+func (repository *ProductImageRepository) LockProductWithTx(ctx context.Context, tx *sql.Tx, productID int) {
+	query := "SELECT id FROM products WHERE id=$1 FOR UPDATE"
+	_, err := tx.ExecContext(ctx, query, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *ProductImageRepository) FindByProductIdWithTx(ctx context.Context, tx *sql.Tx, productID int) []domain.ProductImage {
+	query := "SELECT id,product_id,storage_key,url,content_type,size_bytes,position,created_at FROM product_images WHERE product_id=$1 ORDER BY position, created_at"
+	row, err := tx.QueryContext(ctx, query, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	return repository.scan(row)
+}
+
+func (repository *ProductImageRepository) FindByProductId(ctx context.Context, productID int) []domain.ProductImage {
+	query := "SELECT id,product_id,storage_key,url,content_type,size_bytes,position,created_at FROM product_images WHERE product_id=$1 ORDER BY position, created_at"
+	row, err := repository.DB.QueryContext(ctx, query, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	return repository.scan(row)
+}
+
+func (repository *ProductImageRepository) FindStorageKeysByProductIdsWithTx(ctx context.Context, tx *sql.Tx, productIDs []int) []string {
+	query := "SELECT storage_key FROM product_images WHERE product_id = ANY($1::int[])"
+	row, err := tx.QueryContext(ctx, query, productIDs)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	keys := []string{}
+
+	for row.Next() {
+		var key string
+		err = row.Scan(&key)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// UpdateCoverWithTx points products.product_picture at the first image of the
+// gallery, so clients reading the single picture keep working.
+func (repository *ProductImageRepository) UpdateCoverWithTx(ctx context.Context, tx *sql.Tx, productID int, url string, updatedAt *time.Time) {
+	query := "UPDATE products SET product_picture=$1, updated_at=$2 WHERE id=$3"
+	_, err := tx.ExecContext(ctx, query, url, updatedAt, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *ProductImageRepository) scan(row *sql.Rows) []domain.ProductImage {
+	images := []domain.ProductImage{}
+
+	for row.Next() {
+		image := domain.ProductImage{}
+		err := row.Scan(&image.Id, &image.Product_id, &image.Storage_key, &image.Url, &image.Content_type, &image.Size_bytes, &image.Position, &image.Created_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		images = append(images, image)
+	}
+
+	return images
+}
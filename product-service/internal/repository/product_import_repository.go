@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"time"
+)
+
+type ProductImportRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewProductImportRepository(zerolog *zerolog.Logger, db *sql.DB) *ProductImportRepository {
+	return &ProductImportRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+func (repository *ProductImportRepository) Create(ctx context.Context, productImport domain.ProductImport) {
+	query := "INSERT INTO product_imports (id,seller_id,format,status,data,total_rows,created_at,updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)"
+	_, err := repository.DB.ExecContext(ctx, query, productImport.Id, productImport.Seller_id, productImport.Format, productImport.Status, productImport.Data, productImport.Total_rows, productImport.Created_at, productImport.Updated_at)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+// FindById reads an import of sellerID without its file.
+func (repository *ProductImportRepository) FindById(ctx context.Context, importID string, sellerID string) (domain.ProductImport, error) {
+	query := "SELECT id,seller_id,format,status,total_rows,processed_rows,imported_rows,failed_rows,attempts,error,lease_until,created_at,updated_at,finished_at FROM product_imports WHERE id=$1 AND seller_id=$2"
+	row, err := repository.DB.QueryContext(ctx, query, importID, sellerID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	productImport := domain.ProductImport{}
+
+	if row.Next() {
+		err = row.Scan(&productImport.Id, &productImport.Seller_id, &productImport.Format, &productImport.Status, &productImport.Total_rows, &productImport.Processed_rows, &productImport.Imported_rows, &productImport.Failed_rows, &productImport.Attempts, &productImport.Error, &productImport.Lease_until, &productImport.Created_at, &productImport.Updated_at, &productImport.Finished_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return productImport, nil
+	} else {
+		return productImport, errors.New("import not found")
+	}
+}
+
+// Claim takes the oldest pending import, or a running one whose worker let
+// its lease run out, and leases it until leaseUntil.
+func (repository *ProductImportRepository) Claim(ctx context.Context, now *time.Time, leaseUntil *time.Time) (domain.ProductImport, error) {
+	query := "UPDATE product_imports SET status = 'Running', attempts = attempts + 1, lease_until = $1, updated_at = $2 WHERE id = (SELECT id FROM product_imports WHERE status = 'Pending' OR (status = 'Running' AND lease_until <= $2) ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING id,seller_id,format,status,data,total_rows,processed_rows,imported_rows,failed_rows,attempts,created_at"
+	row, err := repository.DB.QueryContext(ctx, query, leaseUntil, now)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	productImport := domain.ProductImport{}
+
+	if row.Next() {
+		err = row.Scan(&productImport.Id, &productImport.Seller_id, &productImport.Format, &productImport.Status, &productImport.Data, &productImport.Total_rows, &productImport.Processed_rows, &productImport.Imported_rows, &productImport.Failed_rows, &productImport.Attempts, &productImport.Created_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return productImport, nil
+	} else {
+		return productImport, errors.New("import not found")
+	}
+}
+
+// UpdateProgressWithTx saves how far the import got and extends its lease.
+func (repository *ProductImportRepository) UpdateProgressWithTx(ctx context.Context, tx *sql.Tx, productImport domain.ProductImport, leaseUntil *time.Time, updatedAt *time.Time) {
+	query := "UPDATE product_imports SET processed_rows = $1, imported_rows = $2, failed_rows = $3, lease_until = $4, updated_at = $5 WHERE id = $6"
+	_, err := tx.ExecContext(ctx, query, productImport.Processed_rows, productImport.Imported_rows, productImport.Failed_rows, leaseUntil, updatedAt, productImport.Id)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+// Finish ends the import and drops the uploaded file.
+func (repository *ProductImportRepository) Finish(ctx context.Context, importID string, status string, errorMessage string, finishedAt *time.Time) {
+	query := "UPDATE product_imports SET status = $1, error = $2, data = NULL, lease_until = NULL, updated_at = $3, finished_at = $3 WHERE id = $4"
+	_, err := repository.DB.ExecContext(ctx, query, status, errorMessage, finishedAt, importID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *ProductImportRepository) CreateErrorWithTx(ctx context.Context, tx *sql.Tx, importID string, importError domain.ProductImportError) {
+	query := "INSERT INTO product_import_errors (import_id,row_number,error) VALUES ($1,$2,$3)"
+	_, err := tx.ExecContext(ctx, query, importID, importError.Row_number, importError.Error)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *ProductImportRepository) FindErrors(ctx context.Context, importID string) []domain.ProductImportError {
+	query := "SELECT row_number,error FROM product_import_errors WHERE import_id=$1 ORDER BY row_number, id"
+	row, err := repository.DB.QueryContext(ctx, query, importID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	importErrors := []domain.ProductImportError{}
+
+	for row.Next() {
+		importError := domain.ProductImportError{}
+		err = row.Scan(&importError.Row_number, &importError.Error)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		importErrors = append(importErrors, importError)
+	}
+
+	return importErrors
+}
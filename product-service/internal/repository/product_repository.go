@@ -8,6 +8,9 @@ import (
 	"github.com/rs/zerolog"
 	"gocdc/internal/model/domain"
 	"gocdc/internal/model/web/product"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type ProductRepository struct {
@@ -22,16 +25,23 @@ func NewProductRepository(zerolog *zerolog.Logger, db *sql.DB) *ProductRepositor
 	}
 }
 
-func (repository *ProductRepository) CreateWithTx(ctx context.Context, tx *sql.Tx, product domain.Product) {
-	query := "INSERT INTO products (seller_id,name,product_picture,quantity,price,weight,size,description,created_at,updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)"
-	_, err := tx.ExecContext(ctx, query, product.Seller_id, product.Name, product.Product_picture, product.Quantity, product.Price, product.Weight, product.Size, product.Description, product.Created_at, product.Updated_at)
+func (repository *ProductRepository) CreateWithTx(ctx context.Context, tx *sql.Tx, product domain.Product) int {
+	query := "INSERT INTO products (seller_id,name,product_picture,quantity,price,weight,size,status,description,category_id,created_at,updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12) RETURNING id"
+
+	var id int
+	err := tx.QueryRowContext(ctx, query, product.Seller_id, product.Name, product.Product_picture, product.Quantity, product.Price, product.Weight, product.Size, product.Status, product.Description, product.Category_id, product.Created_at, product.Updated_at).Scan(&id)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
 		repository.Log.Panic().Err(err).Msg(respErr.Error())
 	}
+
+	return id
 }
 
-func (repository *ProductRepository) UpdateWithTx(ctx context.Context, tx *sql.Tx, product domain.Product) {
+// UpdateWithTx sets the non-zero fields of product, and the quantity when it
+// is not nil, zero included. A new quantity moves the product between Ready
+// and SoldOut in the same statement, the returned change tells whether it did.
+func (repository *ProductRepository) UpdateWithTx(ctx context.Context, tx *sql.Tx, product domain.Product, quantity *int) domain.ProductStatusChange {
 	query := "UPDATE products SET "
 	args := []interface{}{}
 	argCounter := 1
@@ -41,9 +51,10 @@ func (repository *ProductRepository) UpdateWithTx(ctx context.Context, tx *sql.T
 		args = append(args, product.Name)
 		argCounter++
 	}
-	if product.Quantity != 0 {
-		query += fmt.Sprintf("quantity = $%d, ", argCounter)
-		args = append(args, product.Quantity)
+	if quantity != nil {
+		quantityArg := fmt.Sprintf("$%d::int", argCounter)
+		query += "quantity = " + quantityArg + ", status = " + stockStatus(quantityArg) + ", "
+		args = append(args, *quantity)
 		argCounter++
 	}
 	if product.Price != 0 {
@@ -66,23 +77,32 @@ func (repository *ProductRepository) UpdateWithTx(ctx context.Context, tx *sql.T
 		args = append(args, product.Description)
 		argCounter++
 	}
+	if product.Category_id != nil {
+		query += fmt.Sprintf("category_id = $%d, ", argCounter)
+		args = append(args, product.Category_id)
+		argCounter++
+	}
 
 	query += fmt.Sprintf("updated_at = $%d ", argCounter)
 	args = append(args, product.Updated_at)
 	argCounter++
 
-	query += fmt.Sprintf("WHERE id = $%d", argCounter)
+	query += fmt.Sprintf(stockStatusFrom, argCounter) + stockStatusReturning
 	args = append(args, product.Id)
 
-	_, err := tx.ExecContext(ctx, query, args...)
+	row, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
 		repository.Log.Panic().Err(err).Msg(respErr.Error())
 	}
+
+	change, _ := scanStockStatusChange(repository.Log, row, product.Id, product.Updated_at)
+
+	return change
 }
 
 func (repository *ProductRepository) CheckOwnershipWithTx(ctx context.Context, tx *sql.Tx, userUUID string, productID int) error {
-	query := "SELECT id FROM products WHERE id=$1 AND seller_id=$2"
+	query := "SELECT id FROM products WHERE id=$1 AND seller_id=$2 AND deleted_at IS NULL"
 	row, err := tx.QueryContext(ctx, query, productID, userUUID)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
@@ -99,7 +119,7 @@ func (repository *ProductRepository) CheckOwnershipWithTx(ctx context.Context, t
 }
 
 func (repository *ProductRepository) CheckOwnership(ctx context.Context, userUUID string, productID int) error {
-	query := "SELECT id FROM products WHERE id=$1 AND seller_id=$2"
+	query := "SELECT id FROM products WHERE id=$1 AND seller_id=$2 AND deleted_at IS NULL"
 	row, err := repository.DB.QueryContext(ctx, query, productID, userUUID)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
@@ -115,17 +135,111 @@ func (repository *ProductRepository) CheckOwnership(ctx context.Context, userUUI
 	}
 }
 
-func (repository *ProductRepository) Delete(ctx context.Context, productID int) {
-	query := "DELETE FROM products WHERE id=$1"
-	_, err := repository.DB.ExecContext(ctx, query, productID)
+// Delete only marks the product as deleted, it can be restored until the purge
+// removes it for good.
+func (repository *ProductRepository) Delete(ctx context.Context, productID int, deletedAt *time.Time) {
+	query := "UPDATE products SET deleted_at = $1, updated_at = $1 WHERE id = $2 AND deleted_at IS NULL"
+	_, err := repository.DB.ExecContext(ctx, query, deletedAt, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+// Restore brings back a product of sellerID deleted after deletedAfter.
+func (repository *ProductRepository) Restore(ctx context.Context, sellerID string, productID int, deletedAfter *time.Time, updatedAt *time.Time) error {
+	query := "UPDATE products SET deleted_at = NULL, updated_at = $1 WHERE id = $2 AND seller_id = $3 AND deleted_at > $4"
+	result, err := repository.DB.ExecContext(ctx, query, updatedAt, productID, sellerID, deletedAfter)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	restored, err := result.RowsAffected()
+	if err != nil {
+		respErr := errors.New("failed to get affected rows")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	if restored == 0 {
+		return errors.New("product not found")
+	}
+
+	return nil
+}
+
+// FindPurgeableWithTx locks up to limit products deleted before
+// deletedBefore. Products locked by another purge are skipped.
+func (repository *ProductRepository) FindPurgeableWithTx(ctx context.Context, tx *sql.Tx, deletedBefore *time.Time, limit int) []int {
+	query := "SELECT id FROM products WHERE deleted_at < $1 ORDER BY deleted_at LIMIT $2 FOR UPDATE SKIP LOCKED"
+	row, err := tx.QueryContext(ctx, query, deletedBefore, limit)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	productIDs := []int{}
+
+	for row.Next() {
+		var productID int
+		err = row.Scan(&productID)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		productIDs = append(productIDs, productID)
+	}
+
+	return productIDs
+}
+
+func (repository *ProductRepository) PurgeWithTx(ctx context.Context, tx *sql.Tx, productIDs []int) {
+	query := "DELETE FROM products WHERE id = ANY($1::int[])"
+	_, err := tx.ExecContext(ctx, query, productIDs)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
 		repository.Log.Panic().Err(err).Msg(respErr.Error())
 	}
 }
 
+func (repository *ProductRepository) ArchiveBySellerWithTx(ctx context.Context, tx *sql.Tx, sellerID string, updatedAt *time.Time) int64 {
+	query := "UPDATE products SET status = 'Archived', updated_at = $1 WHERE seller_id = $2 AND deleted_at IS NULL AND status IS DISTINCT FROM 'Archived'"
+	result, err := tx.ExecContext(ctx, query, updatedAt, sellerID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	archived, err := result.RowsAffected()
+	if err != nil {
+		respErr := errors.New("failed to get affected rows")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return archived
+}
+
+func (repository *ProductRepository) RestoreBySellerDeletionWithTx(ctx context.Context, tx *sql.Tx, sagaID string, updatedAt *time.Time) {
+	query := "UPDATE products SET status = seller_deletion_products.previous_status, updated_at = $1 FROM seller_deletion_products WHERE seller_deletion_products.saga_id = $2 AND seller_deletion_products.product_id = products.id"
+	_, err := tx.ExecContext(ctx, query, updatedAt, sagaID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+// productVariantJoin aggregates the variants of each product, the columns of
+// productVariantAggregates fall back to the product's own price and quantity
+// when it has none.
+const productVariantJoin = "LEFT JOIN LATERAL (SELECT min(price) AS min_price, max(price) AS max_price, sum(quantity)::int AS total_stock FROM product_variants WHERE product_variants.product_id = products.id) AS variants ON true"
+
+const productVariantAggregates = "COALESCE(variants.min_price,price),COALESCE(variants.max_price,price),COALESCE(variants.total_stock,quantity)"
+
 func (repository *ProductRepository) FindProductInfo(ctx context.Context, productID int) (product.ProductResponse, error) {
-	query := "SELECT id,seller_id,name,quantity,price,weight,size,status,description,created_at,updated_at FROM products WHERE id=$1"
+	query := "SELECT id,seller_id,name,quantity,price," + productVariantAggregates + ",weight,size,status,description,category_id,created_at,updated_at FROM products " + productVariantJoin + " WHERE id=$1 AND deleted_at IS NULL"
 	row, err := repository.DB.QueryContext(ctx, query, productID)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
@@ -137,7 +251,7 @@ func (repository *ProductRepository) FindProductInfo(ctx context.Context, produc
 	product := product.ProductResponse{}
 
 	if row.Next() {
-		err = row.Scan(&product.Id, &product.Seller_id, &product.Name, &product.Quantity, &product.Price, &product.Weight, &product.Size, &product.Status, &product.Description, &product.Created_at, &product.Updated_at)
+		err = row.Scan(&product.Id, &product.Seller_id, &product.Name, &product.Quantity, &product.Price, &product.Price_range.Min, &product.Price_range.Max, &product.Total_stock, &product.Weight, &product.Size, &product.Status, &product.Description, &product.Category_id, &product.Created_at, &product.Updated_at)
 		if err != nil {
 			respErr := errors.New("failed to scan query result")
 			repository.Log.Panic().Err(err).Msg(respErr.Error())
@@ -149,52 +263,184 @@ func (repository *ProductRepository) FindProductInfo(ctx context.Context, produc
 	}
 }
 
-func (repository *ProductRepository) FindAllProduct(ctx context.Context) ([]product.ProductResponse, error) {
-	query := "SELECT id,seller_id,name,quantity,price,weight,size,status,description,created_at,updated_at FROM products"
-	row, err := repository.DB.QueryContext(ctx, query)
+// FindAllProduct reads one page of the listing with keyset pagination, so a
+// page costs the same wherever it is. Limit+1 rows are read to tell whether
+// another page follows.
+func (repository *ProductRepository) FindAllProduct(ctx context.Context, listQuery domain.ProductListQuery) []product.ProductResponse {
+	args := []interface{}{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	// Draft and Archived products are not listed
+	clauses := append([]string{"deleted_at IS NULL", "status NOT IN ('Draft', 'Archived')"}, productListFilters(listQuery, arg)...)
+
+	column, cast, direction := productListOrder(listQuery.Sort)
+
+	if listQuery.After != nil {
+		comparison := ">"
+		if direction == "DESC" {
+			comparison = "<"
+		}
+
+		clauses = append(clauses, fmt.Sprintf("(%s, id) %s (%s::%s, %s)", column, comparison, arg(listQuery.After.Value), cast, arg(listQuery.After.Id)))
+	}
+
+	query := fmt.Sprintf("SELECT id,seller_id,name,quantity,price,%s,weight,size,status,description,category_id,created_at,updated_at FROM products %s WHERE %s ORDER BY %s %s, id %s LIMIT %s", productVariantAggregates, productVariantJoin, strings.Join(clauses, " AND "), column, direction, direction, arg(listQuery.Limit+1))
+	row, err := repository.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
 		repository.Log.Panic().Err(err).Msg(respErr.Error())
 	}
 
-	hasData := false
-
 	defer row.Close()
 
 	products := []product.ProductResponse{}
 
 	for row.Next() {
 		product := product.ProductResponse{}
-		err = row.Scan(&product.Id, &product.Seller_id, &product.Name, &product.Quantity, &product.Price, &product.Weight, &product.Size, &product.Status, &product.Description, &product.Created_at, &product.Updated_at)
+		err = row.Scan(&product.Id, &product.Seller_id, &product.Name, &product.Quantity, &product.Price, &product.Price_range.Min, &product.Price_range.Max, &product.Total_stock, &product.Weight, &product.Size, &product.Status, &product.Description, &product.Category_id, &product.Created_at, &product.Updated_at)
 		if err != nil {
 			respErr := errors.New("failed to scan query result")
 			repository.Log.Panic().Err(err).Msg(respErr.Error())
 		}
 
 		products = append(products, product)
+	}
+
+	return products
+}
+
+// productListFilters turns the optional filters of listQuery into WHERE
+// clauses, arg binds a value and returns its placeholder.
+func productListFilters(listQuery domain.ProductListQuery, arg func(value interface{}) string) []string {
+	clauses := []string{}
 
-		hasData = true
+	if listQuery.Seller_id != "" {
+		clauses = append(clauses, "seller_id = "+arg(listQuery.Seller_id))
+	}
+	if listQuery.Category_id > 0 {
+		clauses = append(clauses, "category_id IN ("+categorySubtree(arg(listQuery.Category_id))+")")
+	}
+	if len(listQuery.Statuses) > 0 {
+		clauses = append(clauses, "status = ANY("+arg(listQuery.Statuses)+"::text[])")
 	}
+	if len(listQuery.Sizes) > 0 {
+		clauses = append(clauses, "size = ANY("+arg(listQuery.Sizes)+"::text[])")
+	}
+	if listQuery.Min_price > 0 {
+		clauses = append(clauses, "price >= "+arg(listQuery.Min_price))
+	}
+	if listQuery.Max_price > 0 {
+		clauses = append(clauses, "price <= "+arg(listQuery.Max_price))
+	}
+
+	return clauses
+}
 
-	if hasData == false {
-		return products, errors.New("product not found")
+// StreamWithTx reads the products matching the filters of listQuery through a
+// server side cursor, batchSize rows at a time, and hands them to write one by
+// one. Only one batch is held in memory whatever the size of the catalog.
+// Sort, Limit and After are not used, products come in id order.
+func (repository *ProductRepository) StreamWithTx(ctx context.Context, tx *sql.Tx, listQuery domain.ProductListQuery, batchSize int, write func(product.ProductExportRow) error) error {
+	args := []interface{}{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
 	}
 
-	return products, nil
+	clauses := append([]string{"deleted_at IS NULL"}, productListFilters(listQuery, arg)...)
+
+	query := fmt.Sprintf("DECLARE product_export NO SCROLL CURSOR FOR SELECT id,seller_id,name,product_picture,quantity,price,weight,size,status,description,category_id,COALESCE((SELECT string_agg(tags.name, '|' ORDER BY tags.name) FROM product_tags JOIN tags ON tags.id = product_tags.tag_id WHERE product_tags.product_id = products.id),''),created_at,updated_at FROM products WHERE %s ORDER BY id", strings.Join(clauses, " AND "))
+	_, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	fetch := "FETCH FORWARD " + strconv.Itoa(batchSize) + " FROM product_export"
+
+	for {
+		// the client went away, there is no one left to write to
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		fetched, err := repository.fetchExportBatch(ctx, tx, fetch, write)
+		if err != nil || fetched < batchSize {
+			return err
+		}
+	}
+}
+
+func (repository *ProductRepository) fetchExportBatch(ctx context.Context, tx *sql.Tx, fetch string, write func(product.ProductExportRow) error) (int, error) {
+	row, err := tx.QueryContext(ctx, fetch)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	fetched := 0
+
+	for row.Next() {
+		exportRow := product.ProductExportRow{}
+		var tags string
+		err = row.Scan(&exportRow.Id, &exportRow.Seller_id, &exportRow.Name, &exportRow.Product_picture, &exportRow.Quantity, &exportRow.Price, &exportRow.Weight, &exportRow.Size, &exportRow.Status, &exportRow.Description, &exportRow.Category_id, &tags, &exportRow.Created_at, &exportRow.Updated_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		exportRow.Tags = []string{}
+		if tags != "" {
+			exportRow.Tags = strings.Split(tags, "|")
+		}
+
+		fetched++
+
+		err = write(exportRow)
+		if err != nil {
+			return fetched, err
+		}
+	}
+
+	return fetched, nil
+}
+
+// productListOrder maps a listing sort to its column, the type a cursor value
+// is cast to, and the direction. Ties on the column are ordered by id the same
+// way so the order is total.
+func productListOrder(sort string) (string, string, string) {
+	switch sort {
+	case "created_at_asc":
+		return "created_at", "timestamp", "ASC"
+	case "price_asc":
+		return "price", "numeric", "ASC"
+	case "price_desc":
+		return "price", "numeric", "DESC"
+	case "name_asc":
+		return "name", "text", "ASC"
+	case "name_desc":
+		return "name", "text", "DESC"
+	default:
+		return "created_at", "timestamp", "DESC"
+	}
 }
 
 func (repository *ProductRepository) FindAllProductWithTx(ctx context.Context, tx *sql.Tx) ([]product.ProductResponse, error) {
-	query := "SELECT id,seller_id,name,quantity,price,weight,size,status,description,created_at,updated_at FROM products"
+	query := "SELECT id,seller_id,name,quantity,price,weight,size,status,description,created_at,updated_at FROM products WHERE deleted_at IS NULL AND status NOT IN ('Draft', 'Archived')"
 	row, err := repository.DB.QueryContext(ctx, query)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
 		repository.Log.Panic().Err(err).Msg(respErr.Error())
 	}
 
-	hasData := false
-
 	defer row.Close()
 
+	// an empty catalog is an empty page, not a missing product
 	products := []product.ProductResponse{}
 
 	for row.Next() {
@@ -206,12 +452,6 @@ func (repository *ProductRepository) FindAllProductWithTx(ctx context.Context, t
 		}
 
 		products = append(products, product)
-
-		hasData = true
-	}
-
-	if hasData == false {
-		return products, errors.New("product not found")
 	}
 
 	return products, nil
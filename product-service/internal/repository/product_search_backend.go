@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+	"gocdc/internal/model/domain"
+)
+
+// ProductSearchBackend answers product searches. ProductSearchRepository
+// (Elasticsearch) is the primary one, ProductTextSearchRepository (Postgres
+// full-text search) takes over while it is unavailable.
+type ProductSearchBackend interface {
+	Search(ctx context.Context, query domain.ProductSearchQuery) (domain.ProductSearchResult, error)
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,645 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type ProductSearchRepository struct {
+	Log              *zerolog.Logger
+	ElasticSearch    *elasticsearch.Client
+	IndexName        string
+	SuggestIndexName string
+}
+
+func NewProductSearchRepository(zerolog *zerolog.Logger, elasticsearch *elasticsearch.Client, index string, suggestIndex string) *ProductSearchRepository {
+	if index == "" {
+		index = "products"
+	}
+
+	if suggestIndex == "" {
+		suggestIndex = "product_suggestions"
+	}
+
+	return &ProductSearchRepository{
+		Log:              zerolog,
+		ElasticSearch:    elasticsearch,
+		IndexName:        index,
+		SuggestIndexName: suggestIndex,
+	}
+}
+
+type productSearchHits struct {
+	Hits struct {
+		Total struct {
+			Value int64 `json:"value"`
+		} `json:"total"`
+		Hits []struct {
+			Source    domain.ProductDocument `json:"_source"`
+			Highlight struct {
+				Name        []string `json:"name"`
+				Description []string `json:"description"`
+			} `json:"highlight"`
+		} `json:"hits"`
+	} `json:"hits"`
+	Aggregations map[string]struct {
+		Facet struct {
+			Buckets []domain.ProductSearchFacetBucket `json:"buckets"`
+		} `json:"facet"`
+	} `json:"aggregations"`
+}
+
+// Search runs query against the product index. Failures are returned instead
+// of panicking, callers decide how search degrades.
+func (repository *ProductSearchRepository) Search(ctx context.Context, query domain.ProductSearchQuery) (domain.ProductSearchResult, error) {
+	filters := productSearchFilters(query)
+
+	// filters go into post_filter rather than the query, so each facet can be
+	// aggregated over the hits without its own dimension's filter
+	body, err := json.Marshal(map[string]interface{}{
+		"query":       productSearchQuery(query),
+		"post_filter": productSearchFilterClause(filters, ""),
+		"aggs":        productSearchAggregations(filters),
+		"highlight":   productSearchHighlight(),
+		"sort":        productSearchSort(query.Sort),
+		"from":        query.Offset,
+		"size":        query.Limit,
+	})
+	if err != nil {
+		return domain.ProductSearchResult{}, err
+	}
+
+	client := repository.ElasticSearch
+	res, err := client.Search(
+		client.Search.WithContext(ctx),
+		client.Search.WithIndex(repository.IndexName),
+		client.Search.WithBody(bytes.NewReader(body)),
+		client.Search.WithTrackTotalHits(true),
+	)
+	if err != nil {
+		return domain.ProductSearchResult{}, err
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return domain.ProductSearchResult{}, fmt.Errorf("elasticsearch search failed: %s", res.Status())
+	}
+
+	hits := productSearchHits{}
+	err = json.NewDecoder(res.Body).Decode(&hits)
+	if err != nil {
+		return domain.ProductSearchResult{}, err
+	}
+
+	result := domain.ProductSearchResult{
+		Backend:    "elasticsearch",
+		Total:      hits.Hits.Total.Value,
+		Documents:  []domain.ProductDocument{},
+		Highlights: map[int]domain.ProductSearchHighlight{},
+		Facets:     map[string][]domain.ProductSearchFacetBucket{},
+	}
+
+	for _, hit := range hits.Hits.Hits {
+		result.Documents = append(result.Documents, hit.Source)
+
+		if len(hit.Highlight.Name) > 0 || len(hit.Highlight.Description) > 0 {
+			result.Highlights[hit.Source.Id] = domain.ProductSearchHighlight{
+				Name:        hit.Highlight.Name,
+				Description: hit.Highlight.Description,
+			}
+		}
+	}
+
+	// facet aggregations are wrapped in a filter aggregation named after the
+	// dimension, with the buckets under "facet"
+	for name, aggregation := range hits.Aggregations {
+		result.Facets[name] = aggregation.Facet.Buckets
+	}
+
+	return result, nil
+}
+
+// FindDocument reads one product from the product index.
+func (repository *ProductSearchRepository) FindDocument(ctx context.Context, productID int) (domain.ProductDocument, error) {
+	client := repository.ElasticSearch
+	res, err := client.Get(
+		repository.IndexName,
+		strconv.Itoa(productID),
+		client.Get.WithContext(ctx),
+	)
+	if err != nil {
+		return domain.ProductDocument{}, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return domain.ProductDocument{}, errors.New("product not found")
+	}
+
+	if res.IsError() {
+		return domain.ProductDocument{}, fmt.Errorf("elasticsearch get failed: %s", res.Status())
+	}
+
+	hit := struct {
+		Source domain.ProductDocument `json:"_source"`
+	}{}
+
+	err = json.NewDecoder(res.Body).Decode(&hit)
+	if err != nil {
+		return domain.ProductDocument{}, err
+	}
+
+	return hit.Source, nil
+}
+
+// Similar finds products whose name and description read like document's.
+// Sharing its size or price band ranks a product higher, and out of stock
+// items of the same seller are left out since they would crowd the rail.
+func (repository *ProductSearchRepository) Similar(ctx context.Context, document domain.ProductDocument, limit int) ([]domain.ProductDocument, error) {
+	should := []interface{}{
+		map[string]interface{}{"term": map[string]interface{}{"size": map[string]interface{}{"value": document.Size, "boost": 2}}},
+	}
+
+	for _, priceRange := range domain.ProductPriceRanges {
+		if document.Price < priceRange.From || (priceRange.To > 0 && document.Price >= priceRange.To) {
+			continue
+		}
+
+		bounds := map[string]interface{}{"gte": priceRange.From, "boost": 1.5}
+		if priceRange.To > 0 {
+			bounds["lt"] = priceRange.To
+		}
+
+		should = append(should, map[string]interface{}{"range": map[string]interface{}{"price": bounds}})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"more_like_this": map[string]interface{}{
+						"fields":          []string{"name", "description"},
+						"like":            []interface{}{map[string]interface{}{"_index": repository.IndexName, "_id": strconv.Itoa(document.Id)}},
+						"min_term_freq":   1,
+						"min_doc_freq":    1,
+						"max_query_terms": 25,
+					},
+				},
+				"should": should,
+				"must_not": []interface{}{
+					map[string]interface{}{"ids": map[string]interface{}{"values": []string{strconv.Itoa(document.Id)}}},
+					map[string]interface{}{
+						"bool": map[string]interface{}{
+							"filter": []interface{}{
+								map[string]interface{}{"term": map[string]interface{}{"seller_id": document.Seller_id}},
+								map[string]interface{}{"range": map[string]interface{}{"quantity": map[string]interface{}{"lte": 0}}},
+							},
+						},
+					},
+				},
+			},
+		},
+		"size": limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client := repository.ElasticSearch
+	res, err := client.Search(
+		client.Search.WithContext(ctx),
+		client.Search.WithIndex(repository.IndexName),
+		client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("elasticsearch similar failed: %s", res.Status())
+	}
+
+	hits := productSearchHits{}
+	err = json.NewDecoder(res.Body).Decode(&hits)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := []domain.ProductDocument{}
+	for _, hit := range hits.Hits.Hits {
+		documents = append(documents, hit.Source)
+	}
+
+	return documents, nil
+}
+
+// Ping reports the product index usable. An index that exists but holds no
+// documents yet counts as unavailable, Postgres knows more at that point.
+func (repository *ProductSearchRepository) Ping(ctx context.Context) error {
+	client := repository.ElasticSearch
+	res, err := client.Count(
+		client.Count.WithContext(ctx),
+		client.Count.WithIndex(repository.IndexName),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch count failed: %s", res.Status())
+	}
+
+	count := struct {
+		Count int64 `json:"count"`
+	}{}
+
+	err = json.NewDecoder(res.Body).Decode(&count)
+	if err != nil {
+		return err
+	}
+
+	if count.Count == 0 {
+		return errors.New("search index is empty")
+	}
+
+	return nil
+}
+
+// Bulk sends actions in one _bulk request. A failed request returns its HTTP
+// status (0 when Elasticsearch was not reached), otherwise every action gets
+// its own item status in order.
+func (repository *ProductSearchRepository) Bulk(ctx context.Context, actions []domain.SearchIndexAction) ([]domain.SearchBulkItem, int, error) {
+	body := bytes.Buffer{}
+
+	for _, action := range actions {
+		meta, err := json.Marshal(map[string]interface{}{
+			action.Operation: map[string]interface{}{
+				"_index":       repository.IndexName,
+				"_id":          strconv.Itoa(action.Product_id),
+				"version":      action.Version,
+				"version_type": "external_gte",
+			},
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+
+		body.Write(meta)
+		body.WriteByte('\n')
+
+		if action.Operation == "index" {
+			body.Write(action.Body)
+			body.WriteByte('\n')
+		}
+	}
+
+	client := repository.ElasticSearch
+	res, err := client.Bulk(
+		bytes.NewReader(body.Bytes()),
+		client.Bulk.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, res.StatusCode, fmt.Errorf("elasticsearch bulk failed: %s", res.Status())
+	}
+
+	response := struct {
+		Items []map[string]struct {
+			Status int `json:"status"`
+			Error  *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}{}
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return nil, res.StatusCode, err
+	}
+
+	if len(response.Items) != len(actions) {
+		return nil, res.StatusCode, fmt.Errorf("elasticsearch bulk answered %d of %d actions", len(response.Items), len(actions))
+	}
+
+	items := []domain.SearchBulkItem{}
+	for _, item := range response.Items {
+		for _, result := range item {
+			bulkItem := domain.SearchBulkItem{Status: result.Status}
+			if result.Error != nil {
+				bulkItem.Error = result.Error.Type + ": " + result.Error.Reason
+			}
+
+			items = append(items, bulkItem)
+		}
+	}
+
+	return items, res.StatusCode, nil
+}
+
+type productSuggestionDocument struct {
+	Suggest struct {
+		Input  []string `json:"input"`
+		Weight int      `json:"weight"`
+	} `json:"suggest"`
+	Type       string `json:"type"`
+	Text       string `json:"text"`
+	Popularity int    `json:"popularity"`
+}
+
+type productSuggestOptions struct {
+	Suggest map[string][]struct {
+		Options []struct {
+			Source productSuggestionDocument `json:"_source"`
+		} `json:"options"`
+	} `json:"suggest"`
+}
+
+// IndexSuggestion stores a suggestion under id. Every word of the text is a
+// completion input, so "polos" also suggests "Kaos Polos".
+func (repository *ProductSearchRepository) IndexSuggestion(ctx context.Context, id string, suggestion domain.ProductSuggestion) error {
+	document := productSuggestionDocument{
+		Type:       suggestion.Type,
+		Text:       suggestion.Text,
+		Popularity: suggestion.Popularity,
+	}
+
+	words := strings.Fields(suggestion.Text)
+	for i := range words {
+		document.Suggest.Input = append(document.Suggest.Input, strings.Join(words[i:], " "))
+	}
+
+	document.Suggest.Weight = suggestion.Popularity
+
+	body, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	client := repository.ElasticSearch
+	res, err := client.Index(
+		repository.SuggestIndexName,
+		bytes.NewReader(body),
+		client.Index.WithContext(ctx),
+		client.Index.WithDocumentID(id),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch index suggestion failed: %s", res.Status())
+	}
+
+	return nil
+}
+
+func (repository *ProductSearchRepository) DeleteSuggestion(ctx context.Context, id string) error {
+	client := repository.ElasticSearch
+	res, err := client.Delete(
+		repository.SuggestIndexName,
+		id,
+		client.Delete.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch delete suggestion failed: %s", res.Status())
+	}
+
+	return nil
+}
+
+// Suggest completes prefix against product names and seller names, each list
+// ordered by popularity.
+func (repository *ProductSearchRepository) Suggest(ctx context.Context, prefix string, limit int) (map[string][]domain.ProductSuggestion, error) {
+	suggester := func(suggestionType string) map[string]interface{} {
+		return map[string]interface{}{
+			"prefix": prefix,
+			"completion": map[string]interface{}{
+				"field":           "suggest",
+				"size":            limit,
+				"skip_duplicates": true,
+				"contexts":        map[string]interface{}{"type": []string{suggestionType}},
+			},
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"_source": []string{"type", "text", "popularity"},
+		"suggest": map[string]interface{}{
+			"product": suggester("product"),
+			"seller":  suggester("seller"),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client := repository.ElasticSearch
+	res, err := client.Search(
+		client.Search.WithContext(ctx),
+		client.Search.WithIndex(repository.SuggestIndexName),
+		client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("elasticsearch suggest failed: %s", res.Status())
+	}
+
+	options := productSuggestOptions{}
+	err = json.NewDecoder(res.Body).Decode(&options)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := map[string][]domain.ProductSuggestion{}
+	for name, entries := range options.Suggest {
+		for _, entry := range entries {
+			for _, option := range entry.Options {
+				suggestions[name] = append(suggestions[name], domain.ProductSuggestion{
+					Type:       option.Source.Type,
+					Text:       option.Source.Text,
+					Popularity: option.Source.Popularity,
+				})
+			}
+		}
+	}
+
+	return suggestions, nil
+}
+
+// productSearchQuery matches name, tags and description with typo tolerance, a
+// hit on the name weighs more than one on a tag or in the description. The
+// category is not a facet, so it narrows the query itself rather than the
+// post_filter.
+func productSearchQuery(query domain.ProductSearchQuery) map[string]interface{} {
+	match := map[string]interface{}{"match_all": map[string]interface{}{}}
+	if query.Text != "" {
+		match = map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":     query.Text,
+				"fields":    []string{"name^3", "tags^2", "description"},
+				"fuzziness": "AUTO",
+			},
+		}
+	}
+
+	if query.Category_id == 0 {
+		return match
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must":   match,
+			"filter": map[string]interface{}{"term": map[string]interface{}{"category_ids": query.Category_id}},
+		},
+	}
+}
+
+// productSearchHighlight marks the matched terms in the whole name and in up
+// to three description fragments. The html encoder escapes the text around the
+// tags, so fragments are safe to render as they are.
+func productSearchHighlight() map[string]interface{} {
+	return map[string]interface{}{
+		"pre_tags":  []string{"<em>"},
+		"post_tags": []string{"</em>"},
+		"encoder":   "html",
+		"fields": map[string]interface{}{
+			"name":        map[string]interface{}{"number_of_fragments": 0},
+			"description": map[string]interface{}{"fragment_size": 150, "number_of_fragments": 3},
+		},
+	}
+}
+
+// productSearchFilters maps each facet dimension with an active filter to its
+// filter clause.
+func productSearchFilters(query domain.ProductSearchQuery) map[string]interface{} {
+	filters := map[string]interface{}{}
+
+	if len(query.Sizes) > 0 {
+		filters["size"] = map[string]interface{}{"terms": map[string]interface{}{"size": query.Sizes}}
+	}
+
+	if len(query.Statuses) > 0 {
+		filters["status"] = map[string]interface{}{"terms": map[string]interface{}{"status": query.Statuses}}
+	}
+
+	if query.Min_price > 0 || query.Max_price > 0 {
+		bounds := map[string]interface{}{"gte": query.Min_price}
+		if query.Max_price > 0 {
+			bounds["lt"] = query.Max_price
+		}
+
+		filters["price"] = map[string]interface{}{"range": map[string]interface{}{"price": bounds}}
+	}
+
+	if query.Min_weight > 0 || query.Max_weight > 0 {
+		bounds := map[string]interface{}{"gte": query.Min_weight}
+		if query.Max_weight > 0 {
+			bounds["lt"] = query.Max_weight
+		}
+
+		filters["weight"] = map[string]interface{}{"range": map[string]interface{}{"weight": bounds}}
+	}
+
+	return filters
+}
+
+// productSearchFilterClause ANDs every filter except the excluded dimension.
+func productSearchFilterClause(filters map[string]interface{}, exclude string) map[string]interface{} {
+	clauses := []interface{}{}
+
+	for _, dimension := range domain.ProductFacetDimensions {
+		filter, ok := filters[dimension]
+		if ok && dimension != exclude {
+			clauses = append(clauses, filter)
+		}
+	}
+
+	return map[string]interface{}{"bool": map[string]interface{}{"filter": clauses}}
+}
+
+func productSearchAggregations(filters map[string]interface{}) map[string]interface{} {
+	facets := map[string]interface{}{
+		"size":   map[string]interface{}{"terms": map[string]interface{}{"field": "size", "size": 20}},
+		"status": map[string]interface{}{"terms": map[string]interface{}{"field": "status", "size": 10}},
+		"price":  map[string]interface{}{"range": map[string]interface{}{"field": "price", "ranges": productSearchRanges(domain.ProductPriceRanges)}},
+		"weight": map[string]interface{}{"range": map[string]interface{}{"field": "weight", "ranges": productSearchRanges(domain.ProductWeightRanges)}},
+	}
+
+	aggregations := map[string]interface{}{}
+	for _, dimension := range domain.ProductFacetDimensions {
+		aggregations[dimension] = map[string]interface{}{
+			"filter": productSearchFilterClause(filters, dimension),
+			"aggs":   map[string]interface{}{"facet": facets[dimension]},
+		}
+	}
+
+	return aggregations
+}
+
+func productSearchRanges(searchRanges []domain.ProductSearchRange) []map[string]interface{} {
+	ranges := []map[string]interface{}{}
+	for _, searchRange := range searchRanges {
+		bounds := map[string]interface{}{"key": searchRange.Key}
+		if searchRange.From > 0 {
+			bounds["from"] = searchRange.From
+		}
+		if searchRange.To > 0 {
+			bounds["to"] = searchRange.To
+		}
+
+		ranges = append(ranges, bounds)
+	}
+
+	return ranges
+}
+
+func productSearchSort(sort string) []interface{} {
+	switch sort {
+	case "price_asc":
+		return []interface{}{map[string]string{"price": "asc"}, "_score"}
+	case "price_desc":
+		return []interface{}{map[string]string{"price": "desc"}, "_score"}
+	case "newest":
+		return []interface{}{map[string]string{"created_at": "desc"}, "_score"}
+	default:
+		return []interface{}{"_score", map[string]string{"created_at": "desc"}}
+	}
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"time"
+)
+
+type ProductStatusRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewProductStatusRepository(zerolog *zerolog.Logger, db *sql.DB) *ProductStatusRepository {
+	return &ProductStatusRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+// FindProductWithTx locks the product until the transaction ends and reads
+// what a transition is checked against.
+func (repository *ProductStatusRepository) FindProductWithTx(ctx context.Context, tx *sql.Tx, productID int) (domain.Product, error) {
+	query := "SELECT id,seller_id,quantity,status FROM products WHERE id=$1 AND deleted_at IS NULL FOR UPDATE"
+	row, err := tx.QueryContext(ctx, query, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	product := domain.Product{}
+
+	if row.Next() {
+		err = row.Scan(&product.Id, &product.Seller_id, &product.Quantity, &product.Status)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return product, nil
+	} else {
+		return product, errors.New("product not found")
+	}
+}
+
+func (repository *ProductStatusRepository) UpdateStatusWithTx(ctx context.Context, tx *sql.Tx, productID int, status string, updatedAt *time.Time) {
+	query := "UPDATE products SET status = $1, updated_at = $2 WHERE id = $3"
+	_, err := tx.ExecContext(ctx, query, status, updatedAt, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+// stockStatus is the status column of a products update that sets the
+// quantity to the quantity expression: a Ready product whose stock runs out is
+// SoldOut, a SoldOut product with stock again is Ready, any other status is
+// kept. Stock updates set it in the same statement, so no reader ever sees
+// the new quantity with the old status.
+func stockStatus(quantity string) string {
+	return "CASE WHEN products.deleted_at IS NULL AND products.status = 'Ready' AND " + quantity + " <= 0 THEN 'SoldOut' WHEN products.deleted_at IS NULL AND products.status = 'SoldOut' AND " + quantity + " > 0 THEN 'Ready' ELSE products.status END"
+}
+
+// stockStatusFrom locks the product a stock update changes, so the update
+// can return the status it replaced next to the one it set.
+const stockStatusFrom = "FROM (SELECT id,status FROM products WHERE id = $%d FOR UPDATE) AS previous WHERE products.id = previous.id"
+
+const stockStatusReturning = " RETURNING products.seller_id,previous.status,products.status"
+
+// scanStockStatusChange reads the status change returned by a stock update.
+// found is false when the update matched no product, From_status equals
+// To_status when the status was kept.
+func scanStockStatusChange(log *zerolog.Logger, row *sql.Rows, productID int, updatedAt *time.Time) (change domain.ProductStatusChange, found bool) {
+	defer row.Close()
+
+	change = domain.ProductStatusChange{
+		Product_id: productID,
+		Reason:     "stock",
+		Created_at: updatedAt,
+	}
+
+	if row.Next() {
+		err := row.Scan(&change.Seller_id, &change.From_status, &change.To_status)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return change, true
+	}
+
+	return change, false
+}
+
+// UpdateStockStatusWithTx moves a Ready product without stock to SoldOut, and
+// a SoldOut product with stock back to Ready. Stock updates already do this,
+// it repairs rows changed by anything else. It checks the row as it is now,
+// so a stale or redelivered change event changes nothing.
+func (repository *ProductStatusRepository) UpdateStockStatusWithTx(ctx context.Context, tx *sql.Tx, productID int, updatedAt *time.Time) (domain.ProductStatusChange, bool) {
+	query := "UPDATE products SET status = CASE WHEN products.quantity <= 0 THEN 'SoldOut' ELSE 'Ready' END, updated_at = $1 FROM (SELECT id,status FROM products WHERE id = $2 AND deleted_at IS NULL FOR UPDATE) AS previous WHERE products.id = previous.id AND ((products.status = 'Ready' AND products.quantity <= 0) OR (products.status = 'SoldOut' AND products.quantity > 0)) RETURNING products.seller_id,previous.status,products.status"
+	row, err := tx.QueryContext(ctx, query, updatedAt, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	change := domain.ProductStatusChange{
+		Product_id: productID,
+		Reason:     "stock",
+		Created_at: updatedAt,
+	}
+
+	if row.Next() {
+		err = row.Scan(&change.Seller_id, &change.From_status, &change.To_status)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return change, true
+	}
+
+	return change, false
+}
+
+func (repository *ProductStatusRepository) CreateHistoryWithTx(ctx context.Context, tx *sql.Tx, change domain.ProductStatusChange) int64 {
+	query := "INSERT INTO product_status_history (product_id,from_status,to_status,reason,actor_id,created_at) VALUES ($1,$2,$3,$4,$5,$6) RETURNING id"
+
+	var id int64
+	err := tx.QueryRowContext(ctx, query, change.Product_id, change.From_status, change.To_status, change.Reason, change.Actor_id, change.Created_at).Scan(&id)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return id
+}
+
+// CreateSellerArchiveHistoryWithTx records the archiving of every product of
+// a deleted seller. It has to run before the products are archived.
+func (repository *ProductStatusRepository) CreateSellerArchiveHistoryWithTx(ctx context.Context, tx *sql.Tx, sellerID string, createdAt *time.Time) []domain.ProductStatusChange {
+	query := "INSERT INTO product_status_history (product_id,from_status,to_status,reason,actor_id,created_at) SELECT id,status,'Archived','seller_deleted',NULL,$1 FROM products WHERE seller_id = $2 AND deleted_at IS NULL AND status IS DISTINCT FROM 'Archived' RETURNING id,product_id,from_status,to_status,reason,actor_id,created_at"
+	return repository.createMany(ctx, tx, query, sellerID, createdAt, sellerID)
+}
+
+// CreateSellerRestoreHistoryWithTx records putting back the products archived
+// by a seller deletion saga. It has to run before the products are restored.
+func (repository *ProductStatusRepository) CreateSellerRestoreHistoryWithTx(ctx context.Context, tx *sql.Tx, sagaID string, sellerID string, createdAt *time.Time) []domain.ProductStatusChange {
+	query := "INSERT INTO product_status_history (product_id,from_status,to_status,reason,actor_id,created_at) SELECT products.id,products.status,seller_deletion_products.previous_status,'seller_restored',NULL,$1 FROM products JOIN seller_deletion_products ON seller_deletion_products.product_id = products.id WHERE seller_deletion_products.saga_id = $2 AND products.status <> seller_deletion_products.previous_status RETURNING id,product_id,from_status,to_status,reason,actor_id,created_at"
+	return repository.createMany(ctx, tx, query, sellerID, createdAt, sagaID)
+}
+
+func (repository *ProductStatusRepository) createMany(ctx context.Context, tx *sql.Tx, query string, sellerID string, args ...interface{}) []domain.ProductStatusChange {
+	row, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	changes := []domain.ProductStatusChange{}
+
+	for row.Next() {
+		change := repository.scan(row)
+		change.Seller_id = sellerID
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+func (repository *ProductStatusRepository) FindHistoryByProductId(ctx context.Context, productID int) []domain.ProductStatusChange {
+	query := "SELECT id,product_id,from_status,to_status,reason,actor_id,created_at FROM product_status_history WHERE product_id=$1 ORDER BY created_at DESC, id DESC"
+	row, err := repository.DB.QueryContext(ctx, query, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	changes := []domain.ProductStatusChange{}
+
+	for row.Next() {
+		changes = append(changes, repository.scan(row))
+	}
+
+	return changes
+}
+
+func (repository *ProductStatusRepository) scan(row *sql.Rows) domain.ProductStatusChange {
+	change := domain.ProductStatusChange{}
+
+	err := row.Scan(&change.Id, &change.Product_id, &change.From_status, &change.To_status, &change.Reason, &change.Actor_id, &change.Created_at)
+	if err != nil {
+		respErr := errors.New("failed to scan query result")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return change
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+)
+
+// ProductSuggestionRepository computes autocomplete popularity from Postgres.
+type ProductSuggestionRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewProductSuggestionRepository(zerolog *zerolog.Logger, db *sql.DB) *ProductSuggestionRepository {
+	return &ProductSuggestionRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+// FindProductName counts the listings sharing a name, case-insensitively.
+func (repository *ProductSuggestionRepository) FindProductName(ctx context.Context, name string) domain.ProductSuggestion {
+	query := "SELECT count(*) FROM products WHERE lower(name)=lower($1) AND deleted_at IS NULL AND status NOT IN ('Draft', 'Archived')"
+	row, err := repository.DB.QueryContext(ctx, query, name)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	suggestion := domain.ProductSuggestion{
+		Type: "product",
+		Text: name,
+	}
+
+	if row.Next() {
+		err = row.Scan(&suggestion.Popularity)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+	}
+
+	return suggestion
+}
+
+// CountSellerProducts counts the products a seller has listed, which ranks the
+// seller's name.
+func (repository *ProductSuggestionRepository) CountSellerProducts(ctx context.Context, sellerID string) int {
+	query := "SELECT count(*) FROM products WHERE seller_id=$1 AND deleted_at IS NULL AND status NOT IN ('Draft', 'Archived')"
+	row, err := repository.DB.QueryContext(ctx, query, sellerID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	var count int
+
+	if row.Next() {
+		err = row.Scan(&count)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+	}
+
+	return count
+}
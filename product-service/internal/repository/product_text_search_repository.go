@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// ProductTextSearchRepository searches products with Postgres full-text search
+// over the generated search_vector column. It is the fallback when
+// Elasticsearch cannot answer, so relevance is rougher and there is no typo
+// tolerance.
+type ProductTextSearchRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewProductTextSearchRepository(zerolog *zerolog.Logger, db *sql.DB) *ProductTextSearchRepository {
+	return &ProductTextSearchRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+func (repository *ProductTextSearchRepository) Ping(ctx context.Context) error {
+	return repository.DB.PingContext(ctx)
+}
+
+func (repository *ProductTextSearchRepository) Search(ctx context.Context, query domain.ProductSearchQuery) (domain.ProductSearchResult, error) {
+	result := domain.ProductSearchResult{
+		Backend:    "postgres",
+		Documents:  []domain.ProductDocument{},
+		Highlights: map[int]domain.ProductSearchHighlight{},
+		Facets:     map[string][]domain.ProductSearchFacetBucket{},
+	}
+
+	where, args := productTextSearchWhere(query, "")
+
+	err := repository.DB.QueryRowContext(ctx, "SELECT count(*) FROM products WHERE "+where, args...).Scan(&result.Total)
+	if err != nil {
+		return domain.ProductSearchResult{}, err
+	}
+
+	order := productTextSearchOrder(query, len(args))
+	if query.Text != "" && (query.Sort == "" || query.Sort == "relevance") {
+		args = append(args, query.Text)
+	}
+
+	args = append(args, query.Limit, query.Offset)
+	limit := len(args) - 1
+
+	// the search text is always the first placeholder, see productTextSearchWhere
+	headlines := "'',''"
+	if query.Text != "" {
+		args = append(args, productTextSearchNameHeadline, productTextSearchDescriptionHeadline)
+		headlines = fmt.Sprintf("ts_headline('indonesian', name, websearch_to_tsquery('indonesian', $1), $%d),ts_headline('indonesian', description, websearch_to_tsquery('indonesian', $1), $%d)", len(args)-1, len(args))
+	}
+
+	selectQuery := fmt.Sprintf("SELECT id,seller_id,name,quantity,price,weight,size,COALESCE(status,''),description,created_at,updated_at,%s FROM products WHERE %s ORDER BY %s LIMIT $%d OFFSET $%d", headlines, where, order, limit, limit+1)
+
+	rows, err := repository.DB.QueryContext(ctx, selectQuery, args...)
+	if err != nil {
+		return domain.ProductSearchResult{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		document := domain.ProductDocument{}
+		var nameHeadline, descriptionHeadline string
+		err = rows.Scan(&document.Id, &document.Seller_id, &document.Name, &document.Quantity, &document.Price, &document.Weight, &document.Size, &document.Status, &document.Description, &document.Created_at, &document.Updated_at, &nameHeadline, &descriptionHeadline)
+		if err != nil {
+			return domain.ProductSearchResult{}, err
+		}
+
+		result.Documents = append(result.Documents, document)
+
+		highlight := domain.ProductSearchHighlight{
+			Name:        productTextSearchFragments(nameHeadline),
+			Description: productTextSearchFragments(descriptionHeadline),
+		}
+		if len(highlight.Name) > 0 || len(highlight.Description) > 0 {
+			result.Highlights[document.Id] = highlight
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return domain.ProductSearchResult{}, err
+	}
+
+	for _, dimension := range domain.ProductFacetDimensions {
+		result.Facets[dimension], err = repository.facet(ctx, query, dimension)
+		if err != nil {
+			return domain.ProductSearchResult{}, err
+		}
+	}
+
+	return result, nil
+}
+
+// facet counts one dimension under every filter except its own, the same way
+// the Elasticsearch facets are computed.
+func (repository *ProductTextSearchRepository) facet(ctx context.Context, query domain.ProductSearchQuery, dimension string) ([]domain.ProductSearchFacetBucket, error) {
+	where, args := productTextSearchWhere(query, dimension)
+
+	var column string
+	var ranges []domain.ProductSearchRange
+
+	switch dimension {
+	case "size":
+		column = "size"
+	case "status":
+		column = "COALESCE(status,'')"
+	case "price":
+		ranges = domain.ProductPriceRanges
+		column = productTextSearchBucket("price", ranges)
+	case "weight":
+		ranges = domain.ProductWeightRanges
+		column = productTextSearchBucket("weight", ranges)
+	}
+
+	rows, err := repository.DB.QueryContext(ctx, fmt.Sprintf("SELECT %s AS bucket,count(*) FROM products WHERE %s GROUP BY bucket ORDER BY count(*) DESC, bucket LIMIT 20", column, where), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := map[string]int64{}
+	buckets := []domain.ProductSearchFacetBucket{}
+
+	for rows.Next() {
+		bucket := domain.ProductSearchFacetBucket{}
+		err = rows.Scan(&bucket.Key, &bucket.Doc_count)
+		if err != nil {
+			return nil, err
+		}
+
+		counts[bucket.Key] = bucket.Doc_count
+		buckets = append(buckets, bucket)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if ranges == nil {
+		return buckets, nil
+	}
+
+	// range facets list every range in order, empty ones included
+	buckets = []domain.ProductSearchFacetBucket{}
+	for _, searchRange := range ranges {
+		bucket := domain.ProductSearchFacetBucket{
+			Key:       searchRange.Key,
+			Doc_count: counts[searchRange.Key],
+		}
+
+		if searchRange.From > 0 {
+			from := searchRange.From
+			bucket.From = &from
+		}
+		if searchRange.To > 0 {
+			to := searchRange.To
+			bucket.To = &to
+		}
+
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, nil
+}
+
+// productTextSearchWhere builds the filter of a search, leaving out the
+// excluded dimension. Placeholders are numbered in the order of the returned
+// arguments.
+func productTextSearchWhere(query domain.ProductSearchQuery, exclude string) (string, []interface{}) {
+	args := []interface{}{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	clauses := []string{"deleted_at IS NULL", "status NOT IN ('Draft', 'Archived')"}
+
+	if query.Text != "" {
+		clauses = append(clauses, "search_vector @@ websearch_to_tsquery('indonesian', "+arg(query.Text)+")")
+	}
+
+	if query.Category_id > 0 {
+		clauses = append(clauses, "category_id IN ("+categorySubtree(arg(query.Category_id))+")")
+	}
+
+	if len(query.Sizes) > 0 && exclude != "size" {
+		clauses = append(clauses, "size = ANY("+arg(query.Sizes)+"::text[])")
+	}
+
+	if len(query.Statuses) > 0 && exclude != "status" {
+		clauses = append(clauses, "status = ANY("+arg(query.Statuses)+"::text[])")
+	}
+
+	if exclude != "price" {
+		if query.Min_price > 0 {
+			clauses = append(clauses, "price >= "+arg(query.Min_price))
+		}
+		if query.Max_price > 0 {
+			clauses = append(clauses, "price < "+arg(query.Max_price))
+		}
+	}
+
+	if exclude != "weight" {
+		if query.Min_weight > 0 {
+			clauses = append(clauses, "weight >= "+arg(query.Min_weight))
+		}
+		if query.Max_weight > 0 {
+			clauses = append(clauses, "weight < "+arg(query.Max_weight))
+		}
+	}
+
+	return strings.Join(clauses, " AND "), args
+}
+
+// productTextSearchOrder mirrors the Elasticsearch sort options. Relevance
+// ranking takes the search text as the placeholder after the filter ones.
+func productTextSearchOrder(query domain.ProductSearchQuery, argCount int) string {
+	switch query.Sort {
+	case "price_asc":
+		return "price ASC, id ASC"
+	case "price_desc":
+		return "price DESC, id DESC"
+	case "newest":
+		return "created_at DESC, id DESC"
+	}
+
+	if query.Text == "" {
+		return "created_at DESC, id DESC"
+	}
+
+	return fmt.Sprintf("ts_rank(search_vector, websearch_to_tsquery('indonesian', $%d)) DESC, created_at DESC, id DESC", argCount+1)
+}
+
+// ts_headline marks matches with control characters instead of <em>, so the
+// text can be HTML escaped before the tags go in, the same as the Elasticsearch
+// html encoder.
+const (
+	productTextSearchNameHeadline        = "HighlightAll=true, StartSel=\x02, StopSel=\x03"
+	productTextSearchDescriptionHeadline = "MaxFragments=3, MinWords=10, MaxWords=25, StartSel=\x02, StopSel=\x03, FragmentDelimiter=\x1f"
+)
+
+// productTextSearchFragments turns a ts_headline into the fragments with at
+// least one match, ts_headline also returns text when nothing matched.
+func productTextSearchFragments(headline string) []string {
+	fragments := []string{}
+	for _, fragment := range strings.Split(headline, "\x1f") {
+		if !strings.Contains(fragment, "\x02") {
+			continue
+		}
+
+		fragment = html.EscapeString(strings.TrimSpace(fragment))
+		fragment = strings.NewReplacer("\x02", "<em>", "\x03", "</em>").Replace(fragment)
+		fragments = append(fragments, fragment)
+	}
+
+	if len(fragments) == 0 {
+		return nil
+	}
+
+	return fragments
+}
+
+// productTextSearchBucket maps a numeric column to the key of its facet range.
+// The bounds are constants from domain, not user input.
+func productTextSearchBucket(column string, ranges []domain.ProductSearchRange) string {
+	cases := []string{}
+	for _, searchRange := range ranges {
+		conditions := []string{}
+		if searchRange.From > 0 {
+			conditions = append(conditions, column+" >= "+strconv.FormatFloat(searchRange.From, 'f', -1, 64))
+		}
+		if searchRange.To > 0 {
+			conditions = append(conditions, column+" < "+strconv.FormatFloat(searchRange.To, 'f', -1, 64))
+		}
+		if len(conditions) == 0 {
+			conditions = append(conditions, "true")
+		}
+
+		cases = append(cases, fmt.Sprintf("WHEN %s THEN '%s'", strings.Join(conditions, " AND "), searchRange.Key))
+	}
+
+	return "CASE " + strings.Join(cases, " ") + " END"
+}
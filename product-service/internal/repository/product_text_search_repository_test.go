@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProductTextSearchFragments(t *testing.T) {
+	tests := []struct {
+		name     string
+		headline string
+		want     []string
+	}{
+		{name: "no match", headline: "Kemeja batik tulis", want: nil},
+		{name: "empty", headline: "", want: nil},
+		{name: "one match", headline: " Kemeja \x02batik\x03 tulis ", want: []string{"Kemeja <em>batik</em> tulis"}},
+		{name: "fragments without a match are dropped", headline: "kain \x02batik\x03 halus\x1fukuran besar\x1f\x02batik\x03 cap", want: []string{"kain <em>batik</em> halus", "<em>batik</em> cap"}},
+		{name: "text is escaped, marks are not", headline: "<b>\x02batik\x03</b> & \"sutra\"", want: []string{"&lt;b&gt;<em>batik</em>&lt;/b&gt; &amp; &#34;sutra&#34;"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := productTextSearchFragments(test.headline)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("productTextSearchFragments(%q) = %q, want %q", test.headline, got, test.want)
+			}
+		})
+	}
+}
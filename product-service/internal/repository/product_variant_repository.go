@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"time"
+)
+
+type ProductVariantRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewProductVariantRepository(zerolog *zerolog.Logger, db *sql.DB) *ProductVariantRepository {
+	return &ProductVariantRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+func (repository *ProductVariantRepository) CreateWithTx(ctx context.Context, tx *sql.Tx, variant domain.ProductVariant) int {
+	query := "INSERT INTO product_variants (product_id,sku,size,attributes,price,weight,quantity,created_at,updated_at) VALUES ($1,$2,$3,$4::jsonb,$5,$6,$7,$8,$9) RETURNING id"
+
+	var id int
+	err := tx.QueryRowContext(ctx, query, variant.Product_id, variant.Sku, variant.Size, repository.attributesJSON(variant.Attributes), variant.Price, variant.Weight, variant.Quantity, variant.Created_at, variant.Updated_at).Scan(&id)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return id
+}
+
+func (repository *ProductVariantRepository) UpdateWithTx(ctx context.Context, tx *sql.Tx, variant domain.ProductVariant) {
+	query := "UPDATE product_variants SET sku = $1, size = $2, attributes = $3::jsonb, price = $4, weight = $5, quantity = $6, updated_at = $7 WHERE id = $8"
+	_, err := tx.ExecContext(ctx, query, variant.Sku, variant.Size, repository.attributesJSON(variant.Attributes), variant.Price, variant.Weight, variant.Quantity, variant.Updated_at, variant.Id)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *ProductVariantRepository) DeleteWithTx(ctx context.Context, tx *sql.Tx, variantID int) {
+	query := "DELETE FROM product_variants WHERE id=$1"
+	_, err := tx.ExecContext(ctx, query, variantID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+// FindByIdWithTx locks the variant until the transaction ends.
+func (repository *ProductVariantRepository) FindByIdWithTx(ctx context.Context, tx *sql.Tx, productID int, variantID int) (domain.ProductVariant, error) {
+	query := "SELECT id,product_id,sku,size,attributes,price,weight,quantity,created_at,updated_at FROM product_variants WHERE id=$1 AND product_id=$2 FOR UPDATE"
+	row, err := tx.QueryContext(ctx, query, variantID, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	if row.Next() {
+		return repository.scan(row), nil
+	} else {
+		return domain.ProductVariant{}, errors.New("variant not found")
+	}
+}
+
+func (repository *ProductVariantRepository) FindByProductId(ctx context.Context, productID int) []domain.ProductVariant {
+	query := "SELECT id,product_id,sku,size,attributes,price,weight,quantity,created_at,updated_at FROM product_variants WHERE product_id=$1 ORDER BY id"
+	row, err := repository.DB.QueryContext(ctx, query, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	variants := []domain.ProductVariant{}
+
+	for row.Next() {
+		variants = append(variants, repository.scan(row))
+	}
+
+	return variants
+}
+
+// ExistsBySkuWithTx tells whether another variant, of any product, already
+// uses sku. SKUs compare case-insensitively.
+func (repository *ProductVariantRepository) ExistsBySkuWithTx(ctx context.Context, tx *sql.Tx, sku string, excludeID int) bool {
+	query := "SELECT EXISTS(SELECT 1 FROM product_variants WHERE lower(sku) = lower($1) AND id <> $2)"
+
+	var exists bool
+	err := tx.QueryRowContext(ctx, query, sku, excludeID).Scan(&exists)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return exists
+}
+
+// ExistsByOptionsWithTx tells whether another variant of the product already
+// has the same size and attributes.
+func (repository *ProductVariantRepository) ExistsByOptionsWithTx(ctx context.Context, tx *sql.Tx, variant domain.ProductVariant) bool {
+	query := "SELECT EXISTS(SELECT 1 FROM product_variants WHERE product_id = $1 AND size = $2 AND attributes = $3::jsonb AND id <> $4)"
+
+	var exists bool
+	err := tx.QueryRowContext(ctx, query, variant.Product_id, variant.Size, repository.attributesJSON(variant.Attributes), variant.Id).Scan(&exists)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return exists
+}
+
+// SyncProductWithTx sets the product's quantity to the stock of all its
+// variants and its price to the cheapest one, so listings, search and stock
+// alerts keep working on the product row. The product is left as it is once
+// its last variant is gone. Touching updated_at also emits the change event
+// that invalidates cached reads of the product. The status follows the new
+// stock in the same statement, the returned change tells whether it moved.
+func (repository *ProductVariantRepository) SyncProductWithTx(ctx context.Context, tx *sql.Tx, productID int, updatedAt *time.Time) domain.ProductStatusChange {
+	quantity := "COALESCE(variants.total_stock, products.quantity)"
+	query := "UPDATE products SET quantity = " + quantity + ", status = " + stockStatus(quantity) + ", price = COALESCE(variants.min_price, products.price), updated_at = $1 FROM (SELECT sum(quantity)::int AS total_stock, min(price) AS min_price FROM product_variants WHERE product_id = $2) AS variants, (SELECT id,status FROM products WHERE id = $2 FOR UPDATE) AS previous WHERE products.id = previous.id" + stockStatusReturning
+	row, err := tx.QueryContext(ctx, query, updatedAt, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	change, _ := scanStockStatusChange(repository.Log, row, productID, updatedAt)
+
+	return change
+}
+
+func (repository *ProductVariantRepository) attributesJSON(attributes map[string]string) string {
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
+
+	attributesJSON, err := json.Marshal(attributes)
+	if err != nil {
+		respErr := errors.New("failed to marshal variant attributes")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return string(attributesJSON)
+}
+
+func (repository *ProductVariantRepository) scan(row *sql.Rows) domain.ProductVariant {
+	variant := domain.ProductVariant{}
+	attributesJSON := []byte{}
+
+	err := row.Scan(&variant.Id, &variant.Product_id, &variant.Sku, &variant.Size, &attributesJSON, &variant.Price, &variant.Weight, &variant.Quantity, &variant.Created_at, &variant.Updated_at)
+	if err != nil {
+		respErr := errors.New("failed to scan query result")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	err = json.Unmarshal(attributesJSON, &variant.Attributes)
+	if err != nil {
+		respErr := fmt.Errorf("failed to unmarshal attributes of variant %d", variant.Id)
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return variant
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+)
+
+type ReplicationRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewReplicationRepository(zerolog *zerolog.Logger, db *sql.DB) *ReplicationRepository {
+	return &ReplicationRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+// FindSlot reads WAL retention of a logical slot together with the stats of the
+// walsender currently attached to it, if any.
+func (repository *ReplicationRepository) FindSlot(ctx context.Context, slotName string) (domain.ReplicationSlot, error) {
+	query := "SELECT s.slot_name,s.active,COALESCE(pg_wal_lsn_diff(pg_current_wal_lsn(), s.restart_lsn),0)::bigint,COALESCE(pg_wal_lsn_diff(pg_current_wal_lsn(), s.confirmed_flush_lsn),0)::bigint,COALESCE(EXTRACT(EPOCH FROM r.replay_lag),0)::float8,COALESCE(r.state,'') FROM pg_replication_slots s LEFT JOIN pg_stat_replication r ON r.pid = s.active_pid WHERE s.slot_name=$1"
+	row, err := repository.DB.QueryContext(ctx, query, slotName)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	slot := domain.ReplicationSlot{}
+
+	if row.Next() {
+		err = row.Scan(&slot.Slot_name, &slot.Active, &slot.Retained_wal_bytes, &slot.Confirmed_flush_lag_bytes, &slot.Replay_lag_seconds, &slot.Sender_state)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return slot, nil
+	} else {
+		return slot, errors.New("replication slot not found")
+	}
+}
+
+// CountProductChanges reads how many rows of products were ever inserted,
+// updated or deleted. Every one of them is a change the slot has to stream.
+func (repository *ReplicationRepository) CountProductChanges(ctx context.Context) int64 {
+	query := "SELECT COALESCE(SUM(n_tup_ins + n_tup_upd + n_tup_del),0)::bigint FROM pg_stat_user_tables WHERE relname='products'"
+	row, err := repository.DB.QueryContext(ctx, query)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	var changes int64
+
+	if row.Next() {
+		err = row.Scan(&changes)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+	}
+
+	return changes
+}
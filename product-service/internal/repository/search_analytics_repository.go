@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"time"
+)
+
+type SearchAnalyticsRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewSearchAnalyticsRepository(zerolog *zerolog.Logger, db *sql.DB) *SearchAnalyticsRepository {
+	return &SearchAnalyticsRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+// Add merges stat into the totals of its bucket and query.
+func (repository *SearchAnalyticsRepository) Add(ctx context.Context, stat domain.SearchQueryStat) {
+	query := "INSERT INTO search_query_stats (bucket,query,searches,zero_results,total_results,total_latency_ms) VALUES ($1,$2,$3,$4,$5,$6) ON CONFLICT (bucket,query) DO UPDATE SET searches = search_query_stats.searches + EXCLUDED.searches, zero_results = search_query_stats.zero_results + EXCLUDED.zero_results, total_results = search_query_stats.total_results + EXCLUDED.total_results, total_latency_ms = search_query_stats.total_latency_ms + EXCLUDED.total_latency_ms"
+	_, err := repository.DB.ExecContext(ctx, query, stat.Bucket, stat.Query, stat.Searches, stat.Zero_results, stat.Total_results, stat.Total_latency_ms)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+// FindTopQueries sums the buckets in [from, to) per query, most searched first.
+func (repository *SearchAnalyticsRepository) FindTopQueries(ctx context.Context, from time.Time, to time.Time, limit int) []domain.SearchQueryStat {
+	query := "SELECT query,sum(searches),sum(zero_results),sum(total_results),sum(total_latency_ms) FROM search_query_stats WHERE bucket >= $1 AND bucket < $2 GROUP BY query ORDER BY sum(searches) DESC, query LIMIT $3"
+
+	return repository.findStats(ctx, query, from, to, limit)
+}
+
+// FindZeroResultQueries lists the queries that came back empty in [from, to),
+// most frequent first.
+func (repository *SearchAnalyticsRepository) FindZeroResultQueries(ctx context.Context, from time.Time, to time.Time, limit int) []domain.SearchQueryStat {
+	query := "SELECT query,sum(searches),sum(zero_results),sum(total_results),sum(total_latency_ms) FROM search_query_stats WHERE bucket >= $1 AND bucket < $2 GROUP BY query HAVING sum(zero_results) > 0 ORDER BY sum(zero_results) DESC, query LIMIT $3"
+
+	return repository.findStats(ctx, query, from, to, limit)
+}
+
+func (repository *SearchAnalyticsRepository) findStats(ctx context.Context, query string, from time.Time, to time.Time, limit int) []domain.SearchQueryStat {
+	row, err := repository.DB.QueryContext(ctx, query, from, to, limit)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	stats := []domain.SearchQueryStat{}
+
+	for row.Next() {
+		stat := domain.SearchQueryStat{}
+		err = row.Scan(&stat.Query, &stat.Searches, &stat.Zero_results, &stat.Total_results, &stat.Total_latency_ms)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		stats = append(stats, stat)
+	}
+
+	return stats
+}
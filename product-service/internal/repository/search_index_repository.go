@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"net/http"
+)
+
+//go:embed mapping/*.json
+var searchIndexMappings embed.FS
+
+type searchIndexBody struct {
+	Mappings struct {
+		Meta struct {
+			Version int `json:"version"`
+		} `json:"_meta"`
+		Properties map[string]interface{} `json:"properties"`
+	} `json:"mappings"`
+}
+
+type SearchIndexRepository struct {
+	Log           *zerolog.Logger
+	ElasticSearch *elasticsearch.Client
+}
+
+func NewSearchIndexRepository(zerolog *zerolog.Logger, elasticsearch *elasticsearch.Client) *SearchIndexRepository {
+	return &SearchIndexRepository{
+		Log:           zerolog,
+		ElasticSearch: elasticsearch,
+	}
+}
+
+// FindDefinition reads mapping/<name>.json, the settings and mappings an index
+// is created with.
+func (repository *SearchIndexRepository) FindDefinition(name string) domain.SearchIndexDefinition {
+	body, err := searchIndexMappings.ReadFile("mapping/" + name + ".json")
+	if err != nil {
+		respErr := errors.New("search index definition not found")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	parsed := searchIndexBody{}
+	err = json.Unmarshal(body, &parsed)
+	if err != nil {
+		respErr := errors.New("failed to unmarshal search index definition")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return domain.SearchIndexDefinition{
+		Name:       name,
+		Version:    parsed.Mappings.Meta.Version,
+		Body:       body,
+		Properties: parsed.Mappings.Properties,
+	}
+}
+
+// FindByAlias resolves alias to its concrete index. An index created by
+// dynamic mapping carries no _meta and reports version 0.
+func (repository *SearchIndexRepository) FindByAlias(ctx context.Context, alias string) (domain.SearchIndex, error) {
+	client := repository.ElasticSearch
+	res, err := client.Indices.GetMapping(
+		client.Indices.GetMapping.WithContext(ctx),
+		client.Indices.GetMapping.WithIndex(alias),
+	)
+	if err != nil {
+		return domain.SearchIndex{}, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return domain.SearchIndex{}, errors.New("search index not found")
+	}
+
+	if res.IsError() {
+		return domain.SearchIndex{}, fmt.Errorf("elasticsearch get mapping failed: %s", res.Status())
+	}
+
+	indices := map[string]searchIndexBody{}
+	err = json.NewDecoder(res.Body).Decode(&indices)
+	if err != nil {
+		return domain.SearchIndex{}, err
+	}
+
+	if len(indices) != 1 {
+		return domain.SearchIndex{}, fmt.Errorf("alias %s points at %d indices", alias, len(indices))
+	}
+
+	index := domain.SearchIndex{}
+	for name, body := range indices {
+		index.Name = name
+		index.Version = body.Mappings.Meta.Version
+		index.Properties = body.Mappings.Properties
+	}
+
+	return index, nil
+}
+
+// Create makes index from its definition, pointing alias at it when given.
+func (repository *SearchIndexRepository) Create(ctx context.Context, index string, alias string, definition domain.SearchIndexDefinition) error {
+	body := map[string]interface{}{}
+	err := json.Unmarshal(definition.Body, &body)
+	if err != nil {
+		return err
+	}
+
+	if alias != "" {
+		body["aliases"] = map[string]interface{}{alias: map[string]interface{}{}}
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	client := repository.ElasticSearch
+	res, err := client.Indices.Create(
+		index,
+		client.Indices.Create.WithContext(ctx),
+		client.Indices.Create.WithBody(bytes.NewReader(bodyJSON)),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch create index failed: %s", res.Status())
+	}
+
+	return nil
+}
+
+// Reindex copies every document of source into dest and waits until done. A
+// copy that timed out, reports failures or created fewer documents than it
+// read is an error, dest is then incomplete.
+func (repository *SearchIndexRepository) Reindex(ctx context.Context, source string, dest string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"source": map[string]interface{}{"index": source},
+		"dest":   map[string]interface{}{"index": dest},
+	})
+	if err != nil {
+		return err
+	}
+
+	client := repository.ElasticSearch
+	res, err := client.Reindex(
+		bytes.NewReader(body),
+		client.Reindex.WithContext(ctx),
+		client.Reindex.WithWaitForCompletion(true),
+		client.Reindex.WithRefresh(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch reindex failed: %s", res.Status())
+	}
+
+	var reindexResponse struct {
+		Timed_out bool              `json:"timed_out"`
+		Total     int64             `json:"total"`
+		Created   int64             `json:"created"`
+		Updated   int64             `json:"updated"`
+		Failures  []json.RawMessage `json:"failures"`
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&reindexResponse)
+	if err != nil {
+		return err
+	}
+
+	if len(reindexResponse.Failures) > 0 {
+		return fmt.Errorf("elasticsearch reindex had %d failures, first: %s", len(reindexResponse.Failures), reindexResponse.Failures[0])
+	}
+
+	if reindexResponse.Timed_out {
+		return errors.New("elasticsearch reindex timed out")
+	}
+
+	if reindexResponse.Created+reindexResponse.Updated < reindexResponse.Total {
+		return fmt.Errorf("elasticsearch reindex copied %d of %d documents", reindexResponse.Created+reindexResponse.Updated, reindexResponse.Total)
+	}
+
+	return nil
+}
+
+// Delete drops index, an index that does not exist is not an error.
+func (repository *SearchIndexRepository) Delete(ctx context.Context, index string) error {
+	client := repository.ElasticSearch
+	res, err := client.Indices.Delete(
+		[]string{index},
+		client.Indices.Delete.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() && res.StatusCode != 404 {
+		return fmt.Errorf("elasticsearch delete index failed: %s", res.Status())
+	}
+
+	return nil
+}
+
+// SwapAlias atomically moves alias from the old index to the new one and drops
+// the old index. A legacy index named like the alias is removed in the same
+// step, which is what frees the name for the alias.
+func (repository *SearchIndexRepository) SwapAlias(ctx context.Context, alias string, oldIndex string, newIndex string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"actions": []interface{}{
+			map[string]interface{}{"add": map[string]interface{}{"index": newIndex, "alias": alias}},
+			map[string]interface{}{"remove_index": map[string]interface{}{"index": oldIndex}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	client := repository.ElasticSearch
+	res, err := client.Indices.UpdateAliases(
+		bytes.NewReader(body),
+		client.Indices.UpdateAliases.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch update aliases failed: %s", res.Status())
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"time"
+)
+
+type SellerDeletionRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewSellerDeletionRepository(zerolog *zerolog.Logger, db *sql.DB) *SellerDeletionRepository {
+	return &SellerDeletionRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+func (repository *SellerDeletionRepository) CreateWithTx(ctx context.Context, tx *sql.Tx, deletion domain.SellerDeletion) {
+	query := "INSERT INTO seller_deletions (saga_id,seller_id,status,created_at,updated_at) VALUES ($1,$2,$3,$4,$5)"
+	_, err := tx.ExecContext(ctx, query, deletion.Saga_id, deletion.Seller_id, deletion.Status, deletion.Created_at, deletion.Updated_at)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *SellerDeletionRepository) FindBySagaIdWithTx(ctx context.Context, tx *sql.Tx, sagaID string) (domain.SellerDeletion, error) {
+	query := "SELECT saga_id,seller_id,status,created_at,updated_at FROM seller_deletions WHERE saga_id=$1 FOR UPDATE"
+	row, err := tx.QueryContext(ctx, query, sagaID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	deletion := domain.SellerDeletion{}
+
+	if row.Next() {
+		err = row.Scan(&deletion.Saga_id, &deletion.Seller_id, &deletion.Status, &deletion.Created_at, &deletion.Updated_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return deletion, nil
+	} else {
+		return deletion, errors.New("seller deletion not found")
+	}
+}
+
+// SnapshotProductsWithTx remembers the status of every product that is about to
+// be archived so a compensating event can put it back.
+func (repository *SellerDeletionRepository) SnapshotProductsWithTx(ctx context.Context, tx *sql.Tx, sagaID string, sellerID string) {
+	query := "INSERT INTO seller_deletion_products (saga_id,product_id,previous_status) SELECT $1,id,status FROM products WHERE seller_id=$2 AND deleted_at IS NULL AND status IS DISTINCT FROM 'Archived'"
+	_, err := tx.ExecContext(ctx, query, sagaID, sellerID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *SellerDeletionRepository) UpdateStatusWithTx(ctx context.Context, tx *sql.Tx, sagaID string, status string, updatedAt *time.Time) {
+	query := "UPDATE seller_deletions SET status = $1, updated_at = $2 WHERE saga_id = $3"
+	_, err := tx.ExecContext(ctx, query, status, updatedAt, sagaID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
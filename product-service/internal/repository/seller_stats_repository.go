@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/product"
+)
+
+type SellerStatsRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewSellerStatsRepository(zerolog *zerolog.Logger, db *sql.DB) *SellerStatsRepository {
+	return &SellerStatsRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+func (repository *SellerStatsRepository) FindProductWithTx(ctx context.Context, tx *sql.Tx, productID int) (domain.SellerStatsProduct, error) {
+	query := "SELECT product_id,seller_id,quantity,price,status,source_lsn FROM seller_stats_products WHERE product_id=$1 FOR UPDATE"
+	row, err := tx.QueryContext(ctx, query, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	statsProduct := domain.SellerStatsProduct{}
+
+	if row.Next() {
+		err = row.Scan(&statsProduct.Product_id, &statsProduct.Seller_id, &statsProduct.Quantity, &statsProduct.Price, &statsProduct.Status, &statsProduct.Source_lsn)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return statsProduct, nil
+	} else {
+		return statsProduct, errors.New("product not found")
+	}
+}
+
+func (repository *SellerStatsRepository) UpsertProductWithTx(ctx context.Context, tx *sql.Tx, statsProduct domain.SellerStatsProduct) {
+	query := "INSERT INTO seller_stats_products (product_id,seller_id,quantity,price,status,source_lsn) VALUES ($1,$2,$3,$4,$5,$6) ON CONFLICT (product_id) DO UPDATE SET seller_id = EXCLUDED.seller_id, quantity = EXCLUDED.quantity, price = EXCLUDED.price, status = EXCLUDED.status, source_lsn = EXCLUDED.source_lsn"
+	_, err := tx.ExecContext(ctx, query, statsProduct.Product_id, statsProduct.Seller_id, statsProduct.Quantity, statsProduct.Price, statsProduct.Status, statsProduct.Source_lsn)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+// DeleteProductWithTx keeps the row as a tombstone carrying the delete LSN so a
+// replayed insert for the same product is still recognised as stale.
+func (repository *SellerStatsRepository) DeleteProductWithTx(ctx context.Context, tx *sql.Tx, productID int, sellerID string, sourceLsn int64) {
+	query := "INSERT INTO seller_stats_products (product_id,seller_id,quantity,price,status,source_lsn) VALUES ($1,$2,0,0,'Deleted',$3) ON CONFLICT (product_id) DO UPDATE SET quantity = 0, price = 0, status = 'Deleted', source_lsn = EXCLUDED.source_lsn"
+	_, err := tx.ExecContext(ctx, query, productID, sellerID, sourceLsn)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *SellerStatsRepository) ApplyDeltaWithTx(ctx context.Context, tx *sql.Tx, delta domain.SellerStats) {
+	query := "INSERT INTO seller_stats (seller_id,active_product,sold_out_product,total_product,inventory_value,last_updated_at) VALUES ($1,$2,$3,$4,$5,$6) ON CONFLICT (seller_id) DO UPDATE SET active_product = seller_stats.active_product + EXCLUDED.active_product, sold_out_product = seller_stats.sold_out_product + EXCLUDED.sold_out_product, total_product = seller_stats.total_product + EXCLUDED.total_product, inventory_value = seller_stats.inventory_value + EXCLUDED.inventory_value, last_updated_at = GREATEST(seller_stats.last_updated_at, EXCLUDED.last_updated_at)"
+	_, err := tx.ExecContext(ctx, query, delta.Seller_id, delta.Active_product, delta.Sold_out_product, delta.Total_product, delta.Inventory_value, delta.Last_updated_at)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *SellerStatsRepository) FindBySellerId(ctx context.Context, sellerID string) (product.SellerStatsResponse, error) {
+	query := "SELECT seller_id,active_product,sold_out_product,total_product,inventory_value,last_updated_at FROM seller_stats WHERE seller_id=$1"
+	row, err := repository.DB.QueryContext(ctx, query, sellerID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	stats := product.SellerStatsResponse{}
+
+	if row.Next() {
+		err = row.Scan(&stats.Seller_id, &stats.Active_product, &stats.Sold_out_product, &stats.Total_product, &stats.Inventory_value, &stats.Last_updated_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return stats, nil
+	} else {
+		return stats, errors.New("seller stats not found")
+	}
+}
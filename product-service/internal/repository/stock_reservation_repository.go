@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"time"
+)
+
+type StockReservationRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewStockReservationRepository(zerolog *zerolog.Logger, db *sql.DB) *StockReservationRepository {
+	return &StockReservationRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+// LockIdWithTx serializes transactions working on the same reservation id
+// until they end, including one that is about to create it.
+func (repository *StockReservationRepository) LockIdWithTx(ctx context.Context, tx *sql.Tx, reservationID string) {
+	query := "SELECT pg_advisory_xact_lock(hashtext($1))"
+	_, err := tx.ExecContext(ctx, query, reservationID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *StockReservationRepository) CreateWithTx(ctx context.Context, tx *sql.Tx, reservation domain.StockReservation) {
+	query := "INSERT INTO stock_reservations (id,product_id,variant_id,buyer_id,quantity,status,expires_at,created_at,updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)"
+	_, err := tx.ExecContext(ctx, query, reservation.Id, reservation.Product_id, reservation.Variant_id, reservation.Buyer_id, reservation.Quantity, reservation.Status, reservation.Expires_at, reservation.Created_at, reservation.Updated_at)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *StockReservationRepository) UpdateStatusWithTx(ctx context.Context, tx *sql.Tx, reservationID string, status string, updatedAt *time.Time) {
+	query := "UPDATE stock_reservations SET status = $1, updated_at = $2 WHERE id = $3"
+	_, err := tx.ExecContext(ctx, query, status, updatedAt, reservationID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+// FindByIdWithTx locks the reservation until the transaction ends.
+func (repository *StockReservationRepository) FindByIdWithTx(ctx context.Context, tx *sql.Tx, reservationID string) (domain.StockReservation, error) {
+	query := "SELECT id,product_id,variant_id,buyer_id,quantity,status,expires_at,created_at,updated_at FROM stock_reservations WHERE id=$1 FOR UPDATE"
+	row, err := tx.QueryContext(ctx, query, reservationID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	if row.Next() {
+		return repository.scan(row), nil
+	} else {
+		return domain.StockReservation{}, errors.New("reservation not found")
+	}
+}
+
+func (repository *StockReservationRepository) FindById(ctx context.Context, reservationID string) (domain.StockReservation, error) {
+	query := "SELECT id,product_id,variant_id,buyer_id,quantity,status,expires_at,created_at,updated_at FROM stock_reservations WHERE id=$1"
+	row, err := repository.DB.QueryContext(ctx, query, reservationID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	if row.Next() {
+		return repository.scan(row), nil
+	} else {
+		return domain.StockReservation{}, errors.New("reservation not found")
+	}
+}
+
+// FindExpiredWithTx locks up to limit reservations whose hold ran out.
+// Reservations locked by another transaction are skipped, so several
+// instances can sweep at once.
+func (repository *StockReservationRepository) FindExpiredWithTx(ctx context.Context, tx *sql.Tx, now *time.Time, limit int) []domain.StockReservation {
+	query := "SELECT id,product_id,variant_id,buyer_id,quantity,status,expires_at,created_at,updated_at FROM stock_reservations WHERE status = 'Reserved' AND expires_at <= $1 ORDER BY expires_at LIMIT $2 FOR UPDATE SKIP LOCKED"
+	row, err := tx.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	reservations := []domain.StockReservation{}
+
+	for row.Next() {
+		reservations = append(reservations, repository.scan(row))
+	}
+
+	return reservations
+}
+
+// TakeStockWithTx takes quantity units out of a product, or out of one of its
+// variants when variantID is set. The update only applies while enough units
+// are left, so two buyers can never take the same last unit. Taking the last
+// unit of a product marks it SoldOut in the same statement, the returned change
+// tells whether it did. A variant's product follows in SyncProductWithTx.
+func (repository *StockReservationRepository) TakeStockWithTx(ctx context.Context, tx *sql.Tx, productID int, variantID *int, quantity int, updatedAt *time.Time) (domain.ProductStatusChange, bool) {
+	if variantID != nil {
+		query := "UPDATE product_variants SET quantity = quantity - $1, updated_at = $2 WHERE product_id = $3 AND id = $4 AND quantity >= $1"
+		result, err := tx.ExecContext(ctx, query, quantity, updatedAt, productID, *variantID)
+		if err != nil {
+			respErr := errors.New("failed to query into database")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		taken, err := result.RowsAffected()
+		if err != nil {
+			respErr := errors.New("failed to get affected rows")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return domain.ProductStatusChange{}, taken == 1
+	}
+
+	query := "UPDATE products SET quantity = products.quantity - $1, status = " + stockStatus("products.quantity - $1") + ", updated_at = $2 " + fmt.Sprintf(stockStatusFrom, 3) + " AND products.deleted_at IS NULL AND products.quantity >= $1" + stockStatusReturning
+	row, err := tx.QueryContext(ctx, query, quantity, updatedAt, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return scanStockStatusChange(repository.Log, row, productID, updatedAt)
+}
+
+// ReturnStockWithTx puts the units of a reservation back. A SoldOut product
+// is Ready again in the same statement, the returned change tells whether it
+// was.
+func (repository *StockReservationRepository) ReturnStockWithTx(ctx context.Context, tx *sql.Tx, reservation domain.StockReservation, updatedAt *time.Time) domain.ProductStatusChange {
+	if reservation.Variant_id != nil {
+		query := "UPDATE product_variants SET quantity = quantity + $1, updated_at = $2 WHERE product_id = $3 AND id = $4"
+		_, err := tx.ExecContext(ctx, query, reservation.Quantity, updatedAt, reservation.Product_id, *reservation.Variant_id)
+		if err != nil {
+			respErr := errors.New("failed to query into database")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return domain.ProductStatusChange{}
+	}
+
+	query := "UPDATE products SET quantity = products.quantity + $1, status = " + stockStatus("products.quantity + $1") + ", updated_at = $2 " + fmt.Sprintf(stockStatusFrom, 3) + stockStatusReturning
+	row, err := tx.QueryContext(ctx, query, reservation.Quantity, updatedAt, reservation.Product_id)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	change, _ := scanStockStatusChange(repository.Log, row, reservation.Product_id, updatedAt)
+
+	return change
+}
+
+func (repository *StockReservationRepository) scan(row *sql.Rows) domain.StockReservation {
+	reservation := domain.StockReservation{}
+
+	err := row.Scan(&reservation.Id, &reservation.Product_id, &reservation.Variant_id, &reservation.Buyer_id, &reservation.Quantity, &reservation.Status, &reservation.Expires_at, &reservation.Created_at, &reservation.Updated_at)
+	if err != nil {
+		respErr := errors.New("failed to scan query result")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return reservation
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+)
+
+type StockThresholdRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewStockThresholdRepository(zerolog *zerolog.Logger, db *sql.DB) *StockThresholdRepository {
+	return &StockThresholdRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+func (repository *StockThresholdRepository) Upsert(ctx context.Context, threshold domain.StockThreshold) {
+	query := "INSERT INTO seller_stock_thresholds (seller_id,low_stock_threshold,updated_at) VALUES ($1,$2,$3) ON CONFLICT (seller_id) DO UPDATE SET low_stock_threshold = EXCLUDED.low_stock_threshold, updated_at = EXCLUDED.updated_at"
+	_, err := repository.DB.ExecContext(ctx, query, threshold.Seller_id, threshold.Low_stock_threshold, threshold.Updated_at)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *StockThresholdRepository) FindBySellerId(ctx context.Context, sellerID string) (domain.StockThreshold, error) {
+	query := "SELECT seller_id,low_stock_threshold,updated_at FROM seller_stock_thresholds WHERE seller_id=$1"
+	row, err := repository.DB.QueryContext(ctx, query, sellerID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	threshold := domain.StockThreshold{}
+
+	if row.Next() {
+		err = row.Scan(&threshold.Seller_id, &threshold.Low_stock_threshold, &threshold.Updated_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return threshold, nil
+	} else {
+		return threshold, errors.New("stock threshold not found")
+	}
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+)
+
+type TagRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewTagRepository(zerolog *zerolog.Logger, db *sql.DB) *TagRepository {
+	return &TagRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+// ReplaceProductTagsWithTx sets the tags of a product to names, creating tags
+// that do not exist yet.
+func (repository *TagRepository) ReplaceProductTagsWithTx(ctx context.Context, tx *sql.Tx, productID int, names []string) {
+	query := "INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING"
+	_, err := tx.ExecContext(ctx, query, names)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	query = "DELETE FROM product_tags WHERE product_id = $1"
+	_, err = tx.ExecContext(ctx, query, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	query = "INSERT INTO product_tags (product_id,tag_id) SELECT $1, id FROM tags WHERE name = ANY($2::text[])"
+	_, err = tx.ExecContext(ctx, query, productID, names)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *TagRepository) FindByProductId(ctx context.Context, productID int) []string {
+	query := "SELECT tags.name FROM product_tags JOIN tags ON tags.id = product_tags.tag_id WHERE product_tags.product_id = $1 ORDER BY tags.name"
+	row, err := repository.DB.QueryContext(ctx, query, productID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	tags := []string{}
+
+	for row.Next() {
+		var tag string
+		err = row.Scan(&tag)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
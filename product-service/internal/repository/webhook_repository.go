@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"strings"
+	"time"
+)
+
+type WebhookRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewWebhookRepository(zerolog *zerolog.Logger, db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+func (repository *WebhookRepository) CreateEndpointWithTx(ctx context.Context, tx *sql.Tx, endpoint domain.WebhookEndpoint) {
+	query := "INSERT INTO webhook_endpoints (id,user_id,url,secret,active,created_at,updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7)"
+	_, err := tx.ExecContext(ctx, query, endpoint.Id, endpoint.User_id, endpoint.Url, endpoint.Secret, endpoint.Active, endpoint.Created_at, endpoint.Updated_at)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	query = "INSERT INTO webhook_endpoint_events (endpoint_id,event_type) VALUES ($1,$2) ON CONFLICT DO NOTHING"
+	for _, eventType := range endpoint.Event_types {
+		_, err = tx.ExecContext(ctx, query, endpoint.Id, eventType)
+		if err != nil {
+			respErr := errors.New("failed to query into database")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+	}
+}
+
+func (repository *WebhookRepository) FindEndpointsByUserId(ctx context.Context, userUUID string) []domain.WebhookEndpoint {
+	query := "SELECT e.id,e.user_id,e.url,e.active,COALESCE(string_agg(ev.event_type, ','),''),e.created_at,e.updated_at FROM webhook_endpoints e LEFT JOIN webhook_endpoint_events ev ON ev.endpoint_id = e.id WHERE e.user_id=$1 GROUP BY e.id ORDER BY e.created_at"
+	row, err := repository.DB.QueryContext(ctx, query, userUUID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	endpoints := []domain.WebhookEndpoint{}
+
+	for row.Next() {
+		endpoint := domain.WebhookEndpoint{}
+		var eventTypes string
+		err = row.Scan(&endpoint.Id, &endpoint.User_id, &endpoint.Url, &endpoint.Active, &eventTypes, &endpoint.Created_at, &endpoint.Updated_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		endpoint.Event_types = splitEventTypes(eventTypes)
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints
+}
+
+func (repository *WebhookRepository) FindEndpoint(ctx context.Context, userUUID string, endpointID string) (domain.WebhookEndpoint, error) {
+	query := "SELECT e.id,e.user_id,e.url,e.active,COALESCE(string_agg(ev.event_type, ','),''),e.created_at,e.updated_at FROM webhook_endpoints e LEFT JOIN webhook_endpoint_events ev ON ev.endpoint_id = e.id WHERE e.id=$1 AND e.user_id=$2 GROUP BY e.id"
+	row, err := repository.DB.QueryContext(ctx, query, endpointID, userUUID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	endpoint := domain.WebhookEndpoint{}
+
+	if row.Next() {
+		var eventTypes string
+		err = row.Scan(&endpoint.Id, &endpoint.User_id, &endpoint.Url, &endpoint.Active, &eventTypes, &endpoint.Created_at, &endpoint.Updated_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		endpoint.Event_types = splitEventTypes(eventTypes)
+
+		return endpoint, nil
+	} else {
+		return endpoint, errors.New("webhook not found")
+	}
+}
+
+func (repository *WebhookRepository) SetEndpointActive(ctx context.Context, endpointID string, active bool, updatedAt *time.Time) {
+	query := "UPDATE webhook_endpoints SET active = $1, updated_at = $2 WHERE id = $3"
+	_, err := repository.DB.ExecContext(ctx, query, active, updatedAt, endpointID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *WebhookRepository) DeleteEndpoint(ctx context.Context, endpointID string) {
+	query := "DELETE FROM webhook_endpoints WHERE id=$1"
+	_, err := repository.DB.ExecContext(ctx, query, endpointID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+// FindSubscribedEndpointIds returns the active endpoints of ownerID subscribed
+// to eventType.
+func (repository *WebhookRepository) FindSubscribedEndpointIds(ctx context.Context, eventType string, ownerID string) []string {
+	query := "SELECT e.id FROM webhook_endpoints e JOIN webhook_endpoint_events ev ON ev.endpoint_id = e.id WHERE e.active AND ev.event_type = $1 AND e.user_id = $2"
+	row, err := repository.DB.QueryContext(ctx, query, eventType, ownerID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	endpointIDs := []string{}
+
+	for row.Next() {
+		var endpointID string
+		err = row.Scan(&endpointID)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		endpointIDs = append(endpointIDs, endpointID)
+	}
+
+	return endpointIDs
+}
+
+func (repository *WebhookRepository) CreateDelivery(ctx context.Context, delivery domain.WebhookDelivery) {
+	query := "INSERT INTO webhook_deliveries (id,endpoint_id,event_type,payload,status,attempts,next_attempt_at,created_at,updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)"
+	_, err := repository.DB.ExecContext(ctx, query, delivery.Id, delivery.Endpoint_id, delivery.Event_type, delivery.Payload, delivery.Status, delivery.Attempts, delivery.Next_attempt_at, delivery.Created_at, delivery.Updated_at)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+// ClaimDueDeliveries leases due deliveries by pushing next_attempt_at forward,
+// so several instances can dispatch without sending the same attempt twice.
+// Deliveries of disabled endpoints stay pending until the endpoint is enabled.
+func (repository *WebhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]domain.WebhookDelivery, []domain.WebhookEndpoint) {
+	query := "UPDATE webhook_deliveries d SET next_attempt_at = $1 FROM webhook_endpoints e WHERE d.endpoint_id = e.id AND d.id IN (SELECT d2.id FROM webhook_deliveries d2 JOIN webhook_endpoints e2 ON e2.id = d2.endpoint_id WHERE d2.status = 'Pending' AND d2.next_attempt_at <= $2 AND e2.active ORDER BY d2.next_attempt_at LIMIT $3 FOR UPDATE OF d2 SKIP LOCKED) RETURNING d.id,d.endpoint_id,d.event_type,d.payload,d.attempts,e.url,e.secret"
+	row, err := repository.DB.QueryContext(ctx, query, leaseUntil, now, limit)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	endpoints := []domain.WebhookEndpoint{}
+
+	for row.Next() {
+		delivery := domain.WebhookDelivery{}
+		endpoint := domain.WebhookEndpoint{}
+		err = row.Scan(&delivery.Id, &delivery.Endpoint_id, &delivery.Event_type, &delivery.Payload, &delivery.Attempts, &endpoint.Url, &endpoint.Secret)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		endpoint.Id = delivery.Endpoint_id
+		deliveries = append(deliveries, delivery)
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return deliveries, endpoints
+}
+
+func (repository *WebhookRepository) RecordAttemptWithTx(ctx context.Context, tx *sql.Tx, attempt domain.WebhookDeliveryAttempt) {
+	query := "INSERT INTO webhook_delivery_attempts (delivery_id,attempt,status_code,error,duration_ms,created_at) VALUES ($1,$2,$3,$4,$5,$6)"
+	_, err := tx.ExecContext(ctx, query, attempt.Delivery_id, attempt.Attempt, attempt.Status_code, attempt.Error, attempt.Duration_ms, attempt.Created_at)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *WebhookRepository) UpdateDeliveryWithTx(ctx context.Context, tx *sql.Tx, delivery domain.WebhookDelivery) {
+	query := "UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3, updated_at = $4 WHERE id = $5"
+	_, err := tx.ExecContext(ctx, query, delivery.Status, delivery.Attempts, delivery.Next_attempt_at, delivery.Updated_at, delivery.Id)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *WebhookRepository) FindDeliveriesByEndpointId(ctx context.Context, endpointID string, limit int) []domain.WebhookDelivery {
+	query := "SELECT id,endpoint_id,event_type,payload,status,attempts,next_attempt_at,created_at,updated_at FROM webhook_deliveries WHERE endpoint_id=$1 ORDER BY created_at DESC LIMIT $2"
+	row, err := repository.DB.QueryContext(ctx, query, endpointID, limit)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+
+	for row.Next() {
+		delivery := domain.WebhookDelivery{}
+		err = row.Scan(&delivery.Id, &delivery.Endpoint_id, &delivery.Event_type, &delivery.Payload, &delivery.Status, &delivery.Attempts, &delivery.Next_attempt_at, &delivery.Created_at, &delivery.Updated_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries
+}
+
+func (repository *WebhookRepository) FindDelivery(ctx context.Context, endpointID string, deliveryID string) (domain.WebhookDelivery, error) {
+	query := "SELECT id,endpoint_id,event_type,payload,status,attempts,next_attempt_at,created_at,updated_at FROM webhook_deliveries WHERE id=$1 AND endpoint_id=$2"
+	row, err := repository.DB.QueryContext(ctx, query, deliveryID, endpointID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	delivery := domain.WebhookDelivery{}
+
+	if row.Next() {
+		err = row.Scan(&delivery.Id, &delivery.Endpoint_id, &delivery.Event_type, &delivery.Payload, &delivery.Status, &delivery.Attempts, &delivery.Next_attempt_at, &delivery.Created_at, &delivery.Updated_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return delivery, nil
+	} else {
+		return delivery, errors.New("delivery not found")
+	}
+}
+
+func (repository *WebhookRepository) FindAttemptsByDeliveryId(ctx context.Context, deliveryID string) []domain.WebhookDeliveryAttempt {
+	query := "SELECT id,delivery_id,attempt,status_code,error,duration_ms,created_at FROM webhook_delivery_attempts WHERE delivery_id=$1 ORDER BY attempt"
+	row, err := repository.DB.QueryContext(ctx, query, deliveryID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	attempts := []domain.WebhookDeliveryAttempt{}
+
+	for row.Next() {
+		attempt := domain.WebhookDeliveryAttempt{}
+		err = row.Scan(&attempt.Id, &attempt.Delivery_id, &attempt.Attempt, &attempt.Status_code, &attempt.Error, &attempt.Duration_ms, &attempt.Created_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts
+}
+
+func splitEventTypes(eventTypes string) []string {
+	if eventTypes == "" {
+		return []string{}
+	}
+
+	return strings.Split(eventTypes, ",")
+}
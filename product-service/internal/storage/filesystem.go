@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FilesystemStorage writes files below Root, for development and tests. The
+// service serves Root itself under PublicUrl.
+type FilesystemStorage struct {
+	Root      string
+	PublicUrl string
+}
+
+func NewFilesystemStorage(root string, publicUrl string) *FilesystemStorage {
+	return &FilesystemStorage{
+		Root:      root,
+		PublicUrl: strings.TrimRight(publicUrl, "/"),
+	}
+}
+
+// Put writes to a temporary file first so a half written file is never
+// served.
+func (storage *FilesystemStorage) Put(_ context.Context, key string, _ string, data []byte) error {
+	path, err := storage.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(temp.Name())
+
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(temp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
+
+func (storage *FilesystemStorage) Delete(_ context.Context, key string) error {
+	path, err := storage.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *FilesystemStorage) URL(key string) string {
+	return storage.PublicUrl + "/" + key
+}
+
+func (storage *FilesystemStorage) path(key string) (string, error) {
+	path := filepath.Join(storage.Root, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, filepath.Clean(storage.Root)+string(filepath.Separator)) {
+		return "", errors.New("storage key escapes the storage root")
+	}
+
+	return path, nil
+}
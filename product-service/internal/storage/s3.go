@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Storage stores files in a bucket of any S3 compatible service (AWS S3,
+// MinIO, R2, ...). Requests are signed with Signature Version 4. PathStyle
+// addresses the bucket as endpoint/bucket/key, which most self hosted services
+// need, instead of bucket.endpoint/key.
+type S3Storage struct {
+	Endpoint   string
+	Region     string
+	Bucket     string
+	AccessKey  string
+	SecretKey  string
+	PublicUrl  string
+	PathStyle  bool
+	HttpClient *http.Client
+}
+
+func NewS3Storage(endpoint string, region string, bucket string, accessKey string, secretKey string, publicUrl string, pathStyle bool) *S3Storage {
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &S3Storage{
+		Endpoint:  strings.TrimRight(endpoint, "/"),
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		PublicUrl: strings.TrimRight(publicUrl, "/"),
+		PathStyle: pathStyle,
+		HttpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+func (storage *S3Storage) Put(ctx context.Context, key string, contentType string, data []byte) error {
+	return storage.do(ctx, http.MethodPut, key, contentType, data)
+}
+
+func (storage *S3Storage) Delete(ctx context.Context, key string) error {
+	return storage.do(ctx, http.MethodDelete, key, "", nil)
+}
+
+// URL is PublicUrl/key when a public URL (such as a CDN) is configured, the
+// object URL otherwise.
+func (storage *S3Storage) URL(key string) string {
+	if storage.PublicUrl != "" {
+		return storage.PublicUrl + "/" + key
+	}
+
+	return storage.objectUrl(key).String()
+}
+
+func (storage *S3Storage) objectUrl(key string) *url.URL {
+	endpoint, _ := url.Parse(storage.Endpoint)
+
+	objectUrl := *endpoint
+	if storage.PathStyle {
+		objectUrl.Path = "/" + storage.Bucket + "/" + key
+	} else {
+		objectUrl.Host = storage.Bucket + "." + endpoint.Host
+		objectUrl.Path = "/" + key
+	}
+
+	return &objectUrl
+}
+
+func (storage *S3Storage) do(ctx context.Context, method string, key string, contentType string, data []byte) error {
+	request, err := http.NewRequestWithContext(ctx, method, storage.objectUrl(key).String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	storage.sign(request, data, time.Now().UTC())
+
+	response, err := storage.HttpClient.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	// deleting a missing key answers 204 as well
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("s3 %s %s failed: %s", method, key, response.Status)
+	}
+
+	return nil
+}
+
+// sign adds a Signature Version 4 Authorization header, see
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (storage *S3Storage) sign(request *http.Request, payload []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	request.Header.Set("Host", request.URL.Host)
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headerNames := []string{}
+	for name := range request.Header {
+		headerNames = append(headerNames, strings.ToLower(name))
+	}
+
+	sort.Strings(headerNames)
+
+	canonicalHeaders := ""
+	for _, name := range headerNames {
+		canonicalHeaders += name + ":" + strings.TrimSpace(request.Header.Get(name)) + "\n"
+	}
+
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + storage.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSha256([]byte("AWS4"+storage.SecretKey), date)
+	key = hmacSha256(key, storage.Region)
+	key = hmacSha256(key, "s3")
+	key = hmacSha256(key, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", storage.AccessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,11 @@
+package storage
+
+import "context"
+
+// Storage keeps uploaded files under a key such as
+// "products/12/5b1f....jpg" and knows the public URL they are served from.
+type Storage interface {
+	Put(ctx context.Context, key string, contentType string, data []byte) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
@@ -0,0 +1,226 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator"
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/cache"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/category"
+	"gocdc/internal/repository"
+	"strings"
+	"time"
+)
+
+const categoryTreeCacheKey = "category:tree"
+
+type CategoryUsecase struct {
+	CategoryRepository *repository.CategoryRepository
+	DB                 *sql.DB
+	Cache              cache.Cache
+	Validator          *validator.Validate
+	Log                *zerolog.Logger
+	Koanf              *koanf.Koanf
+}
+
+func NewCategoryUsecase(categoryRepository *repository.CategoryRepository, db *sql.DB, productCache cache.Cache, validator *validator.Validate, zerolog *zerolog.Logger, koanf *koanf.Koanf) *CategoryUsecase {
+	return &CategoryUsecase{
+		CategoryRepository: categoryRepository,
+		DB:                 db,
+		Cache:              productCache,
+		Validator:          validator,
+		Log:                zerolog,
+		Koanf:              koanf,
+	}
+}
+
+// FindTree returns the root categories with their descendants nested.
+func (usecase *CategoryUsecase) FindTree(ctx context.Context) []category.CategoryResponse {
+	tree := []category.CategoryResponse{}
+
+	value, ok := usecase.Cache.Get(ctx, categoryTreeCacheKey)
+	if ok && json.Unmarshal(value, &tree) == nil {
+		return tree
+	}
+
+	children := map[int][]domain.Category{}
+	roots := []domain.Category{}
+
+	for _, node := range usecase.CategoryRepository.FindAll(ctx) {
+		if node.Parent_id == nil {
+			roots = append(roots, node)
+		} else {
+			children[*node.Parent_id] = append(children[*node.Parent_id], node)
+		}
+	}
+
+	var build func(node domain.Category) category.CategoryResponse
+	build = func(node domain.Category) category.CategoryResponse {
+		categoryResponse := toCategoryResponse(node)
+		for _, child := range children[node.Id] {
+			categoryResponse.Children = append(categoryResponse.Children, build(child))
+		}
+
+		return categoryResponse
+	}
+
+	for _, root := range roots {
+		tree = append(tree, build(root))
+	}
+
+	ttl := usecase.Koanf.Duration("CACHE_TTL")
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	valueJSON, err := json.Marshal(tree)
+	if err == nil {
+		usecase.Cache.Set(ctx, categoryTreeCacheKey, valueJSON, ttl)
+	}
+
+	return tree
+}
+
+func (usecase *CategoryUsecase) FindById(ctx context.Context, categoryID int) (category.CategoryResponse, error) {
+	node, err := usecase.CategoryRepository.FindById(ctx, categoryID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return category.CategoryResponse{}, err
+	}
+
+	categoryResponse := toCategoryResponse(node)
+
+	path := usecase.CategoryRepository.FindPath(ctx, categoryID)
+	for _, ancestor := range path[:len(path)-1] {
+		categoryResponse.Path = append(categoryResponse.Path, toCategoryResponse(ancestor))
+	}
+
+	for _, child := range usecase.CategoryRepository.FindChildren(ctx, categoryID) {
+		categoryResponse.Children = append(categoryResponse.Children, toCategoryResponse(child))
+	}
+
+	return categoryResponse, nil
+}
+
+func (usecase *CategoryUsecase) Create(ctx context.Context, request category.CategoryCreateRequest) (category.CategoryResponse, error) {
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return category.CategoryResponse{}, respErr
+	}
+
+	now := time.Now()
+	node := domain.Category{
+		Name:       strings.Join(strings.Fields(request.Name), " "),
+		Created_at: &now,
+		Updated_at: &now,
+	}
+
+	if request.Parent_id > 0 {
+		_, err = usecase.CategoryRepository.FindById(ctx, request.Parent_id)
+		if err != nil {
+			respErr := errors.New("parent category not found")
+			usecase.Log.Warn().Msg(respErr.Error())
+			return category.CategoryResponse{}, respErr
+		}
+
+		node.Parent_id = &request.Parent_id
+	}
+
+	if usecase.CategoryRepository.ExistsByName(ctx, node.Parent_id, node.Name, 0) {
+		respErr := errors.New("category already exists")
+		usecase.Log.Warn().Msg(respErr.Error())
+		return category.CategoryResponse{}, respErr
+	}
+
+	node.Id = usecase.CategoryRepository.Create(ctx, node)
+
+	usecase.Cache.Delete(ctx, categoryTreeCacheKey)
+
+	return toCategoryResponse(node), nil
+}
+
+// Update renames or moves a category. Every product under it is touched so the
+// search index picks up the new category path.
+func (usecase *CategoryUsecase) Update(ctx context.Context, categoryID int, request category.CategoryUpdateRequest) (category.CategoryResponse, error) {
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return category.CategoryResponse{}, respErr
+	}
+
+	node, err := usecase.CategoryRepository.FindById(ctx, categoryID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return category.CategoryResponse{}, err
+	}
+
+	if request.Name != "" {
+		node.Name = strings.Join(strings.Fields(request.Name), " ")
+	}
+
+	switch {
+	case request.Parent_id == -1:
+		node.Parent_id = nil
+	case request.Parent_id > 0:
+		_, err = usecase.CategoryRepository.FindById(ctx, request.Parent_id)
+		if err != nil {
+			respErr := errors.New("parent category not found")
+			usecase.Log.Warn().Msg(respErr.Error())
+			return category.CategoryResponse{}, respErr
+		}
+
+		if usecase.CategoryRepository.IsInSubtree(ctx, categoryID, request.Parent_id) {
+			respErr := errors.New("category cannot move under itself")
+			usecase.Log.Warn().Msg(respErr.Error())
+			return category.CategoryResponse{}, respErr
+		}
+
+		node.Parent_id = &request.Parent_id
+	}
+
+	if usecase.CategoryRepository.ExistsByName(ctx, node.Parent_id, node.Name, node.Id) {
+		respErr := errors.New("category already exists")
+		usecase.Log.Warn().Msg(respErr.Error())
+		return category.CategoryResponse{}, respErr
+	}
+
+	// deferred first so it runs after the commit
+	defer usecase.Cache.Delete(ctx, categoryTreeCacheKey)
+
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	now := time.Now()
+	node.Updated_at = &now
+
+	usecase.CategoryRepository.UpdateWithTx(ctx, tx, node)
+	touched := usecase.CategoryRepository.TouchProductsWithTx(ctx, tx, categoryID, &now)
+
+	usecase.Log.Info().Int("category_id", categoryID).Int64("products", touched).Msg("Updated category")
+
+	return toCategoryResponse(node), nil
+}
+
+func toCategoryResponse(node domain.Category) category.CategoryResponse {
+	return category.CategoryResponse{
+		Id:         node.Id,
+		Parent_id:  node.Parent_id,
+		Name:       node.Name,
+		Children:   []category.CategoryResponse{},
+		Created_at: node.Created_at,
+		Updated_at: node.Updated_at,
+	}
+}
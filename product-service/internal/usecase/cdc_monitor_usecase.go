@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/web"
+	"gocdc/internal/repository"
+	"sync"
+	"time"
+)
+
+var (
+	cdcMetrics                = expvar.NewMap("cdc")
+	cdcSlotActive             = new(expvar.Int)
+	cdcRetainedWalBytes       = new(expvar.Int)
+	cdcConfirmedFlushLagBytes = new(expvar.Int)
+	cdcReplayLagSeconds       = new(expvar.Float)
+	cdcEndToEndLagMs          = new(expvar.Int)
+	cdcHealthy                = new(expvar.Int)
+)
+
+func init() {
+	cdcMetrics.Set("slot_active", cdcSlotActive)
+	cdcMetrics.Set("retained_wal_bytes", cdcRetainedWalBytes)
+	cdcMetrics.Set("confirmed_flush_lag_bytes", cdcConfirmedFlushLagBytes)
+	cdcMetrics.Set("replay_lag_seconds", cdcReplayLagSeconds)
+	cdcMetrics.Set("end_to_end_lag_ms", cdcEndToEndLagMs)
+	cdcMetrics.Set("healthy", cdcHealthy)
+}
+
+// CDCMonitorUsecase watches the Debezium replication slot. An abandoned slot
+// keeps every WAL segment since its restart_lsn, so retained bytes grow until
+// the Postgres disk fills. It also notices a stream that went quiet, products
+// changing while no change event comes through.
+type CDCMonitorUsecase struct {
+	ReplicationRepository *repository.ReplicationRepository
+	Log                   *zerolog.Logger
+	Koanf                 *koanf.Koanf
+	mutex                 sync.RWMutex
+	health                web.CDCHealthResponse
+	productChanges        int64
+	unstreamedSince       *time.Time
+	observedLagMs         int64
+}
+
+func NewCDCMonitorUsecase(replicationRepository *repository.ReplicationRepository, zerolog *zerolog.Logger, koanf *koanf.Koanf) *CDCMonitorUsecase {
+	return &CDCMonitorUsecase{
+		ReplicationRepository: replicationRepository,
+		Log:                   zerolog,
+		Koanf:                 koanf,
+		productChanges:        -1,
+		health: web.CDCHealthResponse{
+			Status:   "Unknown",
+			Problems: []string{"replication slot not checked yet"},
+		},
+	}
+}
+
+// ObserveEvent records the end-to-end lag of a change event, from the commit
+// in Postgres (source.ts_ms) until it is written to the product index.
+func (usecase *CDCMonitorUsecase) ObserveEvent(sourceTsMs int64) {
+	if sourceTsMs <= 0 {
+		return
+	}
+
+	now := time.Now()
+	lag := now.UnixMilli() - sourceTsMs
+
+	usecase.mutex.Lock()
+	usecase.health.Last_event_at = &now
+	usecase.health.End_to_end_lag_ms = lag
+	usecase.observedLagMs = lag
+	usecase.mutex.Unlock()
+
+	cdcEndToEndLagMs.Set(lag)
+}
+
+func (usecase *CDCMonitorUsecase) Check(ctx context.Context) web.CDCHealthResponse {
+	slotName := usecase.Koanf.String("CDC_SLOT_NAME")
+	if slotName == "" {
+		slotName = "debezium_slot"
+	}
+
+	maxRetainedWalBytes := usecase.Koanf.Int64("CDC_MAX_RETAINED_WAL_BYTES")
+	if maxRetainedWalBytes <= 0 {
+		maxRetainedWalBytes = 1 << 30
+	}
+
+	maxFlushLagBytes := usecase.Koanf.Int64("CDC_MAX_FLUSH_LAG_BYTES")
+	if maxFlushLagBytes <= 0 {
+		maxFlushLagBytes = 256 << 20
+	}
+
+	maxEndToEndLag := usecase.Koanf.Duration("CDC_MAX_END_TO_END_LAG")
+	if maxEndToEndLag <= 0 {
+		maxEndToEndLag = time.Minute
+	}
+
+	maxEventSilence := usecase.Koanf.Duration("CDC_MAX_EVENT_SILENCE")
+	if maxEventSilence <= 0 {
+		maxEventSilence = 5 * time.Minute
+	}
+
+	slot, err := usecase.ReplicationRepository.FindSlot(ctx, slotName)
+	productChanges := usecase.ReplicationRepository.CountProductChanges(ctx)
+
+	now := time.Now()
+	problems := []string{}
+
+	// ObserveEvent writes the same status, it is read and written back whole
+	usecase.mutex.Lock()
+	defer usecase.mutex.Unlock()
+
+	health := usecase.health
+	health.Slot_name = slotName
+	health.Checked_at = &now
+
+	if err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		health.Slot_active = slot.Active
+		health.Sender_state = slot.Sender_state
+		health.Retained_wal_bytes = slot.Retained_wal_bytes
+		health.Confirmed_flush_lag_bytes = slot.Confirmed_flush_lag_bytes
+		health.Replay_lag_seconds = slot.Replay_lag_seconds
+
+		if !slot.Active {
+			problems = append(problems, "replication slot has no active consumer")
+		}
+		if slot.Retained_wal_bytes > maxRetainedWalBytes {
+			problems = append(problems, fmt.Sprintf("retained wal %d bytes exceeds %d", slot.Retained_wal_bytes, maxRetainedWalBytes))
+		}
+		if slot.Confirmed_flush_lag_bytes > maxFlushLagBytes {
+			problems = append(problems, fmt.Sprintf("confirmed flush lag %d bytes exceeds %d", slot.Confirmed_flush_lag_bytes, maxFlushLagBytes))
+		}
+	}
+
+	// products changed since the last check, they are unstreamed until an
+	// event arrives after that
+	if usecase.unstreamedSince != nil && health.Last_event_at != nil && health.Last_event_at.After(*usecase.unstreamedSince) {
+		usecase.unstreamedSince = nil
+	}
+	if usecase.productChanges >= 0 && productChanges > usecase.productChanges && usecase.unstreamedSince == nil {
+		usecase.unstreamedSince = &now
+	}
+	usecase.productChanges = productChanges
+
+	// the lag of the last event only holds while events keep coming. Changes
+	// without an event since age it, a stream that went idle after catching
+	// up has none left.
+	lag := usecase.observedLagMs
+	if usecase.unstreamedSince != nil {
+		if unstreamedLag := now.Sub(*usecase.unstreamedSince).Milliseconds(); unstreamedLag > lag {
+			lag = unstreamedLag
+		}
+	} else if health.Last_event_at == nil || now.Sub(*health.Last_event_at) > usecase.interval() {
+		lag = 0
+	}
+	health.End_to_end_lag_ms = lag
+	cdcEndToEndLagMs.Set(lag)
+
+	if health.End_to_end_lag_ms > maxEndToEndLag.Milliseconds() {
+		problems = append(problems, fmt.Sprintf("end to end lag %dms exceeds %dms", health.End_to_end_lag_ms, maxEndToEndLag.Milliseconds()))
+	}
+
+	if usecase.unstreamedSince != nil && now.Sub(*usecase.unstreamedSince) > maxEventSilence {
+		problems = append(problems, fmt.Sprintf("products changed %s ago but no change event arrived since", now.Sub(*usecase.unstreamedSince).Round(time.Second)))
+	}
+
+	health.Problems = problems
+	health.Status = "Healthy"
+	if len(problems) > 0 {
+		health.Status = "Unhealthy"
+	}
+
+	usecase.health = health
+
+	cdcRetainedWalBytes.Set(health.Retained_wal_bytes)
+	cdcConfirmedFlushLagBytes.Set(health.Confirmed_flush_lag_bytes)
+	cdcReplayLagSeconds.Set(health.Replay_lag_seconds)
+	if health.Slot_active {
+		cdcSlotActive.Set(1)
+	} else {
+		cdcSlotActive.Set(0)
+	}
+	if len(problems) == 0 {
+		cdcHealthy.Set(1)
+	} else {
+		cdcHealthy.Set(0)
+		usecase.Log.Warn().Strs("problems", problems).Msg("CDC replication is unhealthy")
+	}
+
+	return health
+}
+
+func (usecase *CDCMonitorUsecase) Health() web.CDCHealthResponse {
+	usecase.mutex.RLock()
+	defer usecase.mutex.RUnlock()
+
+	return usecase.health
+}
+
+func (usecase *CDCMonitorUsecase) Run(ctx context.Context) {
+	ticker := time.NewTicker(usecase.interval())
+	defer ticker.Stop()
+
+	for {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					usecase.Log.Error().Msg(fmt.Sprintf("cdc monitor check failed: %v", err))
+				}
+			}()
+
+			usecase.Check(ctx)
+		}()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (usecase *CDCMonitorUsecase) interval() time.Duration {
+	interval := usecase.Koanf.Duration("CDC_MONITOR_INTERVAL")
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return interval
+}
@@ -0,0 +1,211 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator"
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/repository"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var productExportColumns = []string{"id", "seller_id", "name", "product_picture", "quantity", "price", "weight", "size", "status", "description", "category_id", "tags", "created_at", "updated_at"}
+
+// ProductExportUsecase streams exports straight from the database. Each one
+// holds a connection until the client has the last row, so only MaxConcurrent
+// run at once and a client has WriteTimeout to take every batch.
+type ProductExportUsecase struct {
+	ProductRepository *repository.ProductRepository
+	DB                *sql.DB
+	Validator         *validator.Validate
+	Log               *zerolog.Logger
+	BatchSize         int
+	WriteTimeout      time.Duration
+	slots             chan struct{}
+}
+
+func NewProductExportUsecase(productRepository *repository.ProductRepository, db *sql.DB, validator *validator.Validate, zerolog *zerolog.Logger, koanf *koanf.Koanf) *ProductExportUsecase {
+	batchSize := koanf.Int("PRODUCT_EXPORT_BATCH_SIZE")
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	// the pool has 5 connections, the rest of the service keeps at least 3
+	maxConcurrent := koanf.Int("PRODUCT_EXPORT_MAX_CONCURRENT")
+	if maxConcurrent <= 0 {
+		maxConcurrent = 2
+	}
+
+	writeTimeout := koanf.Duration("PRODUCT_EXPORT_WRITE_TIMEOUT")
+	if writeTimeout <= 0 {
+		writeTimeout = time.Minute
+	}
+
+	return &ProductExportUsecase{
+		ProductRepository: productRepository,
+		DB:                db,
+		Validator:         validator,
+		Log:               zerolog,
+		BatchSize:         batchSize,
+		WriteTimeout:      writeTimeout,
+		slots:             make(chan struct{}, maxConcurrent),
+	}
+}
+
+// Export streams the products matching request to writer as CSV or NDJSON,
+// flushing after every batch read from the database. Nothing is written when
+// the request is invalid or too many exports are running, so the caller can
+// still answer with an error. Once rows went out a failure is returned as
+// "product export failed", the stream is then incomplete. An empty sellerUUID
+// exports every seller's products, for admins.
+func (usecase *ProductExportUsecase) Export(ctx context.Context, sellerUUID string, request product.ProductExportRequest, writer io.Writer) error {
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return respErr
+	}
+
+	select {
+	case usecase.slots <- struct{}{}:
+		defer func() { <-usecase.slots }()
+	default:
+		respErr := errors.New("too many exports are running, try again later")
+		usecase.Log.Warn().Msg(respErr.Error())
+		return respErr
+	}
+
+	listQuery := domain.ProductListQuery{
+		Seller_id:   request.Seller_id,
+		Category_id: request.Category_id,
+		Statuses:    request.Status,
+		Sizes:       request.Size,
+		Min_price:   request.Min_price,
+		Max_price:   request.Max_price,
+	}
+
+	if sellerUUID != "" {
+		listQuery.Seller_id = sellerUUID
+	}
+
+	// every batch has to reach the client within WriteTimeout, a stalled
+	// download is cut off instead of holding its connection
+	extendDeadline := func() {}
+	if responseWriter, ok := writer.(http.ResponseWriter); ok {
+		responseController := http.NewResponseController(responseWriter)
+		extendDeadline = func() {
+			responseController.SetWriteDeadline(time.Now().Add(usecase.WriteTimeout))
+		}
+
+		// the connection may serve further requests
+		defer responseController.SetWriteDeadline(time.Time{})
+	}
+
+	flush := func() {
+		if flusher, ok := writer.(http.Flusher); ok {
+			flusher.Flush()
+		}
+
+		extendDeadline()
+	}
+
+	var write func(product.ProductExportRow) error
+	var finish func() error
+
+	if request.Format == "csv" {
+		csvWriter := csv.NewWriter(writer)
+		csvWriter.Write(productExportColumns)
+
+		write = func(exportRow product.ProductExportRow) error {
+			return csvWriter.Write(productExportRecord(exportRow))
+		}
+		finish = func() error {
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+	} else {
+		encoder := json.NewEncoder(writer)
+
+		write = func(exportRow product.ProductExportRow) error {
+			return encoder.Encode(exportRow)
+		}
+		finish = func() error {
+			return nil
+		}
+	}
+
+	tx, err := usecase.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	// nothing to commit, and a client that went away has already ended the
+	// transaction with its context
+	defer tx.Rollback()
+
+	extendDeadline()
+
+	exported := 0
+
+	err = usecase.ProductRepository.StreamWithTx(ctx, tx, listQuery, usecase.BatchSize, func(exportRow product.ProductExportRow) error {
+		err := write(exportRow)
+		if err != nil {
+			return err
+		}
+
+		exported++
+		if exported%usecase.BatchSize == 0 {
+			err = finish()
+			flush()
+		}
+
+		return err
+	})
+
+	if err == nil {
+		err = finish()
+		flush()
+	}
+
+	if err != nil {
+		usecase.Log.Warn().Err(err).Msg("product export stopped after " + strconv.Itoa(exported) + " rows")
+		return errors.New("product export failed")
+	}
+
+	return nil
+}
+
+func productExportRecord(exportRow product.ProductExportRow) []string {
+	categoryID := ""
+	if exportRow.Category_id != nil {
+		categoryID = strconv.Itoa(*exportRow.Category_id)
+	}
+
+	return []string{
+		strconv.Itoa(exportRow.Id),
+		exportRow.Seller_id,
+		exportRow.Name,
+		exportRow.Product_picture,
+		strconv.Itoa(exportRow.Quantity),
+		strconv.FormatFloat(exportRow.Price, 'f', 2, 64),
+		strconv.Itoa(exportRow.Weight),
+		exportRow.Size,
+		exportRow.Status,
+		exportRow.Description,
+		categoryID,
+		strings.Join(exportRow.Tags, "|"),
+		exportRow.Created_at.Format(time.RFC3339),
+		exportRow.Updated_at.Format(time.RFC3339),
+	}
+}
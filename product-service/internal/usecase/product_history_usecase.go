@@ -0,0 +1,277 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/repository"
+	"reflect"
+	"time"
+)
+
+type ProductHistoryUsecase struct {
+	ProductRepository        *repository.ProductRepository
+	ProductHistoryRepository *repository.ProductHistoryRepository
+	DB                       *sql.DB
+	Validator                *validator.Validate
+	Log                      *zerolog.Logger
+}
+
+func NewProductHistoryUsecase(productRepository *repository.ProductRepository, productHistoryRepository *repository.ProductHistoryRepository, db *sql.DB, validator *validator.Validate, zerolog *zerolog.Logger) *ProductHistoryUsecase {
+	return &ProductHistoryUsecase{
+		ProductRepository:        productRepository,
+		ProductHistoryRepository: productHistoryRepository,
+		DB:                       db,
+		Validator:                validator,
+		Log:                      zerolog,
+	}
+}
+
+// HandleProductChange records the version a change event produced, with the
+// fields that differ from the version recorded before it. Redelivered events
+// carry an LSN that is already recorded and are ignored.
+func (usecase *ProductHistoryUsecase) HandleProductChange(ctx context.Context, payload product.ProductCDCPayload) {
+	var operation string
+	row := payload.After
+
+	switch payload.Op {
+	case "r":
+		operation = "snapshot"
+	case "c":
+		operation = "created"
+	case "u":
+		operation = "updated"
+		if payload.Before != nil && payload.After != nil {
+			if payload.Before.Deleted_at == nil && payload.After.Deleted_at != nil {
+				operation = "deleted"
+			} else if payload.Before.Deleted_at != nil && payload.After.Deleted_at == nil {
+				operation = "restored"
+			}
+		}
+	case "d":
+		operation, row = "purged", payload.Before
+	default:
+		return
+	}
+
+	if row == nil {
+		return
+	}
+
+	snapshot, err := json.Marshal(row)
+	if err != nil {
+		respErr := errors.New("failed to marshal a json")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	changes := map[string]product.ProductFieldChange{}
+
+	if operation != "purged" {
+		// the recorded version is preferred over Before, so the log stays
+		// continuous even if an event was missed
+		previous := payload.Before
+		previousHistory, err := usecase.ProductHistoryRepository.FindPreviousWithTx(ctx, tx, row.Id, payload.Source.Lsn)
+		if err == nil {
+			previous = &product.ProductCDCRow{}
+			err = json.Unmarshal([]byte(previousHistory.Snapshot), previous)
+			if err != nil {
+				respErr := errors.New("failed to unmarshal a json")
+				usecase.Log.Panic().Err(err).Msg(respErr.Error())
+			}
+		}
+
+		changes = usecase.diffSnapshots(previous, row)
+	}
+
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		respErr := errors.New("failed to marshal a json")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	changedAt := time.UnixMilli(payload.Source.Ts_ms)
+
+	usecase.ProductHistoryRepository.CreateWithTx(ctx, tx, domain.ProductHistory{
+		Product_id: row.Id,
+		Operation:  operation,
+		Snapshot:   string(snapshot),
+		Changes:    string(changesJSON),
+		Source_lsn: payload.Source.Lsn,
+		Changed_at: &changedAt,
+	})
+}
+
+// FindChanges lists the change log of a product, newest first. An empty
+// sellerUUID skips the ownership check, for support staff.
+func (usecase *ProductHistoryUsecase) FindChanges(ctx context.Context, sellerUUID string, productID int, request product.ProductHistoryRequest) (product.ProductHistoryListResponse, error) {
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return product.ProductHistoryListResponse{}, respErr
+	}
+
+	if sellerUUID != "" {
+		err = usecase.ProductRepository.CheckOwnership(ctx, sellerUUID, productID)
+		if err != nil {
+			usecase.Log.Warn().Msg(err.Error())
+			return product.ProductHistoryListResponse{}, err
+		}
+	}
+
+	histories := usecase.ProductHistoryRepository.FindByProductId(ctx, productID, request.Before_id, request.Limit)
+
+	listResponse := product.ProductHistoryListResponse{
+		History: []product.ProductHistoryResponse{},
+	}
+
+	for _, history := range histories {
+		changes := map[string]product.ProductFieldChange{}
+		err = json.Unmarshal([]byte(history.Changes), &changes)
+		if err != nil {
+			respErr := errors.New("failed to unmarshal a json")
+			usecase.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		listResponse.History = append(listResponse.History, product.ProductHistoryResponse{
+			Id:         history.Id,
+			Product_id: history.Product_id,
+			Operation:  history.Operation,
+			Changes:    changes,
+			Changed_at: history.Changed_at,
+		})
+	}
+
+	if len(histories) == request.Limit {
+		nextBeforeID := histories[len(histories)-1].Id
+		listResponse.Next_before_id = &nextBeforeID
+	}
+
+	return listResponse, nil
+}
+
+// FindAsOf returns the product as it was at asOf, an RFC 3339 timestamp. A
+// product that was purged by then is not found. An empty sellerUUID skips the
+// ownership check, for support staff.
+func (usecase *ProductHistoryUsecase) FindAsOf(ctx context.Context, sellerUUID string, productID int, asOf string) (product.ProductAsOfResponse, error) {
+	asOfTime, err := time.Parse(time.RFC3339, asOf)
+	if err != nil {
+		respErr := errors.New("at must be an RFC 3339 timestamp")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return product.ProductAsOfResponse{}, respErr
+	}
+
+	if sellerUUID != "" {
+		err = usecase.ProductRepository.CheckOwnership(ctx, sellerUUID, productID)
+		if err != nil {
+			usecase.Log.Warn().Msg(err.Error())
+			return product.ProductAsOfResponse{}, err
+		}
+	}
+
+	history, err := usecase.ProductHistoryRepository.FindAsOf(ctx, productID, &asOfTime)
+	if err != nil || history.Operation == "purged" {
+		respErr := errors.New("product not found")
+		usecase.Log.Warn().Msg(respErr.Error())
+		return product.ProductAsOfResponse{}, respErr
+	}
+
+	row := product.ProductCDCRow{}
+	err = json.Unmarshal([]byte(history.Snapshot), &row)
+	if err != nil {
+		respErr := errors.New("failed to unmarshal a json")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	asOfResponse := product.ProductAsOfResponse{
+		Product:    toProductSnapshotResponse(&row),
+		Operation:  history.Operation,
+		Changed_at: history.Changed_at,
+		As_of:      &asOfTime,
+	}
+
+	return asOfResponse, nil
+}
+
+// diffSnapshots compares two versions field by field on their JSON
+// form. updated_at moves with every change and is left out.
+func (usecase *ProductHistoryUsecase) diffSnapshots(before *product.ProductCDCRow, after *product.ProductCDCRow) map[string]product.ProductFieldChange {
+	beforeFields := usecase.snapshotFields(before)
+	afterFields := usecase.snapshotFields(after)
+
+	changes := map[string]product.ProductFieldChange{}
+
+	for field, to := range afterFields {
+		from := beforeFields[field]
+		if field == "updated_at" || reflect.DeepEqual(from, to) {
+			continue
+		}
+
+		changes[field] = product.ProductFieldChange{From: from, To: to}
+	}
+
+	return changes
+}
+
+func (usecase *ProductHistoryUsecase) snapshotFields(row *product.ProductCDCRow) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if row == nil {
+		return fields
+	}
+
+	snapshotJSON, err := json.Marshal(toProductSnapshotResponse(row))
+	if err != nil {
+		respErr := errors.New("failed to marshal a json")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	err = json.Unmarshal(snapshotJSON, &fields)
+	if err != nil {
+		respErr := errors.New("failed to unmarshal a json")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return fields
+}
+
+func toProductSnapshotResponse(row *product.ProductCDCRow) product.ProductSnapshotResponse {
+	createdAt := time.UnixMicro(row.Created_at)
+	updatedAt := time.UnixMicro(row.Updated_at)
+
+	var deletedAt *time.Time
+	if row.Deleted_at != nil {
+		deletedAtTime := time.UnixMicro(*row.Deleted_at)
+		deletedAt = &deletedAtTime
+	}
+
+	return product.ProductSnapshotResponse{
+		Id:              row.Id,
+		Seller_id:       row.Seller_id,
+		Name:            row.Name,
+		Product_picture: row.Product_picture,
+		Quantity:        row.Quantity,
+		Price:           row.Price,
+		Weight:          row.Weight,
+		Size:            row.Size,
+		Status:          row.Status,
+		Description:     row.Description,
+		Category_id:     row.Category_id,
+		Created_at:      &createdAt,
+		Updated_at:      &updatedAt,
+		Deleted_at:      deletedAt,
+	}
+}
@@ -0,0 +1,327 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator"
+	googleuuid "github.com/google/uuid"
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/repository"
+	"gocdc/internal/storage"
+	"net/http"
+	"time"
+)
+
+// productImageTypes maps the accepted content types, sniffed from the file
+// itself rather than trusted from the client, to their file extension.
+var productImageTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/webp": "webp",
+}
+
+type ProductImageUsecase struct {
+	ProductRepository      *repository.ProductRepository
+	ProductImageRepository *repository.ProductImageRepository
+	Storage                storage.Storage
+	DB                     *sql.DB
+	Validator              *validator.Validate
+	Log                    *zerolog.Logger
+	MaxImageBytes          int64
+	MaxImages              int
+}
+
+func NewProductImageUsecase(productRepository *repository.ProductRepository, productImageRepository *repository.ProductImageRepository, storage storage.Storage, db *sql.DB, validator *validator.Validate, zerolog *zerolog.Logger, koanf *koanf.Koanf) *ProductImageUsecase {
+	maxImageBytes := koanf.Int64("PRODUCT_IMAGE_MAX_BYTES")
+	if maxImageBytes <= 0 {
+		maxImageBytes = 5 << 20
+	}
+
+	maxImages := koanf.Int("PRODUCT_IMAGE_MAX_COUNT")
+	if maxImages <= 0 {
+		maxImages = 8
+	}
+
+	return &ProductImageUsecase{
+		ProductRepository:      productRepository,
+		ProductImageRepository: productImageRepository,
+		Storage:                storage,
+		DB:                     db,
+		Validator:              validator,
+		Log:                    zerolog,
+		MaxImageBytes:          maxImageBytes,
+		MaxImages:              maxImages,
+	}
+}
+
+// Upload appends images to the end of a product gallery. Files are checked
+// and stored before the gallery is touched, and stored files are removed again
+// when the gallery cannot be updated.
+func (usecase *ProductImageUsecase) Upload(ctx context.Context, userUUID string, productID int, uploads []domain.ProductImageUpload) (responses []product.ProductImageResponse, err error) {
+	err = usecase.ProductRepository.CheckOwnership(ctx, userUUID, productID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return nil, err
+	}
+
+	if len(uploads) == 0 {
+		respErr := errors.New("no image uploaded")
+		usecase.Log.Warn().Msg(respErr.Error())
+		return nil, respErr
+	}
+
+	contentTypes := []string{}
+	for _, upload := range uploads {
+		if int64(len(upload.Data)) > usecase.MaxImageBytes {
+			respErr := fmt.Errorf("image %s is larger than %d bytes", upload.Filename, usecase.MaxImageBytes)
+			usecase.Log.Warn().Msg(respErr.Error())
+			return nil, respErr
+		}
+
+		contentType := http.DetectContentType(upload.Data)
+		if _, ok := productImageTypes[contentType]; !ok {
+			respErr := fmt.Errorf("image %s must be a jpeg, png or webp file", upload.Filename)
+			usecase.Log.Warn().Msg(respErr.Error())
+			return nil, respErr
+		}
+
+		contentTypes = append(contentTypes, contentType)
+	}
+
+	if len(usecase.ProductImageRepository.FindByProductId(ctx, productID))+len(uploads) > usecase.MaxImages {
+		respErr := fmt.Errorf("a product can have at most %d images", usecase.MaxImages)
+		usecase.Log.Warn().Msg(respErr.Error())
+		return nil, respErr
+	}
+
+	storedKeys := []string{}
+
+	// deferred before the transaction, so it runs after a rollback
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			usecase.deleteStored(storedKeys)
+			panic(recovered)
+		}
+		if err != nil {
+			usecase.deleteStored(storedKeys)
+		}
+	}()
+
+	// every file is stored before the transaction opens, an error returned
+	// inside it would commit rows whose files are gone
+	imageIDs := []string{}
+	for i, upload := range uploads {
+		imageID := googleuuid.New().String()
+		key := fmt.Sprintf("products/%d/%s.%s", productID, imageID, productImageTypes[contentTypes[i]])
+
+		err = usecase.Storage.Put(ctx, key, contentTypes[i], upload.Data)
+		if err != nil {
+			respErr := errors.New("image storage is unavailable")
+			usecase.Log.Error().Err(err).Msg(respErr.Error())
+			return nil, respErr
+		}
+
+		imageIDs = append(imageIDs, imageID)
+		storedKeys = append(storedKeys, key)
+	}
+
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	usecase.ProductImageRepository.LockProductWithTx(ctx, tx, productID)
+	images := usecase.ProductImageRepository.FindByProductIdWithTx(ctx, tx, productID)
+
+	// checked again under the lock, nothing is written to the gallery yet
+	if len(images)+len(uploads) > usecase.MaxImages {
+		respErr := fmt.Errorf("a product can have at most %d images", usecase.MaxImages)
+		usecase.Log.Warn().Msg(respErr.Error())
+		return nil, respErr
+	}
+
+	now := time.Now()
+
+	for i, upload := range uploads {
+		image := domain.ProductImage{
+			Id:           imageIDs[i],
+			Product_id:   productID,
+			Storage_key:  storedKeys[i],
+			Url:          usecase.Storage.URL(storedKeys[i]),
+			Content_type: contentTypes[i],
+			Size_bytes:   len(upload.Data),
+			Position:     len(images),
+			Created_at:   &now,
+		}
+
+		usecase.ProductImageRepository.CreateWithTx(ctx, tx, image)
+		images = append(images, image)
+	}
+
+	usecase.ProductImageRepository.UpdateCoverWithTx(ctx, tx, productID, images[0].Url, &now)
+
+	return toProductImageResponses(images), nil
+}
+
+func (usecase *ProductImageUsecase) FindByProductId(ctx context.Context, productID int) []product.ProductImageResponse {
+	return toProductImageResponses(usecase.ProductImageRepository.FindByProductId(ctx, productID))
+}
+
+func (usecase *ProductImageUsecase) Reorder(ctx context.Context, userUUID string, productID int, request product.ProductImageOrderRequest) ([]product.ProductImageResponse, error) {
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return nil, respErr
+	}
+
+	err = usecase.ProductRepository.CheckOwnership(ctx, userUUID, productID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return nil, err
+	}
+
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	usecase.ProductImageRepository.LockProductWithTx(ctx, tx, productID)
+	images := usecase.ProductImageRepository.FindByProductIdWithTx(ctx, tx, productID)
+
+	imagesById := map[string]domain.ProductImage{}
+	for _, image := range images {
+		imagesById[image.Id] = image
+	}
+
+	ordered := []domain.ProductImage{}
+	for position, imageID := range request.Image_ids {
+		image, ok := imagesById[imageID]
+		if !ok {
+			break
+		}
+
+		delete(imagesById, imageID)
+
+		image.Position = position
+		ordered = append(ordered, image)
+	}
+
+	if len(ordered) != len(request.Image_ids) || len(imagesById) > 0 {
+		respErr := errors.New("image ids must list every image of the product once")
+		usecase.Log.Warn().Msg(respErr.Error())
+		return nil, respErr
+	}
+
+	for _, image := range ordered {
+		usecase.ProductImageRepository.UpdatePositionWithTx(ctx, tx, image.Id, image.Position)
+	}
+
+	now := time.Now()
+	usecase.ProductImageRepository.UpdateCoverWithTx(ctx, tx, productID, ordered[0].Url, &now)
+
+	return toProductImageResponses(ordered), nil
+}
+
+// Delete removes an image from the gallery and closes the gap it leaves. The
+// file is removed from storage once the gallery no longer points at it.
+func (usecase *ProductImageUsecase) Delete(ctx context.Context, userUUID string, productID int, imageID string) error {
+	err := usecase.ProductRepository.CheckOwnership(ctx, userUUID, productID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return err
+	}
+
+	image, err := usecase.deleteImage(ctx, productID, imageID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return err
+	}
+
+	usecase.deleteStored([]string{image.Storage_key})
+
+	return nil
+}
+
+func (usecase *ProductImageUsecase) deleteImage(ctx context.Context, productID int, imageID string) (domain.ProductImage, error) {
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	usecase.ProductImageRepository.LockProductWithTx(ctx, tx, productID)
+	images := usecase.ProductImageRepository.FindByProductIdWithTx(ctx, tx, productID)
+
+	deleted := domain.ProductImage{}
+	remaining := []domain.ProductImage{}
+
+	for _, image := range images {
+		if image.Id == imageID {
+			deleted = image
+			continue
+		}
+
+		remaining = append(remaining, image)
+	}
+
+	if deleted.Id == "" {
+		return deleted, errors.New("image not found")
+	}
+
+	usecase.ProductImageRepository.DeleteWithTx(ctx, tx, imageID)
+
+	for position, image := range remaining {
+		if image.Position != position {
+			usecase.ProductImageRepository.UpdatePositionWithTx(ctx, tx, image.Id, position)
+		}
+	}
+
+	// product_picture is required, the last image stays as the picture
+	if len(remaining) > 0 {
+		now := time.Now()
+		usecase.ProductImageRepository.UpdateCoverWithTx(ctx, tx, productID, remaining[0].Url, &now)
+	}
+
+	return deleted, nil
+}
+
+func (usecase *ProductImageUsecase) deleteStored(keys []string) {
+	for _, key := range keys {
+		err := usecase.Storage.Delete(context.Background(), key)
+		if err != nil {
+			usecase.Log.Warn().Err(err).Msg("failed to delete stored image " + key)
+		}
+	}
+}
+
+func toProductImageResponses(images []domain.ProductImage) []product.ProductImageResponse {
+	imageResponses := []product.ProductImageResponse{}
+	for position, image := range images {
+		imageResponses = append(imageResponses, product.ProductImageResponse{
+			Id:           image.Id,
+			Product_id:   image.Product_id,
+			Url:          image.Url,
+			Content_type: image.Content_type,
+			Size_bytes:   image.Size_bytes,
+			Position:     position,
+			Created_at:   image.Created_at,
+		})
+	}
+
+	return imageResponses
+}
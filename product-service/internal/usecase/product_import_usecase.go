@@ -0,0 +1,531 @@
+package usecase
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator"
+	googleuuid "github.com/google/uuid"
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/repository"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// productImportColumns are the CSV columns an import understands, named after
+// the ProductCreateRequest json fields. Tags are separated by "|".
+var productImportColumns = map[string]bool{
+	"name":            true,
+	"product_picture": true,
+	"quantity":        true,
+	"price":           true,
+	"weight":          true,
+	"size":            true,
+	"status":          false,
+	"description":     true,
+	"category_id":     false,
+	"tags":            false,
+}
+
+// productImportRow is one record of an import file. Number counts the
+// records from 1, the CSV header and blank NDJSON lines are not counted.
+type productImportRow struct {
+	Number  int
+	Request product.ProductCreateRequest
+	Err     error
+}
+
+type ProductImportUsecase struct {
+	ProductUsecase          *ProductUsecase
+	ProductRepository       *repository.ProductRepository
+	CategoryRepository      *repository.CategoryRepository
+	TagRepository           *repository.TagRepository
+	ProductImportRepository *repository.ProductImportRepository
+	DB                      *sql.DB
+	Validator               *validator.Validate
+	Log                     *zerolog.Logger
+	Koanf                   *koanf.Koanf
+	MaxBytes                int64
+	MaxRows                 int
+}
+
+func NewProductImportUsecase(productUsecase *ProductUsecase, productRepository *repository.ProductRepository, categoryRepository *repository.CategoryRepository, tagRepository *repository.TagRepository, productImportRepository *repository.ProductImportRepository, db *sql.DB, validator *validator.Validate, zerolog *zerolog.Logger, koanf *koanf.Koanf) *ProductImportUsecase {
+	maxBytes := koanf.Int64("PRODUCT_IMPORT_MAX_BYTES")
+	if maxBytes <= 0 {
+		maxBytes = 10 << 20
+	}
+
+	maxRows := koanf.Int("PRODUCT_IMPORT_MAX_ROWS")
+	if maxRows <= 0 {
+		maxRows = 5000
+	}
+
+	return &ProductImportUsecase{
+		ProductUsecase:          productUsecase,
+		ProductRepository:       productRepository,
+		CategoryRepository:      categoryRepository,
+		TagRepository:           tagRepository,
+		ProductImportRepository: productImportRepository,
+		DB:                      db,
+		Validator:               validator,
+		Log:                     zerolog,
+		Koanf:                   koanf,
+		MaxBytes:                maxBytes,
+		MaxRows:                 maxRows,
+	}
+}
+
+// Create queues an import of a CSV or NDJSON file. format may be left empty
+// when the filename ends in .csv, .ndjson or .jsonl. The file is only checked
+// for its shape here, rows are validated by the worker.
+func (usecase *ProductImportUsecase) Create(ctx context.Context, userUUID string, userAuthToken string, filename string, format string, data []byte) (product.ProductImportResponse, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".csv":
+			format = "csv"
+		case ".ndjson", ".jsonl":
+			format = "ndjson"
+		}
+	}
+
+	if format != "csv" && format != "ndjson" {
+		respErr := errors.New("format must be csv or ndjson")
+		usecase.Log.Warn().Msg(respErr.Error())
+		return product.ProductImportResponse{}, respErr
+	}
+
+	if int64(len(data)) > usecase.MaxBytes {
+		respErr := fmt.Errorf("file is larger than %d bytes", usecase.MaxBytes)
+		usecase.Log.Warn().Msg(respErr.Error())
+		return product.ProductImportResponse{}, respErr
+	}
+
+	rows, err := parseProductImport(format, data)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return product.ProductImportResponse{}, err
+	}
+
+	if len(rows) == 0 {
+		respErr := errors.New("file has no rows")
+		usecase.Log.Warn().Msg(respErr.Error())
+		return product.ProductImportResponse{}, respErr
+	}
+
+	if len(rows) > usecase.MaxRows {
+		respErr := fmt.Errorf("file has more than %d rows", usecase.MaxRows)
+		usecase.Log.Warn().Msg(respErr.Error())
+		return product.ProductImportResponse{}, respErr
+	}
+
+	_, err = usecase.ProductUsecase.FindUserExistenceAPI(ctx, userAuthToken)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return product.ProductImportResponse{}, err
+	}
+
+	now := time.Now()
+	productImport := domain.ProductImport{
+		Id:         googleuuid.New().String(),
+		Seller_id:  userUUID,
+		Format:     format,
+		Status:     "Pending",
+		Data:       data,
+		Total_rows: len(rows),
+		Created_at: &now,
+		Updated_at: &now,
+	}
+
+	usecase.ProductImportRepository.Create(ctx, productImport)
+
+	return toProductImportResponse(productImport), nil
+}
+
+func (usecase *ProductImportUsecase) FindById(ctx context.Context, userUUID string, importID string) (product.ProductImportResponse, error) {
+	productImport, err := usecase.ProductImportRepository.FindById(ctx, importID, userUUID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return product.ProductImportResponse{}, err
+	}
+
+	return toProductImportResponse(productImport), nil
+}
+
+// FindErrorReport writes the rows that were not imported as a CSV of row
+// number and reason.
+func (usecase *ProductImportUsecase) FindErrorReport(ctx context.Context, userUUID string, importID string, writer io.Writer) error {
+	_, err := usecase.ProductImportRepository.FindById(ctx, importID, userUUID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return err
+	}
+
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Write([]string{"row", "error"})
+
+	for _, importError := range usecase.ProductImportRepository.FindErrors(ctx, importID) {
+		csvWriter.Write([]string{strconv.Itoa(importError.Row_number), importError.Error})
+	}
+
+	csvWriter.Flush()
+
+	return csvWriter.Error()
+}
+
+// Run works through queued imports one at a time. Progress is saved after
+// every batch together with the products of that batch.
+func (usecase *ProductImportUsecase) Run(ctx context.Context) {
+	pollInterval := usecase.Koanf.Duration("PRODUCT_IMPORT_POLL_INTERVAL")
+	if pollInterval <= 0 {
+		pollInterval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					usecase.Log.Error().Msg(fmt.Sprintf("product import failed: %v", err))
+				}
+			}()
+
+			for usecase.runNext(ctx) {
+			}
+		}()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (usecase *ProductImportUsecase) runNext(ctx context.Context) bool {
+	lease := usecase.Koanf.Duration("PRODUCT_IMPORT_LEASE")
+	if lease <= 0 {
+		lease = 5 * time.Minute
+	}
+
+	batchSize := usecase.Koanf.Int("PRODUCT_IMPORT_BATCH_SIZE")
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	now := time.Now()
+	leaseUntil := now.Add(lease)
+
+	productImport, err := usecase.ProductImportRepository.Claim(ctx, &now, &leaseUntil)
+	if err != nil {
+		return false
+	}
+
+	// a job that keeps failing is given up instead of being retried forever
+	if productImport.Attempts > 3 {
+		usecase.ProductImportRepository.Finish(ctx, productImport.Id, "Failed", "import failed after 3 attempts", &now)
+		return true
+	}
+
+	rows, err := parseProductImport(productImport.Format, productImport.Data)
+	if err != nil {
+		usecase.ProductImportRepository.Finish(ctx, productImport.Id, "Failed", err.Error(), &now)
+		return true
+	}
+
+	categoryErrors := map[int]error{}
+
+	for start := productImport.Processed_rows; start < len(rows); start += batchSize {
+		end := start + batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		usecase.importBatch(ctx, &productImport, rows[start:end], categoryErrors, lease)
+	}
+
+	finishedAt := time.Now()
+	usecase.ProductImportRepository.Finish(ctx, productImport.Id, "Completed", "", &finishedAt)
+
+	usecase.Log.Info().Msg(fmt.Sprintf("product import %s imported %d of %d rows", productImport.Id, productImport.Imported_rows, productImport.Total_rows))
+
+	return true
+}
+
+func (usecase *ProductImportUsecase) importBatch(ctx context.Context, productImport *domain.ProductImport, rows []productImportRow, categoryErrors map[int]error, lease time.Duration) {
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	now := time.Now()
+
+	for _, row := range rows {
+		err := usecase.importRow(ctx, tx, productImport.Seller_id, row, categoryErrors, &now)
+		if err != nil {
+			usecase.ProductImportRepository.CreateErrorWithTx(ctx, tx, productImport.Id, domain.ProductImportError{
+				Row_number: row.Number,
+				Error:      err.Error(),
+			})
+			productImport.Failed_rows++
+		} else {
+			productImport.Imported_rows++
+		}
+
+		productImport.Processed_rows++
+	}
+
+	leaseUntil := now.Add(lease)
+	usecase.ProductImportRepository.UpdateProgressWithTx(ctx, tx, *productImport, &leaseUntil, &now)
+}
+
+// importRow applies the checks of ProductUsecase.Create to one row and
+// inserts it.
+func (usecase *ProductImportUsecase) importRow(ctx context.Context, tx *sql.Tx, sellerID string, row productImportRow, categoryErrors map[int]error, now *time.Time) error {
+	if row.Err != nil {
+		return row.Err
+	}
+
+	request := row.Request
+
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		return productImportValidationError(err)
+	}
+
+	var categoryID *int
+	if request.Category_id != 0 {
+		categoryErr, checked := categoryErrors[request.Category_id]
+		if !checked {
+			_, categoryErr = usecase.CategoryRepository.FindById(ctx, request.Category_id)
+			categoryErrors[request.Category_id] = categoryErr
+		}
+
+		if categoryErr != nil {
+			return categoryErr
+		}
+
+		categoryID = &request.Category_id
+	}
+
+	status := request.Status
+	if status == "" {
+		status = "Ready"
+	}
+
+	productID := usecase.ProductRepository.CreateWithTx(ctx, tx, domain.Product{
+		Seller_id:       sellerID,
+		Name:            request.Name,
+		Product_picture: request.Product_picture,
+		Quantity:        request.Quantity,
+		Price:           request.Price,
+		Weight:          request.Weight,
+		Size:            request.Size,
+		Status:          status,
+		Description:     request.Description,
+		Category_id:     categoryID,
+		Created_at:      now,
+		Updated_at:      now,
+	})
+
+	tags := normalizeTags(request.Tags)
+	if len(tags) > 0 {
+		usecase.TagRepository.ReplaceProductTagsWithTx(ctx, tx, productID, tags)
+	}
+
+	return nil
+}
+
+// productImportValidationError names the failed fields the way they are
+// spelled in the file.
+func productImportValidationError(err error) error {
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	messages := []string{}
+	for _, fieldError := range validationErrors {
+		messages = append(messages, fmt.Sprintf("%s failed on %s", strings.ToLower(fieldError.Field()), fieldError.Tag()))
+	}
+
+	return errors.New(strings.Join(messages, ", "))
+}
+
+// parseProductImport splits a file into rows. Only a file that cannot be
+// read at all is an error, a bad row carries its own error.
+func parseProductImport(format string, data []byte) ([]productImportRow, error) {
+	if format == "csv" {
+		return parseProductImportCSV(data)
+	}
+
+	return parseProductImportNDJSON(data)
+}
+
+func parseProductImportCSV(data []byte) ([]productImportRow, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("csv header could not be read")
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if _, ok := productImportColumns[column]; !ok {
+			return nil, fmt.Errorf("unknown csv column %s", column)
+		}
+
+		columns[column] = i
+	}
+
+	for column, required := range productImportColumns {
+		if _, ok := columns[column]; required && !ok {
+			return nil, fmt.Errorf("csv header is missing %s", column)
+		}
+	}
+
+	rows := []productImportRow{}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		row := productImportRow{Number: len(rows) + 1}
+
+		if err != nil {
+			row.Err = errors.New("malformed csv record")
+		} else if len(record) != len(header) {
+			row.Err = fmt.Errorf("expected %d fields, got %d", len(header), len(record))
+		} else {
+			row.Request, row.Err = productImportRequest(columns, record)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func productImportRequest(columns map[string]int, record []string) (product.ProductCreateRequest, error) {
+	field := func(column string) string {
+		if i, ok := columns[column]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	number := func(column string) (int, error) {
+		if field(column) == "" {
+			return 0, nil
+		}
+
+		value, err := strconv.Atoi(field(column))
+		if err != nil {
+			return 0, fmt.Errorf("%s must be a whole number", column)
+		}
+
+		return value, nil
+	}
+
+	request := product.ProductCreateRequest{
+		Name:            field("name"),
+		Product_picture: field("product_picture"),
+		Size:            field("size"),
+		Status:          field("status"),
+		Description:     field("description"),
+	}
+
+	var err error
+
+	if request.Quantity, err = number("quantity"); err != nil {
+		return request, err
+	}
+
+	if request.Weight, err = number("weight"); err != nil {
+		return request, err
+	}
+
+	if request.Category_id, err = number("category_id"); err != nil {
+		return request, err
+	}
+
+	if field("price") != "" {
+		request.Price, err = strconv.ParseFloat(field("price"), 64)
+		if err != nil {
+			return request, errors.New("price must be a number")
+		}
+	}
+
+	if field("tags") != "" {
+		request.Tags = strings.Split(field("tags"), "|")
+	}
+
+	return request, nil
+}
+
+func parseProductImportNDJSON(data []byte) ([]productImportRow, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+
+	rows := []productImportRow{}
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		row := productImportRow{Number: len(rows) + 1}
+
+		err := json.Unmarshal(line, &row.Request)
+		if err != nil {
+			row.Err = errors.New("malformed json object")
+		}
+
+		rows = append(rows, row)
+	}
+
+	if scanner.Err() != nil {
+		return nil, errors.New("ndjson file could not be read")
+	}
+
+	return rows, nil
+}
+
+func toProductImportResponse(productImport domain.ProductImport) product.ProductImportResponse {
+	return product.ProductImportResponse{
+		Id:             productImport.Id,
+		Format:         productImport.Format,
+		Status:         productImport.Status,
+		Total_rows:     productImport.Total_rows,
+		Processed_rows: productImport.Processed_rows,
+		Imported_rows:  productImport.Imported_rows,
+		Failed_rows:    productImport.Failed_rows,
+		Error:          productImport.Error,
+		Created_at:     productImport.Created_at,
+		Updated_at:     productImport.Updated_at,
+		Finished_at:    productImport.Finished_at,
+	}
+}
@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"gocdc/internal/model/web/product"
+	"reflect"
+	"testing"
+)
+
+func TestParseProductImportCSV(t *testing.T) {
+	header := "name,product_picture,quantity,price,weight,size,description"
+
+	tests := []struct {
+		name    string
+		data    string
+		rows    []productImportRow
+		rowErrs []string
+		err     string
+	}{
+		{
+			name: "rows",
+			data: "\xef\xbb\xbf" + header + ",status,category_id,tags\n" +
+				"Kemeja Batik,https://cdn.example.com/a.jpg,3,125000.5,250,L,Kemeja batik tulis,Ready,7,batik|kemeja\n" +
+				"Kaos Polos,https://cdn.example.com/b.jpg,10,50000,200,M,Kaos polos katun,,,\n",
+			rows: []productImportRow{
+				{Number: 1, Request: product.ProductCreateRequest{Name: "Kemeja Batik", Product_picture: "https://cdn.example.com/a.jpg", Quantity: 3, Price: 125000.5, Weight: 250, Size: "L", Status: "Ready", Description: "Kemeja batik tulis", Category_id: 7, Tags: []string{"batik", "kemeja"}}},
+				{Number: 2, Request: product.ProductCreateRequest{Name: "Kaos Polos", Product_picture: "https://cdn.example.com/b.jpg", Quantity: 10, Price: 50000, Weight: 200, Size: "M", Description: "Kaos polos katun"}},
+			},
+			rowErrs: []string{"", ""},
+		},
+		{
+			name:    "column order and case",
+			data:    "Size, DESCRIPTION,name,product_picture,quantity,price,weight\nS,Celana pendek,Celana,https://cdn.example.com/c.jpg,1,10,100\n",
+			rows:    []productImportRow{{Number: 1, Request: product.ProductCreateRequest{Name: "Celana", Product_picture: "https://cdn.example.com/c.jpg", Quantity: 1, Price: 10, Weight: 100, Size: "S", Description: "Celana pendek"}}},
+			rowErrs: []string{""},
+		},
+		{
+			name:    "bad rows keep their number",
+			data:    header + "\nA,b,x,1,1,S,d\nA,b,1,x,1,S,d\nA,b,1,1\nA,b,1,1,1.5,S,d\n",
+			rowErrs: []string{"quantity must be a whole number", "price must be a number", "expected 7 fields, got 4", "weight must be a whole number"},
+		},
+		{
+			name:    "header only",
+			data:    header + "\n",
+			rows:    []productImportRow{},
+			rowErrs: []string{},
+		},
+		{
+			name: "empty file",
+			data: "",
+			err:  "csv header could not be read",
+		},
+		{
+			name: "unknown column",
+			data: header + ",colour\n",
+			err:  "unknown csv column colour",
+		},
+		{
+			name: "missing column",
+			data: "name,product_picture,quantity,price,weight,size\n",
+			err:  "csv header is missing description",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows, err := parseProductImportCSV([]byte(test.data))
+			checkProductImportRows(t, rows, err, test.rows, test.rowErrs, test.err)
+		})
+	}
+}
+
+func TestParseProductImportNDJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		rows    []productImportRow
+		rowErrs []string
+		err     string
+	}{
+		{
+			name: "rows",
+			data: `{"name":"Kemeja Batik","quantity":3,"price":125000.5,"tags":["batik"]}` + "\n" +
+				`{"name":"Kaos Polos","size":"M","category_id":2}`,
+			rows: []productImportRow{
+				{Number: 1, Request: product.ProductCreateRequest{Name: "Kemeja Batik", Quantity: 3, Price: 125000.5, Tags: []string{"batik"}}},
+				{Number: 2, Request: product.ProductCreateRequest{Name: "Kaos Polos", Size: "M", Category_id: 2}},
+			},
+			rowErrs: []string{"", ""},
+		},
+		{
+			name:    "blank lines are not counted",
+			data:    "\n  \r\n" + `{"name":"Celana"}` + "\r\n\n",
+			rows:    []productImportRow{{Number: 1, Request: product.ProductCreateRequest{Name: "Celana"}}},
+			rowErrs: []string{""},
+		},
+		{
+			name:    "bad rows keep their number",
+			data:    `{"name":"Celana"` + "\n" + `{"quantity":"3"}` + "\n" + `[1]` + "\n",
+			rowErrs: []string{"malformed json object", "malformed json object", "malformed json object"},
+		},
+		{
+			name:    "empty file",
+			data:    "",
+			rows:    []productImportRow{},
+			rowErrs: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows, err := parseProductImportNDJSON([]byte(test.data))
+			checkProductImportRows(t, rows, err, test.rows, test.rowErrs, test.err)
+		})
+	}
+}
+
+// checkProductImportRows compares the requests only when want is set, rowErrs
+// always lists the error of every row, "" for none.
+func checkProductImportRows(t *testing.T, rows []productImportRow, err error, want []productImportRow, rowErrs []string, wantErr string) {
+	t.Helper()
+
+	if wantErr != "" {
+		if err == nil || err.Error() != wantErr {
+			t.Fatalf("error = %v, want %q", err, wantErr)
+		}
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(rows) != len(rowErrs) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(rowErrs))
+	}
+
+	for i, row := range rows {
+		if row.Number != i+1 {
+			t.Errorf("row %d has number %d", i+1, row.Number)
+		}
+
+		rowErr := ""
+		if row.Err != nil {
+			rowErr = row.Err.Error()
+		}
+
+		if rowErr != rowErrs[i] {
+			t.Errorf("row %d error = %q, want %q", i+1, rowErr, rowErrs[i])
+		}
+
+		if want != nil && !reflect.DeepEqual(row.Request, want[i].Request) {
+			t.Errorf("row %d = %+v, want %+v", i+1, row.Request, want[i].Request)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/repository"
+	"gocdc/internal/storage"
+	"time"
+)
+
+type ProductPurgeUsecase struct {
+	ProductRepository      *repository.ProductRepository
+	ProductImageRepository *repository.ProductImageRepository
+	Storage                storage.Storage
+	DB                     *sql.DB
+	Log                    *zerolog.Logger
+	Koanf                  *koanf.Koanf
+}
+
+func NewProductPurgeUsecase(productRepository *repository.ProductRepository, productImageRepository *repository.ProductImageRepository, storage storage.Storage, db *sql.DB, zerolog *zerolog.Logger, koanf *koanf.Koanf) *ProductPurgeUsecase {
+	return &ProductPurgeUsecase{
+		ProductRepository:      productRepository,
+		ProductImageRepository: productImageRepository,
+		Storage:                storage,
+		DB:                     db,
+		Log:                    zerolog,
+		Koanf:                  koanf,
+	}
+}
+
+// Run hard deletes products that were soft deleted longer than
+// PRODUCT_PURGE_RETENTION ago, together with their stored images. The
+// retention never ends before the restore grace period does.
+func (usecase *ProductPurgeUsecase) Run(ctx context.Context) {
+	interval := usecase.Koanf.Duration("PRODUCT_PURGE_INTERVAL")
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	gracePeriod := usecase.Koanf.Duration("PRODUCT_RESTORE_GRACE_PERIOD")
+	if gracePeriod <= 0 {
+		gracePeriod = 30 * 24 * time.Hour
+	}
+
+	retention := usecase.Koanf.Duration("PRODUCT_PURGE_RETENTION")
+	if retention < gracePeriod {
+		retention = gracePeriod
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					usecase.Log.Error().Msg(fmt.Sprintf("product purge failed: %v", err))
+				}
+			}()
+
+			// a full batch means more may be waiting
+			purged := 100
+			for purged == 100 {
+				purged = usecase.purgeBatch(ctx, retention, 100)
+			}
+		}()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (usecase *ProductPurgeUsecase) purgeBatch(ctx context.Context, retention time.Duration, limit int) int {
+	productIDs, keys := func() ([]int, []string) {
+		tx, err := usecase.DB.Begin()
+		if err != nil {
+			respErr := errors.New("failed to start transaction")
+			usecase.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		defer helper.CommitOrRollback(tx)
+
+		deletedBefore := time.Now().Add(-retention)
+		productIDs := usecase.ProductRepository.FindPurgeableWithTx(ctx, tx, &deletedBefore, limit)
+		if len(productIDs) == 0 {
+			return productIDs, nil
+		}
+
+		// images, variants, tags and reservations go with the product
+		keys := usecase.ProductImageRepository.FindStorageKeysByProductIdsWithTx(ctx, tx, productIDs)
+		usecase.ProductRepository.PurgeWithTx(ctx, tx, productIDs)
+
+		return productIDs, keys
+	}()
+
+	// files are removed only once the rows pointing at them are gone
+	for _, key := range keys {
+		err := usecase.Storage.Delete(ctx, key)
+		if err != nil {
+			usecase.Log.Warn().Err(err).Msg("failed to delete stored image " + key)
+		}
+	}
+
+	if len(productIDs) > 0 {
+		usecase.Log.Info().Msg(fmt.Sprintf("purged %d deleted products", len(productIDs)))
+	}
+
+	return len(productIDs)
+}
@@ -0,0 +1,543 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"github.com/go-playground/validator"
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/breaker"
+	"gocdc/internal/cache"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/repository"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	searchMetrics        = expvar.NewMap("search")
+	searchPrimaryHealthy = new(expvar.Int)
+	searchBreakerState   = new(expvar.String)
+	searchBulkPaused     = new(expvar.Int)
+)
+
+func init() {
+	searchMetrics.Set("primary_healthy", searchPrimaryHealthy)
+	searchMetrics.Set("breaker_state", searchBreakerState)
+	searchMetrics.Set("bulk_paused", searchBulkPaused)
+}
+
+type ProductSearchUsecase struct {
+	UserServiceUrl              string
+	ProductSearchRepository     *repository.ProductSearchRepository
+	ProductSuggestionRepository *repository.ProductSuggestionRepository
+	PrimaryBackend              repository.ProductSearchBackend
+	FallbackBackend             repository.ProductSearchBackend
+	Breaker                     *breaker.Breaker
+	SearchAnalyticsUsecase      *SearchAnalyticsUsecase
+	Cache                       cache.Cache
+	Validator                   *validator.Validate
+	Log                         *zerolog.Logger
+	Koanf                       *koanf.Koanf
+	primaryHealthy              atomic.Bool
+}
+
+func NewProductSearchUsecase(userServiceUrl string, productSearchRepository *repository.ProductSearchRepository, productTextSearchRepository *repository.ProductTextSearchRepository, productSuggestionRepository *repository.ProductSuggestionRepository, searchAnalyticsUsecase *SearchAnalyticsUsecase, productCache cache.Cache, validator *validator.Validate, zerolog *zerolog.Logger, koanf *koanf.Koanf) *ProductSearchUsecase {
+	threshold := koanf.Int("SEARCH_BREAKER_THRESHOLD")
+	if threshold <= 0 {
+		threshold = 5
+	}
+
+	cooldown := koanf.Duration("SEARCH_BREAKER_COOLDOWN")
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+
+	return &ProductSearchUsecase{
+		UserServiceUrl:              userServiceUrl,
+		ProductSearchRepository:     productSearchRepository,
+		ProductSuggestionRepository: productSuggestionRepository,
+		PrimaryBackend:              productSearchRepository,
+		FallbackBackend:             productTextSearchRepository,
+		Breaker:                     breaker.NewBreaker(threshold, cooldown),
+		SearchAnalyticsUsecase:      searchAnalyticsUsecase,
+		Cache:                       productCache,
+		Validator:                   validator,
+		Log:                         zerolog,
+		Koanf:                       koanf,
+	}
+}
+
+// maxSearchResultWindow is index.max_result_window of the product index, the
+// deepest result Elasticsearch serves with from and size.
+const maxSearchResultWindow = 10000
+
+func (usecase *ProductSearchUsecase) Search(ctx context.Context, request product.ProductSearchRequest, userUUID string) (product.ProductSearchResponse, error) {
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return product.ProductSearchResponse{}, respErr
+	}
+
+	if request.Page*request.Per_page > maxSearchResultWindow {
+		respErr := fmt.Errorf("page and per_page reach past the first %d results", maxSearchResultWindow)
+		usecase.Log.Warn().Msg(respErr.Error())
+		return product.ProductSearchResponse{}, respErr
+	}
+
+	query := domain.ProductSearchQuery{
+		Text:        request.Q,
+		Category_id: request.Category,
+		Sort:        request.Sort,
+		Offset:      (request.Page - 1) * request.Per_page,
+		Limit:       request.Per_page,
+		Sizes:       request.Size,
+		Statuses:    request.Status,
+		Min_price:   request.Min_price,
+		Max_price:   request.Max_price,
+		Min_weight:  request.Min_weight,
+		Max_weight:  request.Max_weight,
+	}
+
+	start := time.Now()
+
+	result, err := usecase.search(ctx, query)
+	if err != nil {
+		respErr := errors.New("search is unavailable")
+		usecase.Log.Error().Err(err).Msg(respErr.Error())
+		return product.ProductSearchResponse{}, respErr
+	}
+
+	now := time.Now()
+
+	usecase.SearchAnalyticsUsecase.Record(product.ProductSearchEvent{
+		Query: request.Q,
+		Filters: product.ProductSearchFilters{
+			Sort:       request.Sort,
+			Category:   request.Category,
+			Page:       request.Page,
+			Size:       request.Size,
+			Status:     request.Status,
+			Min_price:  request.Min_price,
+			Max_price:  request.Max_price,
+			Min_weight: request.Min_weight,
+			Max_weight: request.Max_weight,
+		},
+		Backend:      result.Backend,
+		Result_count: result.Total,
+		Latency_ms:   now.Sub(start).Milliseconds(),
+		User_id:      userUUID,
+		Created_at:   &now,
+	})
+
+	searchResponse := product.ProductSearchResponse{
+		Backend:  result.Backend,
+		Total:    result.Total,
+		Page:     request.Page,
+		Per_page: request.Per_page,
+		Products: []product.ProductSearchHit{},
+		Facets: product.ProductSearchFacets{
+			Size:   toProductSearchFacetBuckets(result.Facets["size"]),
+			Status: toProductSearchFacetBuckets(result.Facets["status"]),
+			Price:  toProductSearchFacetBuckets(result.Facets["price"]),
+			Weight: toProductSearchFacetBuckets(result.Facets["weight"]),
+		},
+	}
+
+	snippetLength := usecase.Koanf.Int("SEARCH_SNIPPET_LENGTH")
+	if snippetLength <= 0 {
+		snippetLength = 160
+	}
+
+	for _, document := range result.Documents {
+		hit := product.ProductSearchHit{
+			Id:         document.Id,
+			Seller_id:  document.Seller_id,
+			Name:       document.Name,
+			Quantity:   document.Quantity,
+			Price:      document.Price,
+			Weight:     document.Weight,
+			Size:       document.Size,
+			Status:     document.Status,
+			Snippet:    toSnippet(document.Description, snippetLength),
+			Created_at: document.Created_at,
+			Updated_at: document.Updated_at,
+		}
+
+		highlight, ok := result.Highlights[document.Id]
+		if ok {
+			hit.Highlights = &product.ProductSearchHitHighlight{
+				Name:        highlight.Name,
+				Description: highlight.Description,
+			}
+		}
+
+		searchResponse.Products = append(searchResponse.Products, hit)
+	}
+
+	return searchResponse, nil
+}
+
+// Similar fills the similar items rail of a product page. There is no
+// Postgres equivalent of more-like-this, so it is unavailable while
+// Elasticsearch is down. Answers are cached since product pages are hot.
+func (usecase *ProductSearchUsecase) Similar(ctx context.Context, request product.ProductSimilarRequest) (product.ProductSimilarResponse, error) {
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return product.ProductSimilarResponse{}, respErr
+	}
+
+	cacheKey := fmt.Sprintf("similar:%d:%d", request.Product_id, request.Limit)
+
+	similarResponse := product.ProductSimilarResponse{}
+
+	value, ok := usecase.Cache.Get(ctx, cacheKey)
+	if ok && json.Unmarshal(value, &similarResponse) == nil {
+		return similarResponse, nil
+	}
+
+	document, err := usecase.ProductSearchRepository.FindDocument(ctx, request.Product_id)
+	if err != nil {
+		if err.Error() == "product not found" {
+			return product.ProductSimilarResponse{}, err
+		}
+
+		respErr := errors.New("search is unavailable")
+		usecase.Log.Error().Err(err).Msg(respErr.Error())
+		return product.ProductSimilarResponse{}, respErr
+	}
+
+	documents, err := usecase.ProductSearchRepository.Similar(ctx, document, request.Limit)
+	if err != nil {
+		respErr := errors.New("search is unavailable")
+		usecase.Log.Error().Err(err).Msg(respErr.Error())
+		return product.ProductSimilarResponse{}, respErr
+	}
+
+	snippetLength := usecase.Koanf.Int("SEARCH_SNIPPET_LENGTH")
+	if snippetLength <= 0 {
+		snippetLength = 160
+	}
+
+	similarResponse = product.ProductSimilarResponse{
+		Product_id: request.Product_id,
+		Products:   []product.ProductSearchHit{},
+	}
+
+	for _, document := range documents {
+		similarResponse.Products = append(similarResponse.Products, product.ProductSearchHit{
+			Id:         document.Id,
+			Seller_id:  document.Seller_id,
+			Name:       document.Name,
+			Quantity:   document.Quantity,
+			Price:      document.Price,
+			Weight:     document.Weight,
+			Size:       document.Size,
+			Status:     document.Status,
+			Snippet:    toSnippet(document.Description, snippetLength),
+			Created_at: document.Created_at,
+			Updated_at: document.Updated_at,
+		})
+	}
+
+	ttl := usecase.Koanf.Duration("SIMILAR_CACHE_TTL")
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	valueJSON, err := json.Marshal(similarResponse)
+	if err == nil {
+		usecase.Cache.Set(ctx, cacheKey, valueJSON, ttl)
+	}
+
+	return similarResponse, nil
+}
+
+// search asks Elasticsearch while its last health check passed and the breaker
+// is closed, and Postgres otherwise or when Elasticsearch fails the request.
+func (usecase *ProductSearchUsecase) search(ctx context.Context, query domain.ProductSearchQuery) (domain.ProductSearchResult, error) {
+	if usecase.primaryHealthy.Load() && usecase.Breaker.Allow() {
+		result, err := usecase.PrimaryBackend.Search(ctx, query)
+		if err == nil {
+			usecase.Breaker.Success()
+			searchMetrics.Add("primary", 1)
+			return result, nil
+		}
+
+		usecase.Breaker.Failure()
+		usecase.Log.Warn().Err(err).Msg("primary search backend failed, falling back")
+	}
+
+	searchMetrics.Add("fallback", 1)
+
+	return usecase.FallbackBackend.Search(ctx, query)
+}
+
+// RunHealthCheck pings the primary backend periodically. Until the first ping
+// succeeds searches go to the fallback.
+func (usecase *ProductSearchUsecase) RunHealthCheck(ctx context.Context) {
+	interval := usecase.Koanf.Duration("SEARCH_HEALTH_INTERVAL")
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pingCtx, cancel := context.WithTimeout(ctx, interval)
+		err := usecase.PrimaryBackend.Ping(pingCtx)
+		cancel()
+
+		healthy := err == nil
+		if usecase.primaryHealthy.Swap(healthy) != healthy {
+			if healthy {
+				usecase.Log.Info().Msg("Primary search backend is available")
+			} else {
+				usecase.Log.Warn().Err(err).Msg("primary search backend is unavailable")
+			}
+		}
+
+		if healthy {
+			searchPrimaryHealthy.Set(1)
+		} else {
+			searchPrimaryHealthy.Set(0)
+		}
+
+		searchBreakerState.Set(usecase.Breaker.State())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Suggest serves keystroke-level autocomplete. Answers are cached briefly since
+// consecutive users type the same prefixes.
+func (usecase *ProductSearchUsecase) Suggest(ctx context.Context, request product.ProductSuggestRequest) (product.ProductSuggestResponse, error) {
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return product.ProductSuggestResponse{}, respErr
+	}
+
+	prefix := strings.ToLower(strings.TrimSpace(request.Prefix))
+	cacheKey := fmt.Sprintf("suggest:%d:%s", request.Limit, prefix)
+
+	suggestResponse := product.ProductSuggestResponse{}
+
+	value, ok := usecase.Cache.Get(ctx, cacheKey)
+	if ok && json.Unmarshal(value, &suggestResponse) == nil {
+		return suggestResponse, nil
+	}
+
+	suggestions, err := usecase.ProductSearchRepository.Suggest(ctx, prefix, request.Limit)
+	if err != nil {
+		respErr := errors.New("search is unavailable")
+		usecase.Log.Error().Err(err).Msg(respErr.Error())
+		return product.ProductSuggestResponse{}, respErr
+	}
+
+	suggestResponse.Products = toProductSuggestions(suggestions["product"])
+	suggestResponse.Sellers = toProductSuggestions(suggestions["seller"])
+
+	ttl := usecase.Koanf.Duration("SUGGEST_CACHE_TTL")
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+
+	valueJSON, err := json.Marshal(suggestResponse)
+	if err == nil {
+		usecase.Cache.Set(ctx, cacheKey, valueJSON, ttl)
+	}
+
+	return suggestResponse, nil
+}
+
+// HandleProductChange recomputes the autocomplete entries touched by a change.
+// Stock and price updates leave names and listing counts as they were. Seller
+// names come from user-service, a renamed seller is picked up with their next
+// product change.
+func (usecase *ProductSearchUsecase) HandleProductChange(ctx context.Context, payload product.ProductCDCPayload) {
+	before := payload.Before
+	after := payload.After
+
+	if before != nil && after != nil && before.Name == after.Name && before.Seller_id == after.Seller_id && before.Status == after.Status && (before.Deleted_at == nil) == (after.Deleted_at == nil) {
+		return
+	}
+
+	names := map[string]string{}
+	sellers := map[string]bool{}
+
+	for _, row := range []*product.ProductCDCRow{before, after} {
+		if row != nil {
+			names[strings.ToLower(row.Name)] = row.Name
+			sellers[row.Seller_id] = true
+		}
+	}
+
+	for key, name := range names {
+		suggestion := usecase.ProductSuggestionRepository.FindProductName(ctx, name)
+		usecase.storeSuggestion(ctx, "product:"+key, suggestion)
+	}
+
+	for sellerID := range sellers {
+		suggestion := domain.ProductSuggestion{
+			Type:       "seller",
+			Popularity: usecase.ProductSuggestionRepository.CountSellerProducts(ctx, sellerID),
+		}
+
+		if suggestion.Popularity > 0 {
+			sellerName, err := usecase.FindSellerNameAPI(ctx, sellerID)
+			if err != nil && err.Error() != "seller not found" {
+				respErr := errors.New("failed to find seller name")
+				usecase.Log.Panic().Err(err).Msg(respErr.Error())
+			}
+
+			// a deleted seller is not suggested anymore
+			suggestion.Text = sellerName
+			if err != nil {
+				suggestion.Popularity = 0
+			}
+		}
+
+		usecase.storeSuggestion(ctx, "seller:"+sellerID, suggestion)
+	}
+}
+
+func (usecase *ProductSearchUsecase) FindSellerNameAPI(ctx context.Context, sellerID string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%sseller/%s", usecase.UserServiceUrl, url.PathEscape(sellerID)), nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", errors.New("seller not found")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("user service responded with status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var apiResp web.SellerApiResponse
+	err = json.Unmarshal(body, &apiResp)
+	if err != nil {
+		return "", err
+	}
+
+	return apiResp.Data.Name, nil
+}
+
+// storeSuggestion drops entries nothing is listed under anymore.
+func (usecase *ProductSearchUsecase) storeSuggestion(ctx context.Context, id string, suggestion domain.ProductSuggestion) {
+	var err error
+
+	if suggestion.Popularity > 0 {
+		err = usecase.ProductSearchRepository.IndexSuggestion(ctx, id, suggestion)
+	} else {
+		err = usecase.ProductSearchRepository.DeleteSuggestion(ctx, id)
+	}
+
+	if err != nil {
+		respErr := errors.New("failed to update product suggestion index")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func toProductSuggestions(suggestions []domain.ProductSuggestion) []product.ProductSuggestion {
+	productSuggestions := []product.ProductSuggestion{}
+	for _, suggestion := range suggestions {
+		productSuggestions = append(productSuggestions, product.ProductSuggestion{
+			Text:       suggestion.Text,
+			Popularity: suggestion.Popularity,
+		})
+	}
+
+	return productSuggestions
+}
+
+func toProductSearchFacetBuckets(buckets []domain.ProductSearchFacetBucket) []product.ProductSearchFacetBucket {
+	facetBuckets := []product.ProductSearchFacetBucket{}
+	for _, bucket := range buckets {
+		facetBuckets = append(facetBuckets, product.ProductSearchFacetBucket{
+			Key:   bucket.Key,
+			From:  bucket.From,
+			To:    bucket.To,
+			Count: bucket.Doc_count,
+		})
+	}
+
+	return facetBuckets
+}
+
+// toSnippet cuts text to at most length characters at a word boundary.
+func toSnippet(text string, length int) string {
+	text = strings.Join(strings.Fields(text), " ")
+
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+
+	// a cut that already ends a word keeps it
+	snippet := string(runes[:length])
+	if runes[length] != ' ' {
+		if i := strings.LastIndex(snippet, " "); i > 0 {
+			snippet = snippet[:i]
+		}
+	}
+
+	return strings.TrimRight(snippet, " ,.;:-") + "…"
+}
+
+func toProductDocument(row *product.ProductCDCRow) domain.ProductDocument {
+	createdAt := time.UnixMicro(row.Created_at)
+	updatedAt := time.UnixMicro(row.Updated_at)
+
+	return domain.ProductDocument{
+		Id:          row.Id,
+		Seller_id:   row.Seller_id,
+		Name:        row.Name,
+		Quantity:    row.Quantity,
+		Price:       row.Price,
+		Weight:      row.Weight,
+		Size:        row.Size,
+		Status:      row.Status,
+		Description: row.Description,
+		Category_id: row.Category_id,
+		Created_at:  &createdAt,
+		Updated_at:  &updatedAt,
+	}
+}
@@ -0,0 +1,31 @@
+package usecase
+
+import "testing"
+
+func TestToSnippet(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		length int
+		want   string
+	}{
+		{name: "short text", text: "Kemeja batik", length: 20, want: "Kemeja batik"},
+		{name: "exact length", text: "Kemeja batik", length: 12, want: "Kemeja batik"},
+		{name: "whitespace collapsed", text: "  Kemeja\n\tbatik   tulis ", length: 50, want: "Kemeja batik tulis"},
+		{name: "cut at a word", text: "Kemeja batik tulis asli", length: 15, want: "Kemeja batik…"},
+		{name: "cut at the end of a word", text: "Kemeja batik tulis asli", length: 12, want: "Kemeja batik…"},
+		{name: "punctuation trimmed", text: "Kemeja batik, tulis asli", length: 15, want: "Kemeja batik…"},
+		{name: "single long word", text: "Supercalifragilistic", length: 5, want: "Super…"},
+		{name: "counted in runes", text: "Kaos ünïcödé überlänge", length: 14, want: "Kaos ünïcödé…"},
+		{name: "empty", text: "", length: 10, want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := toSnippet(test.text, test.length)
+			if got != test.want {
+				t.Errorf("toSnippet(%q, %d) = %q, want %q", test.text, test.length, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/repository"
+	"strconv"
+	"time"
+)
+
+type ProductStatusUsecase struct {
+	ProductRepository       *repository.ProductRepository
+	ProductStatusRepository *repository.ProductStatusRepository
+	KafkaWriter             sarama.SyncProducer
+	DB                      *sql.DB
+	Log                     *zerolog.Logger
+}
+
+func NewProductStatusUsecase(productRepository *repository.ProductRepository, productStatusRepository *repository.ProductStatusRepository, kafkaWriter sarama.SyncProducer, db *sql.DB, zerolog *zerolog.Logger) *ProductStatusUsecase {
+	return &ProductStatusUsecase{
+		ProductRepository:       productRepository,
+		ProductStatusRepository: productStatusRepository,
+		KafkaWriter:             kafkaWriter,
+		DB:                      db,
+		Log:                     zerolog,
+	}
+}
+
+// Transition moves a product of userUUID to status if the lifecycle allows
+// it. A product without stock cannot be made Ready.
+func (usecase *ProductStatusUsecase) Transition(ctx context.Context, userUUID string, productID int, status string) (product.ProductStatusChangeResponse, error) {
+	change, err := usecase.transition(ctx, userUUID, productID, status)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return product.ProductStatusChangeResponse{}, err
+	}
+
+	usecase.PublishChanges([]domain.ProductStatusChange{change})
+
+	return toProductStatusChangeResponse(change), nil
+}
+
+func (usecase *ProductStatusUsecase) transition(ctx context.Context, userUUID string, productID int, status string) (domain.ProductStatusChange, error) {
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	productRow, err := usecase.ProductStatusRepository.FindProductWithTx(ctx, tx, productID)
+	if err != nil || productRow.Seller_id != userUUID {
+		return domain.ProductStatusChange{}, errors.New("product not found")
+	}
+
+	if productRow.Status == status {
+		return domain.ProductStatusChange{}, fmt.Errorf("product is already %s", status)
+	}
+
+	if !domain.CanTransitionProductStatus(productRow.Status, status) {
+		return domain.ProductStatusChange{}, fmt.Errorf("product status cannot change from %s to %s", productRow.Status, status)
+	}
+
+	if status == "Ready" && productRow.Quantity <= 0 {
+		return domain.ProductStatusChange{}, errors.New("product is out of stock")
+	}
+
+	now := time.Now()
+
+	change := domain.ProductStatusChange{
+		Product_id:  productID,
+		Seller_id:   productRow.Seller_id,
+		From_status: productRow.Status,
+		To_status:   status,
+		Reason:      "manual",
+		Actor_id:    &userUUID,
+		Created_at:  &now,
+	}
+
+	usecase.ProductStatusRepository.UpdateStatusWithTx(ctx, tx, productID, status, &now)
+	change.Id = usecase.ProductStatusRepository.CreateHistoryWithTx(ctx, tx, change)
+
+	return change, nil
+}
+
+func (usecase *ProductStatusUsecase) FindHistory(ctx context.Context, userUUID string, productID int) ([]product.ProductStatusChangeResponse, error) {
+	err := usecase.ProductRepository.CheckOwnership(ctx, userUUID, productID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return nil, err
+	}
+
+	changeResponses := []product.ProductStatusChangeResponse{}
+	for _, change := range usecase.ProductStatusRepository.FindHistoryByProductId(ctx, productID) {
+		changeResponses = append(changeResponses, toProductStatusChangeResponse(change))
+	}
+
+	return changeResponses, nil
+}
+
+// RecordStockChangeWithTx writes the history row of a status that a stock
+// update switched in the same statement and appends it to changes, which are
+// published with PublishChanges once the transaction committed. A kept status
+// records nothing.
+func (usecase *ProductStatusUsecase) RecordStockChangeWithTx(ctx context.Context, tx *sql.Tx, changes []domain.ProductStatusChange, change domain.ProductStatusChange) []domain.ProductStatusChange {
+	if change.From_status == change.To_status {
+		return changes
+	}
+
+	change.Id = usecase.ProductStatusRepository.CreateHistoryWithTx(ctx, tx, change)
+
+	return append(changes, change)
+}
+
+// HandleProductChange is the repair path for Ready and SoldOut. Stock updates
+// switch the status themselves, this only catches a product whose quantity
+// was changed some other way, when a change event shows its stock ran out or
+// came back.
+func (usecase *ProductStatusUsecase) HandleProductChange(ctx context.Context, payload product.ProductCDCPayload) {
+	after := payload.After
+	if after == nil || after.Deleted_at != nil {
+		return
+	}
+
+	if !(after.Status == "Ready" && after.Quantity <= 0) && !(after.Status == "SoldOut" && after.Quantity > 0) {
+		return
+	}
+
+	change, changed := func() (domain.ProductStatusChange, bool) {
+		tx, err := usecase.DB.Begin()
+		if err != nil {
+			respErr := errors.New("failed to start transaction")
+			usecase.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		defer helper.CommitOrRollback(tx)
+
+		now := time.Now()
+
+		change, changed := usecase.ProductStatusRepository.UpdateStockStatusWithTx(ctx, tx, after.Id, &now)
+		if changed {
+			change.Id = usecase.ProductStatusRepository.CreateHistoryWithTx(ctx, tx, change)
+		}
+
+		return change, changed
+	}()
+
+	if changed {
+		usecase.PublishChanges([]domain.ProductStatusChange{change})
+	}
+}
+
+// PublishCommitted is deferred before helper.CommitOrRollback, so it runs once
+// the transaction ended. It publishes changes after a commit, a rolled back
+// transaction passes its panic on and publishes nothing.
+func (usecase *ProductStatusUsecase) PublishCommitted(changes *[]domain.ProductStatusChange) {
+	if recovered := recover(); recovered != nil {
+		panic(recovered)
+	}
+
+	usecase.PublishChanges(*changes)
+}
+
+// PublishChanges announces status changes once they are committed.
+func (usecase *ProductStatusUsecase) PublishChanges(changes []domain.ProductStatusChange) {
+	for _, change := range changes {
+		statusEvent := product.ProductStatusEvent{
+			Product_id:  change.Product_id,
+			Seller_id:   change.Seller_id,
+			From_status: change.From_status,
+			To_status:   change.To_status,
+			Reason:      change.Reason,
+			Actor_id:    change.Actor_id,
+			Created_at:  change.Created_at,
+		}
+
+		messageJSON, err := json.Marshal(statusEvent)
+		if err != nil {
+			respErr := errors.New("failed to marshal a json")
+			usecase.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		_, _, err = usecase.KafkaWriter.SendMessage(&sarama.ProducerMessage{
+			Topic: "product.status_changed",
+			Key:   sarama.StringEncoder(strconv.Itoa(change.Product_id)),
+			Value: sarama.ByteEncoder(messageJSON),
+		})
+
+		if err != nil {
+			respErr := errors.New("failed to produce an event to kafka broker")
+			usecase.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+	}
+}
+
+func toProductStatusChangeResponse(change domain.ProductStatusChange) product.ProductStatusChangeResponse {
+	return product.ProductStatusChangeResponse{
+		Id:          change.Id,
+		Product_id:  change.Product_id,
+		From_status: change.From_status,
+		To_status:   change.To_status,
+		Reason:      change.Reason,
+		Actor_id:    change.Actor_id,
+		Created_at:  change.Created_at,
+	}
+}
@@ -3,6 +3,7 @@ package usecase
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,37 +12,51 @@ import (
 	"github.com/go-playground/validator"
 	"github.com/knadh/koanf/v2"
 	"github.com/rs/zerolog"
+	"gocdc/internal/cache"
 	"gocdc/internal/helper"
 	"gocdc/internal/model/domain"
 	"gocdc/internal/model/web"
 	"gocdc/internal/model/web/product"
 	"gocdc/internal/repository"
+	"hash/fnv"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type ProductUsecase struct {
-	UserServiceUrl    string
-	ProductRepository *repository.ProductRepository
-	KafkaWriter       sarama.SyncProducer
-	DB                *sql.DB
-	ElasticSearch     *elasticsearch.Client
-	Validator         *validator.Validate
-	Log               *zerolog.Logger
-	Koanf             *koanf.Koanf
+	UserServiceUrl           string
+	ProductRepository        *repository.ProductRepository
+	ProductVariantRepository *repository.ProductVariantRepository
+	CategoryRepository       *repository.CategoryRepository
+	TagRepository            *repository.TagRepository
+	ProductStatusUsecase     *ProductStatusUsecase
+	KafkaWriter              sarama.SyncProducer
+	DB                       *sql.DB
+	ElasticSearch            *elasticsearch.Client
+	Cache                    cache.Cache
+	Validator                *validator.Validate
+	Log                      *zerolog.Logger
+	Koanf                    *koanf.Koanf
 }
 
-func NewProductUsecase(userServiceUrl string, productRepository *repository.ProductRepository, kafkaWriter sarama.SyncProducer, db *sql.DB, elasticsearch *elasticsearch.Client, validator *validator.Validate, zerolog *zerolog.Logger, koanf *koanf.Koanf) *ProductUsecase {
+func NewProductUsecase(userServiceUrl string, productRepository *repository.ProductRepository, productVariantRepository *repository.ProductVariantRepository, categoryRepository *repository.CategoryRepository, tagRepository *repository.TagRepository, productStatusUsecase *ProductStatusUsecase, kafkaWriter sarama.SyncProducer, db *sql.DB, elasticsearch *elasticsearch.Client, productCache cache.Cache, validator *validator.Validate, zerolog *zerolog.Logger, koanf *koanf.Koanf) *ProductUsecase {
 	return &ProductUsecase{
-		UserServiceUrl:    userServiceUrl,
-		ProductRepository: productRepository,
-		KafkaWriter:       kafkaWriter,
-		DB:                db,
-		ElasticSearch:     elasticsearch,
-		Validator:         validator,
-		Log:               zerolog,
-		Koanf:             koanf,
+		UserServiceUrl:           userServiceUrl,
+		ProductRepository:        productRepository,
+		ProductVariantRepository: productVariantRepository,
+		CategoryRepository:       categoryRepository,
+		TagRepository:            tagRepository,
+		ProductStatusUsecase:     productStatusUsecase,
+		KafkaWriter:              kafkaWriter,
+		DB:                       db,
+		ElasticSearch:            elasticsearch,
+		Cache:                    productCache,
+		Validator:                validator,
+		Log:                      zerolog,
+		Koanf:                    koanf,
 	}
 }
 
@@ -53,6 +68,12 @@ func (usecase *ProductUsecase) Create(ctx context.Context, request product.Produ
 		return respErr
 	}
 
+	categoryID, err := usecase.findCategoryId(ctx, request.Category_id)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return err
+	}
+
 	tx, err := usecase.DB.Begin()
 	if err != nil {
 		respErr := errors.New("failed to start transaction")
@@ -75,6 +96,12 @@ func (usecase *ProductUsecase) Create(ctx context.Context, request product.Produ
 
 	productEvent := product.ProductEvent{}
 
+	// a product starts as Ready unless the seller keeps it as a Draft
+	status := request.Status
+	if status == "" {
+		status = "Ready"
+	}
+
 	now := time.Now()
 	product := domain.Product{
 		Seller_id:       userUUID,
@@ -84,12 +111,19 @@ func (usecase *ProductUsecase) Create(ctx context.Context, request product.Produ
 		Price:           request.Price,
 		Weight:          request.Weight,
 		Size:            request.Size,
+		Status:          status,
 		Description:     request.Description,
+		Category_id:     categoryID,
 		Created_at:      &now,
 		Updated_at:      &now,
 	}
 
-	usecase.ProductRepository.CreateWithTx(ctx, tx, product)
+	productID := usecase.ProductRepository.CreateWithTx(ctx, tx, product)
+
+	tags := normalizeTags(request.Tags)
+	if len(tags) > 0 {
+		usecase.TagRepository.ReplaceProductTagsWithTx(ctx, tx, productID, tags)
+	}
 
 	topic := "product.activity"
 
@@ -186,6 +220,15 @@ func (usecase *ProductUsecase) Update(ctx context.Context, request product.Produ
 		return respErr
 	}
 
+	categoryID, err := usecase.findCategoryId(ctx, request.Category_id)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return err
+	}
+
+	changes := []domain.ProductStatusChange{}
+	defer usecase.ProductStatusUsecase.PublishCommitted(&changes)
+
 	tx, err := usecase.DB.Begin()
 	if err != nil {
 		respErr := errors.New("failed to start transaction")
@@ -205,15 +248,21 @@ func (usecase *ProductUsecase) Update(ctx context.Context, request product.Produ
 	product := domain.Product{
 		Id:          productID,
 		Name:        request.Name,
-		Quantity:    request.Quantity,
 		Price:       request.Price,
 		Weight:      request.Weight,
 		Size:        request.Size,
 		Description: request.Description,
+		Category_id: categoryID,
 		Updated_at:  &now,
 	}
 
-	usecase.ProductRepository.UpdateWithTx(ctx, tx, product)
+	changes = usecase.ProductStatusUsecase.RecordStockChangeWithTx(ctx, tx, changes, usecase.ProductRepository.UpdateWithTx(ctx, tx, product, request.Quantity))
+
+	// updated_at changes in the same transaction, so the change event that
+	// reindexes the product already sees the new tags
+	if request.Tags != nil {
+		usecase.TagRepository.ReplaceProductTagsWithTx(ctx, tx, productID, normalizeTags(request.Tags))
+	}
 
 	return nil
 }
@@ -225,29 +274,292 @@ func (usecase *ProductUsecase) Delete(ctx context.Context, userUUID string, prod
 		return err
 	}
 
-	usecase.ProductRepository.Delete(ctx, productID)
+	now := time.Now()
+	usecase.ProductRepository.Delete(ctx, productID, &now)
+
+	return nil
+}
+
+// Restore undoes a Delete made within PRODUCT_RESTORE_GRACE_PERIOD.
+func (usecase *ProductUsecase) Restore(ctx context.Context, userUUID string, productID int) error {
+	gracePeriod := usecase.Koanf.Duration("PRODUCT_RESTORE_GRACE_PERIOD")
+	if gracePeriod <= 0 {
+		gracePeriod = 30 * 24 * time.Hour
+	}
+
+	now := time.Now()
+	deletedAfter := now.Add(-gracePeriod)
+
+	err := usecase.ProductRepository.Restore(ctx, userUUID, productID, &deletedAfter, &now)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return err
+	}
 
 	return nil
 }
 
 func (usecase *ProductUsecase) FindProductInfo(ctx context.Context, productID int) (product.ProductResponse, error) {
+	// the generation is read before the database, see cacheGeneration
+	cacheKey := productCacheKey(productID, usecase.cacheGeneration(ctx, productGenerationKey(productID)))
+
+	productResponse := product.ProductResponse{}
+	if usecase.readCache(ctx, cacheKey, &productResponse) {
+		return productResponse, nil
+	}
+
 	productResponse, err := usecase.ProductRepository.FindProductInfo(ctx, productID)
 	if err != nil {
 		usecase.Log.Warn().Msg(err.Error())
 		return productResponse, err
 	}
 
+	productResponse.Tags = usecase.TagRepository.FindByProductId(ctx, productID)
+
+	// variant changes touch the product row, so the cached copy is invalidated
+	// with it
+	variants := usecase.ProductVariantRepository.FindByProductId(ctx, productID)
+	if len(variants) > 0 {
+		productResponse.Variants = toProductVariantResponses(variants)
+	}
+
+	usecase.writeCache(ctx, cacheKey, productResponse)
+
 	return productResponse, nil
 }
 
-func (usecase *ProductUsecase) FindAllProduct(ctx context.Context) ([]product.ProductResponse, error) {
-	productResponse, err := usecase.ProductRepository.FindAllProduct(ctx)
+func (usecase *ProductUsecase) FindAllProduct(ctx context.Context, request product.ProductListRequest) (product.ProductListResponse, error) {
+	err := usecase.Validator.Struct(request)
 	if err != nil {
-		usecase.Log.Warn().Msg(err.Error())
-		return productResponse, err
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return product.ProductListResponse{}, respErr
 	}
 
-	return productResponse, nil
+	if request.Sort == "" {
+		request.Sort = "created_at_desc"
+	}
+
+	if request.Category_id > 0 {
+		_, err = usecase.CategoryRepository.FindById(ctx, request.Category_id)
+		if err != nil {
+			usecase.Log.Warn().Msg(err.Error())
+			return product.ProductListResponse{}, err
+		}
+	}
+
+	listQuery := domain.ProductListQuery{
+		Seller_id:   request.Seller_id,
+		Category_id: request.Category_id,
+		Statuses:    request.Status,
+		Sizes:       request.Size,
+		Min_price:   request.Min_price,
+		Max_price:   request.Max_price,
+		Sort:        request.Sort,
+		Limit:       request.Limit,
+	}
+
+	if request.Cursor != "" {
+		cursor, err := decodeProductCursor(request.Cursor)
+		if err != nil || cursor.Sort != request.Sort {
+			respErr := errors.New("invalid cursor")
+			usecase.Log.Warn().Msg(respErr.Error())
+			return product.ProductListResponse{}, respErr
+		}
+
+		listQuery.After = &cursor
+	}
+
+	cacheKey := usecase.productListCacheKey(ctx, request)
+
+	listResponse := product.ProductListResponse{}
+	if usecase.readCache(ctx, cacheKey, &listResponse) {
+		return listResponse, nil
+	}
+
+	products := usecase.ProductRepository.FindAllProduct(ctx, listQuery)
+
+	listResponse = product.ProductListResponse{
+		Products: products,
+		Limit:    request.Limit,
+		Sort:     request.Sort,
+	}
+
+	if len(products) > request.Limit {
+		listResponse.Products = products[:request.Limit]
+		listResponse.Has_more = true
+		listResponse.Next_cursor = encodeProductCursor(request.Sort, listResponse.Products[request.Limit-1])
+	}
+
+	usecase.writeCache(ctx, cacheKey, listResponse)
+
+	return listResponse, nil
+}
+
+// findCategoryId checks that a category assigned to a product exists, 0 means
+// none was given.
+func (usecase *ProductUsecase) findCategoryId(ctx context.Context, categoryID int) (*int, error) {
+	if categoryID == 0 {
+		return nil, nil
+	}
+
+	category, err := usecase.CategoryRepository.FindById(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &category.Id, nil
+}
+
+// normalizeTags lowercases tags and collapses their whitespace, dropping
+// duplicates.
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
+
+const productListGenerationKey = "product:list:generation"
+
+// Listing pages are cached under a generation that InvalidateProductCache
+// bumps, since every page of every filter may change with one product. Pages
+// of an old generation are never read again and expire on their own.
+func (usecase *ProductUsecase) productListCacheKey(ctx context.Context, request product.ProductListRequest) string {
+	generation := usecase.cacheGeneration(ctx, productListGenerationKey)
+
+	requestJSON, _ := json.Marshal(request)
+	hash := fnv.New64a()
+	hash.Write(requestJSON)
+
+	return fmt.Sprintf("product:list:%s:%x", generation, hash.Sum64())
+}
+
+func encodeProductCursor(sort string, productResponse product.ProductResponse) string {
+	cursor := domain.ProductCursor{Sort: sort, Id: productResponse.Id}
+
+	switch sort {
+	case "price_asc", "price_desc":
+		cursor.Value = strconv.FormatFloat(productResponse.Price, 'f', -1, 64)
+	case "name_asc", "name_desc":
+		cursor.Value = productResponse.Name
+	default:
+		cursor.Value = productResponse.Created_at.UTC().Format(time.RFC3339Nano)
+	}
+
+	cursorJSON, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(cursorJSON)
+}
+
+func decodeProductCursor(value string) (domain.ProductCursor, error) {
+	cursor := domain.ProductCursor{}
+
+	cursorJSON, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, err
+	}
+
+	err = json.Unmarshal(cursorJSON, &cursor)
+	if err != nil {
+		return cursor, err
+	}
+
+	// the value is cast by Postgres, check it parses so a bad cursor is a bad
+	// request rather than a failed query
+	switch cursor.Sort {
+	case "price_asc", "price_desc":
+		_, err = strconv.ParseFloat(cursor.Value, 64)
+	case "created_at_asc", "created_at_desc":
+		_, err = time.Parse(time.RFC3339Nano, cursor.Value)
+	}
+
+	return cursor, err
+}
+
+// A product is cached under a generation of its own, so only its change
+// event moves it on.
+func productGenerationKey(productID int) string {
+	return fmt.Sprintf("product:%d:generation", productID)
+}
+
+func productCacheKey(productID int, generation string) string {
+	return fmt.Sprintf("product:%d:%s", productID, generation)
+}
+
+// cacheGeneration reads the generation a cache key is filled under. Readers
+// take it before they read the database, so a fill that raced with an
+// invalidation lands under a generation nobody reads anymore. A generation
+// that expired or was evicted starts a new one, never an old one again.
+func (usecase *ProductUsecase) cacheGeneration(ctx context.Context, generationKey string) string {
+	generation, ok := usecase.Cache.Get(ctx, generationKey)
+	if ok {
+		return string(generation)
+	}
+
+	return usecase.bumpCacheGeneration(ctx, generationKey)
+}
+
+func (usecase *ProductUsecase) bumpCacheGeneration(ctx context.Context, generationKey string) string {
+	generation := strconv.FormatInt(time.Now().UnixNano(), 10)
+	usecase.Cache.Set(ctx, generationKey, []byte(generation), 24*time.Hour)
+
+	return generation
+}
+
+func (usecase *ProductUsecase) readCache(ctx context.Context, key string, result interface{}) bool {
+	value, ok := usecase.Cache.Get(ctx, key)
+	if !ok {
+		return false
+	}
+
+	err := json.Unmarshal(value, result)
+	if err != nil {
+		usecase.Log.Warn().Err(err).Msg("failed to unmarshal cached " + key)
+		return false
+	}
+
+	return true
+}
+
+func (usecase *ProductUsecase) writeCache(ctx context.Context, key string, value interface{}) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		usecase.Log.Warn().Err(err).Msg("failed to marshal " + key + " for cache")
+		return
+	}
+
+	ttl := usecase.Koanf.Duration("CACHE_TTL")
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	usecase.Cache.Set(ctx, key, valueJSON, ttl)
+}
+
+// InvalidateProductCache moves the reads touched by a product change event to
+// a new generation, the entries of the old one expire on their own. TTL only
+// bounds staleness if an invalidation is missed.
+func (usecase *ProductUsecase) InvalidateProductCache(ctx context.Context, payload product.ProductCDCPayload) {
+	if payload.Before != nil {
+		usecase.bumpCacheGeneration(ctx, productGenerationKey(payload.Before.Id))
+	}
+	if payload.After != nil && (payload.Before == nil || payload.After.Id != payload.Before.Id) {
+		usecase.bumpCacheGeneration(ctx, productGenerationKey(payload.After.Id))
+	}
+
+	usecase.bumpCacheGeneration(ctx, productListGenerationKey)
+	cache.Metrics.Add("invalidations", 1)
 }
 
 func (usecase *ProductUsecase) FindProductHomePage(ctx context.Context) ([]product.ProductHomePageResponse, error) {
@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/product"
+	"testing"
+	"time"
+)
+
+func TestProductCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 8, 30, 15, 123456000, time.FixedZone("WIB", 7*60*60))
+
+	productResponse := product.ProductResponse{
+		Id:         42,
+		Name:       "Kemeja, \"Batik\"",
+		Price:      125000.5,
+		Created_at: &createdAt,
+	}
+
+	tests := []struct {
+		sort string
+		want domain.ProductCursor
+	}{
+		{sort: "price_asc", want: domain.ProductCursor{Sort: "price_asc", Value: "125000.5", Id: 42}},
+		{sort: "price_desc", want: domain.ProductCursor{Sort: "price_desc", Value: "125000.5", Id: 42}},
+		{sort: "name_asc", want: domain.ProductCursor{Sort: "name_asc", Value: "Kemeja, \"Batik\"", Id: 42}},
+		{sort: "name_desc", want: domain.ProductCursor{Sort: "name_desc", Value: "Kemeja, \"Batik\"", Id: 42}},
+		{sort: "created_at_asc", want: domain.ProductCursor{Sort: "created_at_asc", Value: "2024-03-01T01:30:15.123456Z", Id: 42}},
+		{sort: "created_at_desc", want: domain.ProductCursor{Sort: "created_at_desc", Value: "2024-03-01T01:30:15.123456Z", Id: 42}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.sort, func(t *testing.T) {
+			cursor, err := decodeProductCursor(encodeProductCursor(test.sort, productResponse))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if cursor != test.want {
+				t.Errorf("cursor = %+v, want %+v", cursor, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeProductCursorRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "not base64", value: "not a cursor!"},
+		{name: "padded base64", value: "eyJzIjoicHJpY2VfYXNjIn0="},
+		{name: "not json", value: "bm90IGpzb24"},
+		// {"s":"price_asc","v":"abc","i":1}
+		{name: "price that is not a number", value: "eyJzIjoicHJpY2VfYXNjIiwidiI6ImFiYyIsImkiOjF9"},
+		// {"s":"created_at_desc","v":"yesterday","i":1}
+		{name: "time that does not parse", value: "eyJzIjoiY3JlYXRlZF9hdF9kZXNjIiwidiI6Inllc3RlcmRheSIsImkiOjF9"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decodeProductCursor(test.value)
+			if err == nil {
+				t.Errorf("decodeProductCursor(%q) accepted a bad cursor", test.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/go-playground/validator"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/repository"
+	"strings"
+	"time"
+)
+
+type ProductVariantUsecase struct {
+	ProductRepository        *repository.ProductRepository
+	ProductVariantRepository *repository.ProductVariantRepository
+	ProductStatusUsecase     *ProductStatusUsecase
+	DB                       *sql.DB
+	Validator                *validator.Validate
+	Log                      *zerolog.Logger
+}
+
+func NewProductVariantUsecase(productRepository *repository.ProductRepository, productVariantRepository *repository.ProductVariantRepository, productStatusUsecase *ProductStatusUsecase, db *sql.DB, validator *validator.Validate, zerolog *zerolog.Logger) *ProductVariantUsecase {
+	return &ProductVariantUsecase{
+		ProductRepository:        productRepository,
+		ProductVariantRepository: productVariantRepository,
+		ProductStatusUsecase:     productStatusUsecase,
+		DB:                       db,
+		Validator:                validator,
+		Log:                      zerolog,
+	}
+}
+
+func (usecase *ProductVariantUsecase) Create(ctx context.Context, userUUID string, productID int, request product.ProductVariantCreateRequest) (product.ProductVariantResponse, error) {
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return product.ProductVariantResponse{}, respErr
+	}
+
+	changes := []domain.ProductStatusChange{}
+	defer usecase.ProductStatusUsecase.PublishCommitted(&changes)
+
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	err = usecase.ProductRepository.CheckOwnershipWithTx(ctx, tx, userUUID, productID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return product.ProductVariantResponse{}, err
+	}
+
+	now := time.Now()
+
+	variant := domain.ProductVariant{
+		Product_id: productID,
+		Sku:        strings.TrimSpace(request.Sku),
+		Size:       request.Size,
+		Attributes: normalizeAttributes(request.Attributes),
+		Price:      request.Price,
+		Weight:     request.Weight,
+		Quantity:   request.Quantity,
+		Created_at: &now,
+		Updated_at: &now,
+	}
+
+	err = usecase.checkUnique(ctx, tx, variant)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return product.ProductVariantResponse{}, err
+	}
+
+	variant.Id = usecase.ProductVariantRepository.CreateWithTx(ctx, tx, variant)
+	changes = usecase.ProductStatusUsecase.RecordStockChangeWithTx(ctx, tx, changes, usecase.ProductVariantRepository.SyncProductWithTx(ctx, tx, productID, &now))
+
+	return toProductVariantResponse(variant), nil
+}
+
+func (usecase *ProductVariantUsecase) Update(ctx context.Context, userUUID string, productID int, variantID int, request product.ProductVariantUpdateRequest) (product.ProductVariantResponse, error) {
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return product.ProductVariantResponse{}, respErr
+	}
+
+	changes := []domain.ProductStatusChange{}
+	defer usecase.ProductStatusUsecase.PublishCommitted(&changes)
+
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	err = usecase.ProductRepository.CheckOwnershipWithTx(ctx, tx, userUUID, productID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return product.ProductVariantResponse{}, err
+	}
+
+	variant, err := usecase.ProductVariantRepository.FindByIdWithTx(ctx, tx, productID, variantID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return product.ProductVariantResponse{}, err
+	}
+
+	if request.Sku != "" {
+		variant.Sku = strings.TrimSpace(request.Sku)
+	}
+	if request.Size != "" {
+		variant.Size = request.Size
+	}
+	if request.Attributes != nil {
+		variant.Attributes = normalizeAttributes(request.Attributes)
+	}
+	if request.Price != 0 {
+		variant.Price = request.Price
+	}
+	if request.Weight != 0 {
+		variant.Weight = request.Weight
+	}
+	if request.Quantity != nil {
+		variant.Quantity = *request.Quantity
+	}
+
+	err = usecase.checkUnique(ctx, tx, variant)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return product.ProductVariantResponse{}, err
+	}
+
+	now := time.Now()
+	variant.Updated_at = &now
+
+	usecase.ProductVariantRepository.UpdateWithTx(ctx, tx, variant)
+	changes = usecase.ProductStatusUsecase.RecordStockChangeWithTx(ctx, tx, changes, usecase.ProductVariantRepository.SyncProductWithTx(ctx, tx, productID, &now))
+
+	return toProductVariantResponse(variant), nil
+}
+
+func (usecase *ProductVariantUsecase) Delete(ctx context.Context, userUUID string, productID int, variantID int) error {
+	changes := []domain.ProductStatusChange{}
+	defer usecase.ProductStatusUsecase.PublishCommitted(&changes)
+
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	err = usecase.ProductRepository.CheckOwnershipWithTx(ctx, tx, userUUID, productID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return err
+	}
+
+	_, err = usecase.ProductVariantRepository.FindByIdWithTx(ctx, tx, productID, variantID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return err
+	}
+
+	now := time.Now()
+
+	usecase.ProductVariantRepository.DeleteWithTx(ctx, tx, variantID)
+	changes = usecase.ProductStatusUsecase.RecordStockChangeWithTx(ctx, tx, changes, usecase.ProductVariantRepository.SyncProductWithTx(ctx, tx, productID, &now))
+
+	return nil
+}
+
+func (usecase *ProductVariantUsecase) FindByProductId(ctx context.Context, productID int) []product.ProductVariantResponse {
+	return toProductVariantResponses(usecase.ProductVariantRepository.FindByProductId(ctx, productID))
+}
+
+// checkUnique runs before any write, a transaction that returns an error is
+// still committed.
+func (usecase *ProductVariantUsecase) checkUnique(ctx context.Context, tx *sql.Tx, variant domain.ProductVariant) error {
+	if usecase.ProductVariantRepository.ExistsBySkuWithTx(ctx, tx, variant.Sku, variant.Id) {
+		return errors.New("sku already exists")
+	}
+
+	if usecase.ProductVariantRepository.ExistsByOptionsWithTx(ctx, tx, variant) {
+		return errors.New("variant already exists")
+	}
+
+	return nil
+}
+
+// normalizeAttributes lowercases attribute names so {"Color": "Red"} and
+// {"color": "Red"} are the same combination. Values keep their case.
+func normalizeAttributes(attributes map[string]string) map[string]string {
+	normalized := map[string]string{}
+
+	for name, value := range attributes {
+		normalized[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+
+	return normalized
+}
+
+func toProductVariantResponse(variant domain.ProductVariant) product.ProductVariantResponse {
+	return product.ProductVariantResponse{
+		Id:         variant.Id,
+		Product_id: variant.Product_id,
+		Sku:        variant.Sku,
+		Size:       variant.Size,
+		Attributes: variant.Attributes,
+		Price:      variant.Price,
+		Weight:     variant.Weight,
+		Quantity:   variant.Quantity,
+		Created_at: variant.Created_at,
+		Updated_at: variant.Updated_at,
+	}
+}
+
+func toProductVariantResponses(variants []domain.ProductVariant) []product.ProductVariantResponse {
+	variantResponses := []product.ProductVariantResponse{}
+
+	for _, variant := range variants {
+		variantResponses = append(variantResponses, toProductVariantResponse(variant))
+	}
+
+	return variantResponses
+}
@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/IBM/sarama"
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/repository"
+	"strings"
+	"time"
+)
+
+var searchAnalyticsPeriods = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// SearchAnalyticsUsecase records searches without slowing them down. Events
+// are queued in memory and published to product.search by RunPublisher, a full
+// queue drops events rather than blocking the search.
+type SearchAnalyticsUsecase struct {
+	SearchAnalyticsRepository *repository.SearchAnalyticsRepository
+	KafkaWriter               sarama.SyncProducer
+	Log                       *zerolog.Logger
+	Koanf                     *koanf.Koanf
+	events                    chan product.ProductSearchEvent
+}
+
+func NewSearchAnalyticsUsecase(searchAnalyticsRepository *repository.SearchAnalyticsRepository, kafkaWriter sarama.SyncProducer, zerolog *zerolog.Logger, koanf *koanf.Koanf) *SearchAnalyticsUsecase {
+	queueSize := koanf.Int("SEARCH_ANALYTICS_QUEUE_SIZE")
+	if queueSize <= 0 {
+		queueSize = 1000
+	}
+
+	return &SearchAnalyticsUsecase{
+		SearchAnalyticsRepository: searchAnalyticsRepository,
+		KafkaWriter:               kafkaWriter,
+		Log:                       zerolog,
+		Koanf:                     koanf,
+		events:                    make(chan product.ProductSearchEvent, queueSize),
+	}
+}
+
+func (usecase *SearchAnalyticsUsecase) Record(event product.ProductSearchEvent) {
+	select {
+	case usecase.events <- event:
+	default:
+		searchMetrics.Add("analytics_dropped", 1)
+	}
+}
+
+func (usecase *SearchAnalyticsUsecase) RunPublisher(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-usecase.events:
+			usecase.publish(event)
+		}
+	}
+}
+
+func (usecase *SearchAnalyticsUsecase) publish(event product.ProductSearchEvent) {
+	messageJSON, err := json.Marshal(event)
+	if err != nil {
+		usecase.Log.Warn().Err(err).Msg("failed to marshal a json")
+		return
+	}
+
+	_, _, err = usecase.KafkaWriter.SendMessage(&sarama.ProducerMessage{
+		Topic: "product.search",
+		Key:   sarama.StringEncoder(normalizeSearchQuery(event.Query)),
+		Value: sarama.ByteEncoder(messageJSON),
+	})
+
+	if err != nil {
+		usecase.Log.Warn().Err(err).Msg("failed to produce an event to kafka broker")
+	}
+}
+
+// HandleSearchEvent adds a product.search event to the hourly query totals.
+// Browsing without a query is not a query worth reporting.
+func (usecase *SearchAnalyticsUsecase) HandleSearchEvent(ctx context.Context, event product.ProductSearchEvent) {
+	query := normalizeSearchQuery(event.Query)
+	if query == "" {
+		return
+	}
+
+	createdAt := time.Now()
+	if event.Created_at != nil {
+		createdAt = *event.Created_at
+	}
+
+	bucket := createdAt.UTC().Truncate(time.Hour)
+
+	stat := domain.SearchQueryStat{
+		Bucket:           &bucket,
+		Query:            query,
+		Searches:         1,
+		Total_results:    event.Result_count,
+		Total_latency_ms: event.Latency_ms,
+	}
+
+	if event.Result_count == 0 {
+		stat.Zero_results = 1
+	}
+
+	usecase.SearchAnalyticsRepository.Add(ctx, stat)
+}
+
+func (usecase *SearchAnalyticsUsecase) FindReport(ctx context.Context, period string, limit int) (product.SearchAnalyticsResponse, error) {
+	if period == "" {
+		period = "week"
+	}
+
+	length, ok := searchAnalyticsPeriods[period]
+	if !ok || limit < 1 || limit > 100 {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Msg(respErr.Error())
+		return product.SearchAnalyticsResponse{}, respErr
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-length)
+
+	return product.SearchAnalyticsResponse{
+		Period:              period,
+		From:                &from,
+		To:                  &to,
+		Top_queries:         toSearchQueryStatistics(usecase.SearchAnalyticsRepository.FindTopQueries(ctx, from, to, limit)),
+		Zero_result_queries: toSearchQueryStatistics(usecase.SearchAnalyticsRepository.FindZeroResultQueries(ctx, from, to, limit)),
+	}, nil
+}
+
+func toSearchQueryStatistics(stats []domain.SearchQueryStat) []product.SearchQueryStatistic {
+	statistics := []product.SearchQueryStatistic{}
+	for _, stat := range stats {
+		statistic := product.SearchQueryStatistic{
+			Query:        stat.Query,
+			Searches:     stat.Searches,
+			Zero_results: stat.Zero_results,
+		}
+
+		if stat.Searches > 0 {
+			statistic.Avg_results = float64(stat.Total_results) / float64(stat.Searches)
+			statistic.Avg_latency_ms = float64(stat.Total_latency_ms) / float64(stat.Searches)
+		}
+
+		statistics = append(statistics, statistic)
+	}
+
+	return statistics
+}
+
+// normalizeSearchQuery folds case and whitespace so "Kaos  Polos" and
+// "kaos polos" count as the same query.
+func normalizeSearchQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
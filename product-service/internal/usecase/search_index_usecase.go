@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/repository"
+	"sort"
+)
+
+// SearchIndexUsecase applies the index definitions under repository/mapping.
+// Search always goes through an alias, the concrete index is <alias>_v<n>.
+type SearchIndexUsecase struct {
+	SearchIndexRepository *repository.SearchIndexRepository
+	Log                   *zerolog.Logger
+	Koanf                 *koanf.Koanf
+}
+
+func NewSearchIndexUsecase(searchIndexRepository *repository.SearchIndexRepository, zerolog *zerolog.Logger, koanf *koanf.Koanf) *SearchIndexUsecase {
+	return &SearchIndexUsecase{
+		SearchIndexRepository: searchIndexRepository,
+		Log:                   zerolog,
+		Koanf:                 koanf,
+	}
+}
+
+// EnsureIndex runs at startup before any consumer writes to alias. A live index
+// with an older version or a conflicting mapping stops the service, unless
+// ELASTICSEARCH_REINDEX is set, then it is rebuilt into a new index and the
+// alias is swapped over. Elasticsearch being unreachable is not fatal.
+func (usecase *SearchIndexUsecase) EnsureIndex(ctx context.Context, definitionName string, alias string) {
+	definition := usecase.SearchIndexRepository.FindDefinition(definitionName)
+	index := fmt.Sprintf("%s_v%d", alias, definition.Version)
+
+	live, err := usecase.SearchIndexRepository.FindByAlias(ctx, alias)
+	if err != nil {
+		if err.Error() != "search index not found" {
+			usecase.Log.Warn().Err(err).Msg("failed to read search index " + alias)
+			return
+		}
+
+		err = usecase.SearchIndexRepository.Create(ctx, index, alias, definition)
+		if err != nil {
+			usecase.Log.Warn().Err(err).Msg("failed to create search index " + index)
+			return
+		}
+
+		usecase.Log.Info().Msg("Created search index " + index)
+		return
+	}
+
+	conflicts := mappingConflicts(definition.Properties, live.Properties, "")
+
+	if live.Version > definition.Version && len(conflicts) == 0 {
+		usecase.Log.Warn().Msg(fmt.Sprintf("search index %s is at version %d, ahead of mapping version %d", live.Name, live.Version, definition.Version))
+		return
+	}
+
+	if live.Version == definition.Version && len(conflicts) == 0 {
+		return
+	}
+
+	if !usecase.Koanf.Bool("ELASTICSEARCH_REINDEX") {
+		usecase.Log.Fatal().Strs("conflicts", conflicts).Msg(fmt.Sprintf("search index %s (version %d) is incompatible with mapping version %d, set ELASTICSEARCH_REINDEX=true to rebuild it", live.Name, live.Version, definition.Version))
+	}
+
+	if live.Name == index {
+		usecase.Log.Fatal().Strs("conflicts", conflicts).Msg(fmt.Sprintf("search index %s was changed by hand, bump the mapping version to rebuild it", live.Name))
+	}
+
+	usecase.Log.Info().Msg(fmt.Sprintf("Reindexing %s into %s", live.Name, index))
+
+	err = usecase.SearchIndexRepository.Create(ctx, index, "", definition)
+	if err != nil {
+		usecase.Log.Fatal().Err(err).Msg("failed to create search index " + index)
+	}
+
+	// the alias stays on the live index unless every document was copied, a
+	// partial copy is dropped so the next start can try again
+	err = usecase.SearchIndexRepository.Reindex(ctx, live.Name, index)
+	if err != nil {
+		deleteErr := usecase.SearchIndexRepository.Delete(ctx, index)
+		if deleteErr != nil {
+			usecase.Log.Error().Err(deleteErr).Msg("failed to drop incomplete search index " + index)
+		}
+
+		usecase.Log.Fatal().Err(err).Msg("failed to reindex " + live.Name)
+	}
+
+	err = usecase.SearchIndexRepository.SwapAlias(ctx, alias, live.Name, index)
+	if err != nil {
+		usecase.Log.Fatal().Err(err).Msg("failed to point " + alias + " at " + index)
+	}
+
+	usecase.Log.Info().Msg(fmt.Sprintf("Search alias %s now points at %s", alias, index))
+}
+
+// mappingConflicts lists fields whose live type or analyzer differs from the
+// definition. Attributes the definition leaves to Elasticsearch defaults and
+// fields only present in the live index do not conflict.
+func mappingConflicts(desired map[string]interface{}, live map[string]interface{}, path string) []string {
+	conflicts := []string{}
+
+	names := []string{}
+	for name := range desired {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		desiredField, _ := desired[name].(map[string]interface{})
+		liveField, ok := live[name].(map[string]interface{})
+		if !ok {
+			conflicts = append(conflicts, path+name+" is missing")
+			continue
+		}
+
+		for _, attribute := range []string{"type", "analyzer", "search_analyzer", "scaling_factor"} {
+			desiredValue, ok := desiredField[attribute]
+			if !ok {
+				continue
+			}
+
+			if fmt.Sprint(desiredValue) != fmt.Sprint(liveField[attribute]) {
+				conflicts = append(conflicts, fmt.Sprintf("%s%s.%s is %v, want %v", path, name, attribute, liveField[attribute], desiredValue))
+			}
+		}
+
+		for _, nested := range []string{"properties", "fields"} {
+			desiredNested, ok := desiredField[nested].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			liveNested, _ := liveField[nested].(map[string]interface{})
+			conflicts = append(conflicts, mappingConflicts(desiredNested, liveNested, path+name+".")...)
+		}
+	}
+
+	return conflicts
+}
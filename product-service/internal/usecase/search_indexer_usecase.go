@@ -0,0 +1,311 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/repository"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const productCDCTopic = "dbz.public.products"
+
+// SearchIndexerUsecase writes product changes to the product index in bulk.
+// Actions are queued by the CDC consumer and flushed by Run when enough
+// documents or bytes are waiting, or the flush interval passes. While
+// Elasticsearch pushes back the partitions of the indexer's consumer group are
+// paused, so Kafka keeps the backlog instead of this process. The offset of a
+// change event is only committed once its action is indexed or dead-lettered,
+// so actions still queued when the process stops are replayed.
+type SearchIndexerUsecase struct {
+	ProductSearchRepository *repository.ProductSearchRepository
+	CategoryRepository      *repository.CategoryRepository
+	TagRepository           *repository.TagRepository
+	CDCMonitorUsecase       *CDCMonitorUsecase
+	KafkaWriter             sarama.SyncProducer
+	ConsumerGroup           sarama.ConsumerGroup
+	Log                     *zerolog.Logger
+	Koanf                   *koanf.Koanf
+	actions                 chan domain.SearchIndexAction
+	mutex                   sync.Mutex
+	paused                  bool
+}
+
+func NewSearchIndexerUsecase(productSearchRepository *repository.ProductSearchRepository, categoryRepository *repository.CategoryRepository, tagRepository *repository.TagRepository, cdcMonitorUsecase *CDCMonitorUsecase, kafkaWriter sarama.SyncProducer, consumerGroup sarama.ConsumerGroup, zerolog *zerolog.Logger, koanf *koanf.Koanf) *SearchIndexerUsecase {
+	queueSize := koanf.Int("SEARCH_BULK_QUEUE_SIZE")
+	if queueSize <= 0 {
+		queueSize = 5000
+	}
+
+	return &SearchIndexerUsecase{
+		ProductSearchRepository: productSearchRepository,
+		CategoryRepository:      categoryRepository,
+		TagRepository:           tagRepository,
+		CDCMonitorUsecase:       cdcMonitorUsecase,
+		KafkaWriter:             kafkaWriter,
+		ConsumerGroup:           consumerGroup,
+		Log:                     zerolog,
+		Koanf:                   koanf,
+		actions:                 make(chan domain.SearchIndexAction, queueSize),
+	}
+}
+
+// HandleProductChange queues the index write for a change event. Draft,
+// Archived and deleted products are removed so they never show up in search. Category path and tags
+// live in their own tables and are read when the document is built. A full
+// queue blocks the caller, which is the last line of backpressure. ack is
+// called once the write has landed.
+func (usecase *SearchIndexerUsecase) HandleProductChange(ctx context.Context, payload product.ProductCDCPayload, ack func()) {
+	action := domain.SearchIndexAction{
+		Version:      payload.Source.Lsn,
+		Source_ts_ms: payload.Source.Ts_ms,
+		Ack:          ack,
+	}
+
+	switch {
+	case payload.Op == "d" && payload.Before != nil:
+		action.Operation = "delete"
+		action.Product_id = payload.Before.Id
+	case payload.After == nil:
+		ack()
+		return
+	case payload.After.Deleted_at != nil || payload.After.Status == "Draft" || payload.After.Status == "Archived":
+		action.Operation = "delete"
+		action.Product_id = payload.After.Id
+	default:
+		document := toProductDocument(payload.After)
+		document.Tags = usecase.TagRepository.FindByProductId(ctx, document.Id)
+
+		if document.Category_id != nil {
+			for _, category := range usecase.CategoryRepository.FindPath(ctx, *document.Category_id) {
+				document.Category_ids = append(document.Category_ids, category.Id)
+				document.Category_path = append(document.Category_path, category.Name)
+			}
+		}
+
+		body, err := json.Marshal(document)
+		if err != nil {
+			respErr := errors.New("failed to marshal a json")
+			usecase.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		action.Operation = "index"
+		action.Product_id = payload.After.Id
+		action.Body = body
+	}
+
+	if len(usecase.actions) >= cap(usecase.actions)*3/4 {
+		usecase.setPaused(true, "bulk indexer queue is filling up")
+	}
+
+	select {
+	case usecase.actions <- action:
+	case <-ctx.Done():
+	}
+}
+
+func (usecase *SearchIndexerUsecase) Run(ctx context.Context) {
+	flushDocuments := usecase.Koanf.Int("SEARCH_BULK_FLUSH_DOCUMENTS")
+	if flushDocuments <= 0 {
+		flushDocuments = 500
+	}
+
+	flushBytes := usecase.Koanf.Int("SEARCH_BULK_FLUSH_BYTES")
+	if flushBytes <= 0 {
+		flushBytes = 5 << 20
+	}
+
+	flushInterval := usecase.Koanf.Duration("SEARCH_BULK_FLUSH_INTERVAL")
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := []domain.SearchIndexAction{}
+	batchBytes := 0
+
+	flush := func() {
+		if len(batch) > 0 {
+			usecase.flush(ctx, batch)
+		}
+
+		batch = []domain.SearchIndexAction{}
+		batchBytes = 0
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			flush()
+			return
+		case action := <-usecase.actions:
+			batch = append(batch, action)
+			batchBytes += len(action.Body) + 128
+
+			if len(batch) >= flushDocuments || batchBytes >= flushBytes {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// flush sends a batch until every action is either indexed or dead-lettered.
+// A request Elasticsearch refuses as a whole (429, 503 or unreachable) is
+// retried with backoff for as long as it takes, with the CDC partitions paused.
+// Throttled items are retried up to SEARCH_BULK_MAX_RETRIES, other item
+// failures go to product.index.dlq right away.
+func (usecase *SearchIndexerUsecase) flush(ctx context.Context, batch []domain.SearchIndexAction) {
+	maxRetries := usecase.Koanf.Int("SEARCH_BULK_MAX_RETRIES")
+	if maxRetries <= 0 {
+		maxRetries = 5
+	}
+
+	oldestTsMs := int64(0)
+	for _, action := range batch {
+		if oldestTsMs == 0 || (action.Source_ts_ms > 0 && action.Source_ts_ms < oldestTsMs) {
+			oldestTsMs = action.Source_ts_ms
+		}
+	}
+
+	for attempt := 0; len(batch) > 0; attempt++ {
+		if attempt > 0 {
+			searchMetrics.Add("bulk_retries", 1)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(bulkBackoff(attempt)):
+			}
+		}
+
+		items, status, err := usecase.ProductSearchRepository.Bulk(ctx, batch)
+		if err != nil {
+			if status == 0 || status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+				usecase.setPaused(true, err.Error())
+				continue
+			}
+
+			for _, action := range batch {
+				usecase.deadLetter(action, status, err.Error())
+			}
+
+			break
+		}
+
+		searchMetrics.Add("bulk_flushes", 1)
+
+		retry := []domain.SearchIndexAction{}
+
+		for i, item := range items {
+			action := batch[i]
+			action.Attempts++
+
+			switch {
+			case item.Status < 300, item.Status == http.StatusConflict, action.Operation == "delete" && item.Status == http.StatusNotFound:
+				// a version conflict means a newer write is already indexed
+				searchMetrics.Add("bulk_indexed", 1)
+				action.Ack()
+			case (item.Status == http.StatusTooManyRequests || item.Status == http.StatusServiceUnavailable) && action.Attempts < maxRetries:
+				retry = append(retry, action)
+			default:
+				usecase.deadLetter(action, item.Status, item.Error)
+			}
+		}
+
+		batch = retry
+	}
+
+	usecase.CDCMonitorUsecase.ObserveEvent(oldestTsMs)
+
+	if len(usecase.actions) < cap(usecase.actions)/4 {
+		usecase.setPaused(false, "")
+	}
+}
+
+// deadLetter acks the action once it is in product.index.dlq. An action that
+// could not be produced stays unacked, it is replayed after a restart.
+func (usecase *SearchIndexerUsecase) deadLetter(action domain.SearchIndexAction, status int, reason string) {
+	searchMetrics.Add("bulk_dead_lettered", 1)
+
+	now := time.Now()
+	failureEvent := product.ProductIndexFailureEvent{
+		Product_id: action.Product_id,
+		Operation:  action.Operation,
+		Version:    action.Version,
+		Status:     status,
+		Error:      reason,
+		Attempts:   action.Attempts,
+		Document:   action.Body,
+		Failed_at:  &now,
+	}
+
+	usecase.Log.Warn().Int("product_id", action.Product_id).Int("status", status).Msg("product index write dead-lettered: " + reason)
+
+	messageJSON, err := json.Marshal(failureEvent)
+	if err != nil {
+		usecase.Log.Error().Err(err).Msg("failed to marshal a json")
+		return
+	}
+
+	_, _, err = usecase.KafkaWriter.SendMessage(&sarama.ProducerMessage{
+		Topic: "product.index.dlq",
+		Key:   sarama.StringEncoder(fmt.Sprint(action.Product_id)),
+		Value: sarama.ByteEncoder(messageJSON),
+	})
+
+	if err != nil {
+		usecase.Log.Error().Err(err).Msg("failed to produce an event to kafka broker")
+		return
+	}
+
+	action.Ack()
+}
+
+// setPaused pauses or resumes the indexer's consumer group on a change of
+// state. The other projections of the CDC topic keep their own pace.
+func (usecase *SearchIndexerUsecase) setPaused(paused bool, reason string) {
+	usecase.mutex.Lock()
+	defer usecase.mutex.Unlock()
+
+	if usecase.paused == paused {
+		return
+	}
+
+	if paused {
+		usecase.ConsumerGroup.PauseAll()
+		searchBulkPaused.Set(1)
+		usecase.Log.Warn().Msg("Paused " + productCDCTopic + ": " + reason)
+	} else {
+		usecase.ConsumerGroup.ResumeAll()
+		searchBulkPaused.Set(0)
+		usecase.Log.Info().Msg("Resumed " + productCDCTopic)
+	}
+
+	usecase.paused = paused
+}
+
+// bulkBackoff waits 500ms before the first retry, doubling up to 30s.
+func bulkBackoff(attempt int) time.Duration {
+	backoff := 500 * time.Millisecond
+	for i := 1; i < attempt && backoff < 30*time.Second; i++ {
+		backoff *= 2
+	}
+
+	if backoff > 30*time.Second {
+		backoff = 30 * time.Second
+	}
+
+	return backoff
+}
@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/repository"
+	"time"
+)
+
+type SellerStatsUsecase struct {
+	SellerStatsRepository *repository.SellerStatsRepository
+	DB                    *sql.DB
+	Log                   *zerolog.Logger
+}
+
+func NewSellerStatsUsecase(sellerStatsRepository *repository.SellerStatsRepository, db *sql.DB, zerolog *zerolog.Logger) *SellerStatsUsecase {
+	return &SellerStatsUsecase{
+		SellerStatsRepository: sellerStatsRepository,
+		DB:                    db,
+		Log:                   zerolog,
+	}
+}
+
+// HandleProductChange moves the seller_stats projection from the product's
+// previously applied state to the state in the change event. Events with an
+// LSN that was already applied are ignored, so redelivery is harmless. Rows
+// seeded from products when the projection was introduced carry LSN 0, any
+// change event replaces them.
+func (usecase *SellerStatsUsecase) HandleProductChange(ctx context.Context, payload product.ProductCDCPayload) {
+	row := payload.After
+	if payload.Op == "d" {
+		row = payload.Before
+	}
+
+	if row == nil {
+		return
+	}
+
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	previous, err := usecase.SellerStatsRepository.FindProductWithTx(ctx, tx, row.Id)
+	if err == nil && previous.Source_lsn >= payload.Source.Lsn {
+		return
+	}
+
+	current := domain.SellerStatsProduct{
+		Product_id: row.Id,
+		Seller_id:  row.Seller_id,
+		Quantity:   row.Quantity,
+		Price:      row.Price,
+		Status:     row.Status,
+		Source_lsn: payload.Source.Lsn,
+	}
+
+	// soft deleted products keep their row for the LSN check but count for
+	// nothing, the same as purged ones
+	if payload.Op == "d" || row.Deleted_at != nil {
+		current.Status = "Deleted"
+	}
+
+	updatedAt := time.UnixMilli(payload.Source.Ts_ms)
+
+	delta := contribution(current)
+	delta.Seller_id = row.Seller_id
+	delta.Last_updated_at = &updatedAt
+
+	if previous.Seller_id != "" {
+		previousContribution := contribution(previous)
+		delta.Active_product -= previousContribution.Active_product
+		delta.Sold_out_product -= previousContribution.Sold_out_product
+		delta.Total_product -= previousContribution.Total_product
+		delta.Inventory_value -= previousContribution.Inventory_value
+	}
+
+	if payload.Op == "d" {
+		usecase.SellerStatsRepository.DeleteProductWithTx(ctx, tx, row.Id, row.Seller_id, payload.Source.Lsn)
+	} else {
+		usecase.SellerStatsRepository.UpsertProductWithTx(ctx, tx, current)
+	}
+
+	usecase.SellerStatsRepository.ApplyDeltaWithTx(ctx, tx, delta)
+}
+
+// contribution is what a product adds to its seller's stats. Active and sold
+// out follow the product status, Draft and Archived products only count
+// towards the total.
+func contribution(statsProduct domain.SellerStatsProduct) domain.SellerStats {
+	stats := domain.SellerStats{}
+
+	if statsProduct.Status == "Deleted" {
+		return stats
+	}
+
+	stats.Total_product = 1
+
+	switch statsProduct.Status {
+	case "Ready":
+		stats.Active_product = 1
+	case "SoldOut":
+		stats.Sold_out_product = 1
+	default:
+		return stats
+	}
+
+	stats.Inventory_value = float64(statsProduct.Quantity) * statsProduct.Price
+
+	return stats
+}
+
+func (usecase *SellerStatsUsecase) FindSellerStats(ctx context.Context, sellerID string) (product.SellerStatsResponse, error) {
+	stats, err := usecase.SellerStatsRepository.FindBySellerId(ctx, sellerID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return stats, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,410 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/go-playground/validator"
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/repository"
+	"strconv"
+	"time"
+)
+
+type StockReservationUsecase struct {
+	ProductRepository          *repository.ProductRepository
+	ProductVariantRepository   *repository.ProductVariantRepository
+	StockReservationRepository *repository.StockReservationRepository
+	ProductStatusRepository    *repository.ProductStatusRepository
+	ProductStatusUsecase       *ProductStatusUsecase
+	KafkaWriter                sarama.SyncProducer
+	DB                         *sql.DB
+	Validator                  *validator.Validate
+	Log                        *zerolog.Logger
+	Koanf                      *koanf.Koanf
+}
+
+func NewStockReservationUsecase(productRepository *repository.ProductRepository, productVariantRepository *repository.ProductVariantRepository, stockReservationRepository *repository.StockReservationRepository, productStatusRepository *repository.ProductStatusRepository, productStatusUsecase *ProductStatusUsecase, kafkaWriter sarama.SyncProducer, db *sql.DB, validator *validator.Validate, zerolog *zerolog.Logger, koanf *koanf.Koanf) *StockReservationUsecase {
+	return &StockReservationUsecase{
+		ProductRepository:          productRepository,
+		ProductVariantRepository:   productVariantRepository,
+		StockReservationRepository: stockReservationRepository,
+		ProductStatusRepository:    productStatusRepository,
+		ProductStatusUsecase:       productStatusUsecase,
+		KafkaWriter:                kafkaWriter,
+		DB:                         db,
+		Validator:                  validator,
+		Log:                        zerolog,
+		Koanf:                      koanf,
+	}
+}
+
+// Reserve takes units out of the available stock for buyerUUID until the
+// reservation is committed, released or expires. Sending the same reservation
+// id again returns the reservation made the first time.
+func (usecase *StockReservationUsecase) Reserve(ctx context.Context, buyerUUID string, productID int, request product.StockReservationRequest) (product.StockReservationResponse, error) {
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return product.StockReservationResponse{}, respErr
+	}
+
+	var variantID *int
+	if request.Variant_id > 0 {
+		variantID = &request.Variant_id
+	} else if len(usecase.ProductVariantRepository.FindByProductId(ctx, productID)) > 0 {
+		respErr := errors.New("variant_id is required for a product with variants")
+		usecase.Log.Warn().Msg(respErr.Error())
+		return product.StockReservationResponse{}, respErr
+	}
+
+	ttl := time.Duration(request.Ttl_seconds) * time.Second
+	if ttl <= 0 {
+		ttl = usecase.Koanf.Duration("STOCK_RESERVATION_TTL")
+	}
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+
+	reservation, created, changes, err := usecase.reserve(ctx, buyerUUID, productID, variantID, request, ttl)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return product.StockReservationResponse{}, err
+	}
+
+	if created {
+		usecase.publish(reservation, "StockReserved", -reservation.Quantity)
+		usecase.ProductStatusUsecase.PublishChanges(changes)
+	}
+
+	return toStockReservationResponse(reservation), nil
+}
+
+func (usecase *StockReservationUsecase) reserve(ctx context.Context, buyerUUID string, productID int, variantID *int, request product.StockReservationRequest, ttl time.Duration) (domain.StockReservation, bool, []domain.ProductStatusChange, error) {
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	usecase.StockReservationRepository.LockIdWithTx(ctx, tx, request.Reservation_id)
+
+	reservation, err := usecase.StockReservationRepository.FindByIdWithTx(ctx, tx, request.Reservation_id)
+	if err == nil {
+		if reservation.Buyer_id != buyerUUID || reservation.Product_id != productID || !sameVariant(reservation.Variant_id, variantID) || reservation.Quantity != request.Quantity {
+			return reservation, false, nil, errors.New("reservation id is already used")
+		}
+
+		return reservation, false, nil, nil
+	}
+
+	if variantID != nil {
+		_, err = usecase.ProductVariantRepository.FindByIdWithTx(ctx, tx, productID, *variantID)
+		if err != nil {
+			return reservation, false, nil, err
+		}
+	}
+
+	// the product is locked after its variant, in the order every variant
+	// change locks the two, so its status and deletion hold until the stock
+	// is taken
+	productRow, err := usecase.ProductStatusRepository.FindProductWithTx(ctx, tx, productID)
+	if err != nil || productRow.Status == "Draft" || productRow.Status == "Archived" {
+		return reservation, false, nil, errors.New("product not found")
+	}
+
+	now := time.Now()
+
+	// nothing is written before this point, a transaction that returns an
+	// error is still committed
+	change, taken := usecase.StockReservationRepository.TakeStockWithTx(ctx, tx, productID, variantID, request.Quantity, &now)
+	if !taken {
+		return reservation, false, nil, errors.New("insufficient stock")
+	}
+
+	changes := usecase.ProductStatusUsecase.RecordStockChangeWithTx(ctx, tx, nil, change)
+
+	if variantID != nil {
+		changes = usecase.ProductStatusUsecase.RecordStockChangeWithTx(ctx, tx, changes, usecase.ProductVariantRepository.SyncProductWithTx(ctx, tx, productID, &now))
+	}
+
+	expiresAt := now.Add(ttl)
+
+	reservation = domain.StockReservation{
+		Id:         request.Reservation_id,
+		Product_id: productID,
+		Variant_id: variantID,
+		Buyer_id:   buyerUUID,
+		Quantity:   request.Quantity,
+		Status:     "Reserved",
+		Expires_at: &expiresAt,
+		Created_at: &now,
+		Updated_at: &now,
+	}
+
+	usecase.StockReservationRepository.CreateWithTx(ctx, tx, reservation)
+
+	return reservation, true, changes, nil
+}
+
+// Commit keeps the reserved units out of stock for good. Committing twice
+// is a no-op, committing after the hold ran out expires the reservation.
+func (usecase *StockReservationUsecase) Commit(ctx context.Context, buyerUUID string, reservationID string) (product.StockReservationResponse, error) {
+	reservation, event, changes, err := usecase.commit(ctx, buyerUUID, reservationID)
+
+	// an expired reservation returns its units in a committed transaction
+	usecase.ProductStatusUsecase.PublishChanges(changes)
+
+	switch event {
+	case "StockCommitted":
+		usecase.publish(reservation, event, 0)
+	case "StockExpired":
+		usecase.publish(reservation, event, reservation.Quantity)
+	}
+
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return product.StockReservationResponse{}, err
+	}
+
+	return toStockReservationResponse(reservation), nil
+}
+
+func (usecase *StockReservationUsecase) commit(ctx context.Context, buyerUUID string, reservationID string) (domain.StockReservation, string, []domain.ProductStatusChange, error) {
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	reservation, err := usecase.StockReservationRepository.FindByIdWithTx(ctx, tx, reservationID)
+	if err != nil || reservation.Buyer_id != buyerUUID {
+		return reservation, "", nil, errors.New("reservation not found")
+	}
+
+	switch reservation.Status {
+	case "Committed":
+		return reservation, "", nil, nil
+	case "Released", "Expired":
+		return reservation, "", nil, errors.New("reservation is no longer active")
+	}
+
+	now := time.Now()
+
+	if !reservation.Expires_at.After(now) {
+		changes := usecase.returnStock(ctx, tx, &reservation, "Expired", &now)
+		return reservation, "StockExpired", changes, errors.New("reservation is no longer active")
+	}
+
+	reservation.Status = "Committed"
+	reservation.Updated_at = &now
+	usecase.StockReservationRepository.UpdateStatusWithTx(ctx, tx, reservation.Id, reservation.Status, &now)
+
+	return reservation, "StockCommitted", nil, nil
+}
+
+// Release puts the reserved units back. Releasing twice, or after the
+// reservation expired, is a no-op.
+func (usecase *StockReservationUsecase) Release(ctx context.Context, buyerUUID string, reservationID string) (product.StockReservationResponse, error) {
+	reservation, released, changes, err := usecase.release(ctx, buyerUUID, reservationID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return product.StockReservationResponse{}, err
+	}
+
+	if released {
+		usecase.publish(reservation, "StockReleased", reservation.Quantity)
+		usecase.ProductStatusUsecase.PublishChanges(changes)
+	}
+
+	return toStockReservationResponse(reservation), nil
+}
+
+func (usecase *StockReservationUsecase) release(ctx context.Context, buyerUUID string, reservationID string) (domain.StockReservation, bool, []domain.ProductStatusChange, error) {
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	reservation, err := usecase.StockReservationRepository.FindByIdWithTx(ctx, tx, reservationID)
+	if err != nil || reservation.Buyer_id != buyerUUID {
+		return reservation, false, nil, errors.New("reservation not found")
+	}
+
+	switch reservation.Status {
+	case "Released", "Expired":
+		return reservation, false, nil, nil
+	case "Committed":
+		return reservation, false, nil, errors.New("reservation is already committed")
+	}
+
+	now := time.Now()
+	changes := usecase.returnStock(ctx, tx, &reservation, "Released", &now)
+
+	return reservation, true, changes, nil
+}
+
+func (usecase *StockReservationUsecase) FindById(ctx context.Context, buyerUUID string, reservationID string) (product.StockReservationResponse, error) {
+	reservation, err := usecase.StockReservationRepository.FindById(ctx, reservationID)
+	if err != nil || reservation.Buyer_id != buyerUUID {
+		respErr := errors.New("reservation not found")
+		usecase.Log.Warn().Msg(respErr.Error())
+		return product.StockReservationResponse{}, respErr
+	}
+
+	return toStockReservationResponse(reservation), nil
+}
+
+// Run expires reservations whose hold ran out, in batches, every
+// STOCK_RESERVATION_SWEEP_INTERVAL.
+func (usecase *StockReservationUsecase) Run(ctx context.Context) {
+	interval := usecase.Koanf.Duration("STOCK_RESERVATION_SWEEP_INTERVAL")
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					usecase.Log.Error().Msg(fmt.Sprintf("stock reservation sweep failed: %v", err))
+				}
+			}()
+
+			// a full batch means more may be waiting
+			expired := 100
+			for expired == 100 {
+				expired = usecase.expireBatch(ctx, 100)
+			}
+		}()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (usecase *StockReservationUsecase) expireBatch(ctx context.Context, limit int) int {
+	reservations, changes := func() ([]domain.StockReservation, []domain.ProductStatusChange) {
+		tx, err := usecase.DB.Begin()
+		if err != nil {
+			respErr := errors.New("failed to start transaction")
+			usecase.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		defer helper.CommitOrRollback(tx)
+
+		now := time.Now()
+		reservations := usecase.StockReservationRepository.FindExpiredWithTx(ctx, tx, &now, limit)
+
+		changes := []domain.ProductStatusChange{}
+		for i := range reservations {
+			changes = append(changes, usecase.returnStock(ctx, tx, &reservations[i], "Expired", &now)...)
+		}
+
+		return reservations, changes
+	}()
+
+	for _, reservation := range reservations {
+		usecase.publish(reservation, "StockExpired", reservation.Quantity)
+	}
+
+	usecase.ProductStatusUsecase.PublishChanges(changes)
+
+	if len(reservations) > 0 {
+		usecase.Log.Info().Msg(fmt.Sprintf("expired %d stock reservations", len(reservations)))
+	}
+
+	return len(reservations)
+}
+
+// returnStock returns the status changes the units coming back caused, to be
+// published after the transaction committed.
+func (usecase *StockReservationUsecase) returnStock(ctx context.Context, tx *sql.Tx, reservation *domain.StockReservation, status string, now *time.Time) []domain.ProductStatusChange {
+	changes := usecase.ProductStatusUsecase.RecordStockChangeWithTx(ctx, tx, nil, usecase.StockReservationRepository.ReturnStockWithTx(ctx, tx, *reservation, now))
+
+	if reservation.Variant_id != nil {
+		changes = usecase.ProductStatusUsecase.RecordStockChangeWithTx(ctx, tx, changes, usecase.ProductVariantRepository.SyncProductWithTx(ctx, tx, reservation.Product_id, now))
+	}
+
+	reservation.Status = status
+	reservation.Updated_at = now
+	usecase.StockReservationRepository.UpdateStatusWithTx(ctx, tx, reservation.Id, status, now)
+
+	return changes
+}
+
+// publish runs after the transaction committed, a movement that was rolled
+// back is never announced.
+func (usecase *StockReservationUsecase) publish(reservation domain.StockReservation, event string, delta int) {
+	now := time.Now()
+
+	movementEvent := product.StockMovementEvent{
+		Reservation_id: reservation.Id,
+		Product_id:     reservation.Product_id,
+		Variant_id:     reservation.Variant_id,
+		Buyer_id:       reservation.Buyer_id,
+		Event:          event,
+		Quantity:       reservation.Quantity,
+		Delta:          delta,
+		Created_at:     &now,
+	}
+
+	messageJSON, err := json.Marshal(movementEvent)
+	if err != nil {
+		respErr := errors.New("failed to marshal stock movement event")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	_, _, err = usecase.KafkaWriter.SendMessage(&sarama.ProducerMessage{
+		Topic: "product.stock_movement",
+		Key:   sarama.StringEncoder(strconv.Itoa(reservation.Product_id)),
+		Value: sarama.ByteEncoder(messageJSON),
+	})
+
+	if err != nil {
+		respErr := errors.New("failed to produce an event to kafka broker")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func sameVariant(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func toStockReservationResponse(reservation domain.StockReservation) product.StockReservationResponse {
+	return product.StockReservationResponse{
+		Id:         reservation.Id,
+		Product_id: reservation.Product_id,
+		Variant_id: reservation.Variant_id,
+		Quantity:   reservation.Quantity,
+		Status:     reservation.Status,
+		Expires_at: reservation.Expires_at,
+		Created_at: reservation.Created_at,
+		Updated_at: reservation.Updated_at,
+	}
+}
@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/IBM/sarama"
+	"github.com/go-playground/validator"
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/repository"
+	"strconv"
+	"time"
+)
+
+type StockUsecase struct {
+	StockThresholdRepository *repository.StockThresholdRepository
+	KafkaWriter              sarama.SyncProducer
+	Validator                *validator.Validate
+	Log                      *zerolog.Logger
+	Koanf                    *koanf.Koanf
+}
+
+func NewStockUsecase(stockThresholdRepository *repository.StockThresholdRepository, kafkaWriter sarama.SyncProducer, validator *validator.Validate, zerolog *zerolog.Logger, koanf *koanf.Koanf) *StockUsecase {
+	return &StockUsecase{
+		StockThresholdRepository: stockThresholdRepository,
+		KafkaWriter:              kafkaWriter,
+		Validator:                validator,
+		Log:                      zerolog,
+		Koanf:                    koanf,
+	}
+}
+
+func (usecase *StockUsecase) FindThreshold(ctx context.Context, sellerID string) product.StockThresholdResponse {
+	threshold, err := usecase.StockThresholdRepository.FindBySellerId(ctx, sellerID)
+	if err != nil {
+		return product.StockThresholdResponse{
+			Seller_id:           sellerID,
+			Low_stock_threshold: usecase.Koanf.Int("DEFAULT_LOW_STOCK_THRESHOLD"),
+		}
+	}
+
+	return product.StockThresholdResponse{
+		Seller_id:           threshold.Seller_id,
+		Low_stock_threshold: threshold.Low_stock_threshold,
+		Updated_at:          threshold.Updated_at,
+	}
+}
+
+func (usecase *StockUsecase) UpdateThreshold(ctx context.Context, request product.StockThresholdUpdateRequest, sellerID string) (product.StockThresholdResponse, error) {
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return product.StockThresholdResponse{}, respErr
+	}
+
+	now := time.Now()
+
+	threshold := domain.StockThreshold{
+		Seller_id:           sellerID,
+		Low_stock_threshold: request.Low_stock_threshold,
+		Updated_at:          &now,
+	}
+
+	usecase.StockThresholdRepository.Upsert(ctx, threshold)
+
+	return product.StockThresholdResponse{
+		Seller_id:           threshold.Seller_id,
+		Low_stock_threshold: threshold.Low_stock_threshold,
+		Updated_at:          threshold.Updated_at,
+	}, nil
+}
+
+// HandleProductChange derives stock events from the before and after images of
+// a product update. Only updates can change stock of an existing product.
+func (usecase *StockUsecase) HandleProductChange(ctx context.Context, payload product.ProductCDCPayload) {
+	if payload.Op != "u" || payload.Before == nil || payload.After == nil {
+		return
+	}
+
+	before := payload.Before
+	after := payload.After
+
+	if before.Quantity == after.Quantity {
+		return
+	}
+
+	threshold := usecase.FindThreshold(ctx, after.Seller_id).Low_stock_threshold
+	now := time.Now()
+
+	stockEvent := product.StockEvent{
+		Product_id:        after.Id,
+		Seller_id:         after.Seller_id,
+		Event:             "StockChanged",
+		Previous_quantity: before.Quantity,
+		Quantity:          after.Quantity,
+		Delta:             after.Quantity - before.Quantity,
+		Threshold:         threshold,
+		Created_at:        &now,
+	}
+
+	usecase.publish("product.stock_changed", strconv.Itoa(after.Id), stockEvent)
+
+	if before.Quantity > 0 && after.Quantity <= 0 {
+		stockEvent.Event = "OutOfStock"
+		usecase.publish("product.out_of_stock", strconv.Itoa(after.Id), stockEvent)
+		usecase.notifySeller(after, stockEvent.Event, &now)
+		return
+	}
+
+	if before.Quantity > threshold && after.Quantity <= threshold {
+		stockEvent.Event = "LowStock"
+		usecase.publish("product.low_stock", strconv.Itoa(after.Id), stockEvent)
+		usecase.notifySeller(after, stockEvent.Event, &now)
+	}
+}
+
+func (usecase *StockUsecase) notifySeller(row *product.ProductCDCRow, event string, now *time.Time) {
+	notificationEvent := product.StockNotificationEvent{
+		Id:           row.Seller_id,
+		Product_id:   row.Id,
+		Product_name: row.Name,
+		Event:        event,
+		Quantity:     row.Quantity,
+		Created_at:   now,
+	}
+
+	usecase.publish("product.notification", row.Seller_id, notificationEvent)
+}
+
+func (usecase *StockUsecase) publish(topic string, key string, event interface{}) {
+	messageJSON, err := json.Marshal(event)
+	if err != nil {
+		respErr := errors.New("failed to marshal a json")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	_, _, err = usecase.KafkaWriter.SendMessage(&sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.StringEncoder(key),
+		Value: sarama.ByteEncoder(messageJSON),
+	})
+
+	if err != nil {
+		respErr := errors.New("failed to produce an event to kafka broker")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/user"
+	"gocdc/internal/repository"
+	"time"
+)
+
+type UserDeletionUsecase struct {
+	ProductRepository        *repository.ProductRepository
+	ProductStatusRepository  *repository.ProductStatusRepository
+	SellerDeletionRepository *repository.SellerDeletionRepository
+	ProductStatusUsecase     *ProductStatusUsecase
+	KafkaWriter              sarama.SyncProducer
+	DB                       *sql.DB
+	Log                      *zerolog.Logger
+}
+
+func NewUserDeletionUsecase(productRepository *repository.ProductRepository, productStatusRepository *repository.ProductStatusRepository, sellerDeletionRepository *repository.SellerDeletionRepository, productStatusUsecase *ProductStatusUsecase, kafkaWriter sarama.SyncProducer, db *sql.DB, zerolog *zerolog.Logger) *UserDeletionUsecase {
+	return &UserDeletionUsecase{
+		ProductRepository:        productRepository,
+		ProductStatusRepository:  productStatusRepository,
+		SellerDeletionRepository: sellerDeletionRepository,
+		ProductStatusUsecase:     productStatusUsecase,
+		KafkaWriter:              kafkaWriter,
+		DB:                       db,
+		Log:                      zerolog,
+	}
+}
+
+// ArchiveSellerProducts handles user.deleted. A saga id that was already
+// processed only gets its result published again.
+func (usecase *UserDeletionUsecase) ArchiveSellerProducts(ctx context.Context, event user.UserDeletedEvent) error {
+	archived, changes, err := usecase.archiveSellerProducts(ctx, event)
+	if err == nil {
+		usecase.ProductStatusUsecase.PublishChanges(changes)
+	}
+
+	resultEvent := user.UserDeletionResultEvent{
+		Saga_id:          event.Saga_id,
+		Id:               event.Id,
+		Status:           "Completed",
+		Archived_product: archived,
+	}
+
+	if err != nil {
+		usecase.Log.Error().Err(err).Msg("failed to archive seller products")
+		resultEvent.Status = "Failed"
+		resultEvent.Reason = err.Error()
+	}
+
+	usecase.publishResult(resultEvent)
+
+	return err
+}
+
+func (usecase *UserDeletionUsecase) archiveSellerProducts(ctx context.Context, event user.UserDeletedEvent) (archived int64, changes []domain.ProductStatusChange, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("%v", recovered)
+		}
+	}()
+
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	deletion, err := usecase.SellerDeletionRepository.FindBySagaIdWithTx(ctx, tx, event.Saga_id)
+	if err == nil {
+		if deletion.Status == "Restored" {
+			return 0, nil, errors.New("user deletion has been compensated")
+		}
+
+		usecase.Log.Debug().Msg("User deletion " + event.Saga_id + " already processed")
+		return 0, nil, nil
+	}
+
+	now := time.Now()
+
+	deletion = domain.SellerDeletion{
+		Saga_id:    event.Saga_id,
+		Seller_id:  event.Id,
+		Status:     "Archived",
+		Created_at: &now,
+		Updated_at: &now,
+	}
+
+	usecase.SellerDeletionRepository.CreateWithTx(ctx, tx, deletion)
+	usecase.SellerDeletionRepository.SnapshotProductsWithTx(ctx, tx, event.Saga_id, event.Id)
+	changes = usecase.ProductStatusRepository.CreateSellerArchiveHistoryWithTx(ctx, tx, event.Id, &now)
+	archived = usecase.ProductRepository.ArchiveBySellerWithTx(ctx, tx, event.Id, &now)
+
+	return archived, changes, nil
+}
+
+// RestoreSellerProducts handles user.deletion.compensate by putting every
+// archived product of the saga back to its previous status.
+func (usecase *UserDeletionUsecase) RestoreSellerProducts(ctx context.Context, event user.UserDeletedEvent) error {
+	changes, err := usecase.restoreSellerProducts(ctx, event)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return err
+	}
+
+	usecase.ProductStatusUsecase.PublishChanges(changes)
+
+	return nil
+}
+
+func (usecase *UserDeletionUsecase) restoreSellerProducts(ctx context.Context, event user.UserDeletedEvent) ([]domain.ProductStatusChange, error) {
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	now := time.Now()
+
+	deletion, err := usecase.SellerDeletionRepository.FindBySagaIdWithTx(ctx, tx, event.Saga_id)
+	if err != nil {
+		// compensated before user.deleted arrived, remember it so the late
+		// event does not archive anything
+		usecase.SellerDeletionRepository.CreateWithTx(ctx, tx, domain.SellerDeletion{
+			Saga_id:    event.Saga_id,
+			Seller_id:  event.Id,
+			Status:     "Restored",
+			Created_at: &now,
+			Updated_at: &now,
+		})
+
+		return nil, nil
+	}
+
+	if deletion.Status != "Archived" {
+		usecase.Log.Debug().Msg("User deletion " + event.Saga_id + " already " + deletion.Status)
+		return nil, nil
+	}
+
+	changes := usecase.ProductStatusRepository.CreateSellerRestoreHistoryWithTx(ctx, tx, event.Saga_id, deletion.Seller_id, &now)
+	usecase.ProductRepository.RestoreBySellerDeletionWithTx(ctx, tx, event.Saga_id, &now)
+	usecase.SellerDeletionRepository.UpdateStatusWithTx(ctx, tx, event.Saga_id, "Restored", &now)
+
+	return changes, nil
+}
+
+func (usecase *UserDeletionUsecase) publishResult(resultEvent user.UserDeletionResultEvent) {
+	now := time.Now()
+	resultEvent.Created_at = &now
+
+	messageJSON, err := json.Marshal(resultEvent)
+	if err != nil {
+		respErr := errors.New("failed to marshal a json")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	_, _, err = usecase.KafkaWriter.SendMessage(&sarama.ProducerMessage{
+		Topic: "user.deletion.result",
+		Key:   sarama.StringEncoder(resultEvent.Id),
+		Value: sarama.ByteEncoder(messageJSON),
+	})
+
+	if err != nil {
+		respErr := errors.New("failed to produce an event to kafka broker")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
@@ -0,0 +1,549 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator"
+	googleuuid "github.com/google/uuid"
+	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
+	"gocdc/internal/helper"
+	"gocdc/internal/model/domain"
+	"gocdc/internal/model/web/product"
+	"gocdc/internal/model/web/webhook"
+	"gocdc/internal/repository"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type WebhookUsecase struct {
+	WebhookRepository *repository.WebhookRepository
+	DB                *sql.DB
+	HttpClient        *http.Client
+	Validator         *validator.Validate
+	Log               *zerolog.Logger
+	Koanf             *koanf.Koanf
+}
+
+func NewWebhookUsecase(webhookRepository *repository.WebhookRepository, db *sql.DB, validator *validator.Validate, zerolog *zerolog.Logger, koanf *koanf.Koanf) *WebhookUsecase {
+	return &WebhookUsecase{
+		WebhookRepository: webhookRepository,
+		DB:                db,
+		HttpClient: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				// every address dialled is checked, including those of redirects
+				// and of a name that resolves differently by now
+				DialContext: (&net.Dialer{
+					Timeout: 5 * time.Second,
+					Control: controlWebhookDial,
+				}).DialContext,
+				TLSHandshakeTimeout: 5 * time.Second,
+				MaxIdleConnsPerHost: 2,
+			},
+		},
+		Validator: validator,
+		Log:       zerolog,
+		Koanf:     koanf,
+	}
+}
+
+func (usecase *WebhookUsecase) Create(ctx context.Context, request webhook.WebhookCreateRequest, userUUID string) (webhook.WebhookResponse, error) {
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return webhook.WebhookResponse{}, respErr
+	}
+
+	endpointUrl, err := url.Parse(request.Url)
+	if err != nil || (endpointUrl.Scheme != "https" && endpointUrl.Scheme != "http") {
+		respErr := errors.New("webhook url must be http or https")
+		usecase.Log.Warn().Msg(respErr.Error())
+		return webhook.WebhookResponse{}, respErr
+	}
+
+	err = checkWebhookHost(ctx, endpointUrl.Hostname())
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return webhook.WebhookResponse{}, err
+	}
+
+	secret := make([]byte, 32)
+	_, err = rand.Read(secret)
+	if err != nil {
+		respErr := errors.New("failed to generate webhook secret")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	now := time.Now()
+
+	endpoint := domain.WebhookEndpoint{
+		Id:          googleuuid.New().String(),
+		User_id:     userUUID,
+		Url:         request.Url,
+		Secret:      hex.EncodeToString(secret),
+		Active:      true,
+		Event_types: request.Event_types,
+		Created_at:  &now,
+		Updated_at:  &now,
+	}
+
+	usecase.WebhookRepository.CreateEndpointWithTx(ctx, tx, endpoint)
+
+	// the secret is only ever returned here, subscribers need it to verify signatures
+	webhookResponse := toWebhookResponse(endpoint)
+	webhookResponse.Secret = endpoint.Secret
+
+	return webhookResponse, nil
+}
+
+func (usecase *WebhookUsecase) FindAll(ctx context.Context, userUUID string) []webhook.WebhookResponse {
+	endpoints := usecase.WebhookRepository.FindEndpointsByUserId(ctx, userUUID)
+
+	webhookResponses := []webhook.WebhookResponse{}
+	for _, endpoint := range endpoints {
+		webhookResponses = append(webhookResponses, toWebhookResponse(endpoint))
+	}
+
+	return webhookResponses
+}
+
+func (usecase *WebhookUsecase) SetActive(ctx context.Context, userUUID string, endpointID string, active bool) error {
+	_, err := usecase.WebhookRepository.FindEndpoint(ctx, userUUID, endpointID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return err
+	}
+
+	now := time.Now()
+	usecase.WebhookRepository.SetEndpointActive(ctx, endpointID, active, &now)
+
+	return nil
+}
+
+func (usecase *WebhookUsecase) Delete(ctx context.Context, userUUID string, endpointID string) error {
+	_, err := usecase.WebhookRepository.FindEndpoint(ctx, userUUID, endpointID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return err
+	}
+
+	usecase.WebhookRepository.DeleteEndpoint(ctx, endpointID)
+
+	return nil
+}
+
+func (usecase *WebhookUsecase) FindDeliveries(ctx context.Context, userUUID string, endpointID string) ([]webhook.WebhookDeliveryResponse, error) {
+	_, err := usecase.WebhookRepository.FindEndpoint(ctx, userUUID, endpointID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return []webhook.WebhookDeliveryResponse{}, err
+	}
+
+	deliveries := usecase.WebhookRepository.FindDeliveriesByEndpointId(ctx, endpointID, 100)
+
+	deliveryResponses := []webhook.WebhookDeliveryResponse{}
+	for _, delivery := range deliveries {
+		deliveryResponses = append(deliveryResponses, toWebhookDeliveryResponse(delivery))
+	}
+
+	return deliveryResponses, nil
+}
+
+func (usecase *WebhookUsecase) FindDelivery(ctx context.Context, userUUID string, endpointID string, deliveryID string) (webhook.WebhookDeliveryResponse, error) {
+	_, err := usecase.WebhookRepository.FindEndpoint(ctx, userUUID, endpointID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return webhook.WebhookDeliveryResponse{}, err
+	}
+
+	delivery, err := usecase.WebhookRepository.FindDelivery(ctx, endpointID, deliveryID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return webhook.WebhookDeliveryResponse{}, err
+	}
+
+	deliveryResponse := toWebhookDeliveryResponse(delivery)
+	for _, attempt := range usecase.WebhookRepository.FindAttemptsByDeliveryId(ctx, deliveryID) {
+		deliveryResponse.Attempt_logs = append(deliveryResponse.Attempt_logs, webhook.WebhookDeliveryAttemptResponse{
+			Attempt:     attempt.Attempt,
+			Status_code: attempt.Status_code,
+			Error:       attempt.Error,
+			Duration_ms: attempt.Duration_ms,
+			Created_at:  attempt.Created_at,
+		})
+	}
+
+	return deliveryResponse, nil
+}
+
+// Replay queues a fresh delivery with the payload of an earlier one, the
+// original delivery and its attempt log are left untouched.
+func (usecase *WebhookUsecase) Replay(ctx context.Context, userUUID string, endpointID string, deliveryID string) (webhook.WebhookDeliveryResponse, error) {
+	endpoint, err := usecase.WebhookRepository.FindEndpoint(ctx, userUUID, endpointID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return webhook.WebhookDeliveryResponse{}, err
+	}
+
+	if !endpoint.Active {
+		respErr := errors.New("webhook is disabled")
+		usecase.Log.Warn().Msg(respErr.Error())
+		return webhook.WebhookDeliveryResponse{}, respErr
+	}
+
+	delivery, err := usecase.WebhookRepository.FindDelivery(ctx, endpointID, deliveryID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return webhook.WebhookDeliveryResponse{}, err
+	}
+
+	now := time.Now()
+
+	replay := domain.WebhookDelivery{
+		Id:              googleuuid.New().String(),
+		Endpoint_id:     endpointID,
+		Event_type:      delivery.Event_type,
+		Payload:         delivery.Payload,
+		Status:          "Pending",
+		Next_attempt_at: &now,
+		Created_at:      &now,
+		Updated_at:      &now,
+	}
+
+	usecase.WebhookRepository.CreateDelivery(ctx, replay)
+
+	return toWebhookDeliveryResponse(replay), nil
+}
+
+// Enqueue fans an event out to every active endpoint of ownerID subscribed to
+// eventType.
+func (usecase *WebhookUsecase) Enqueue(ctx context.Context, eventType string, ownerID string, data []byte) {
+	endpointIDs := usecase.WebhookRepository.FindSubscribedEndpointIds(ctx, eventType, ownerID)
+	if len(endpointIDs) == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	for _, endpointID := range endpointIDs {
+		deliveryID := googleuuid.New().String()
+
+		payload, err := json.Marshal(webhook.WebhookEvent{
+			Id:         deliveryID,
+			Type:       eventType,
+			Data:       data,
+			Created_at: &now,
+		})
+		if err != nil {
+			respErr := errors.New("failed to marshal a json")
+			usecase.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		usecase.WebhookRepository.CreateDelivery(ctx, domain.WebhookDelivery{
+			Id:              deliveryID,
+			Endpoint_id:     endpointID,
+			Event_type:      eventType,
+			Payload:         string(payload),
+			Status:          "Pending",
+			Next_attempt_at: &now,
+			Created_at:      &now,
+			Updated_at:      &now,
+		})
+	}
+}
+
+func (usecase *WebhookUsecase) HandleProductChange(ctx context.Context, payload product.ProductCDCPayload) {
+	var eventType string
+	var row *product.ProductCDCRow
+
+	switch payload.Op {
+	case "c":
+		eventType, row = "product.created", payload.After
+	case "u":
+		eventType, row = "product.updated", payload.After
+		// deletion is a soft delete, the row itself is removed by the purge
+		if payload.Before != nil && payload.After != nil {
+			if payload.Before.Deleted_at == nil && payload.After.Deleted_at != nil {
+				eventType = "product.deleted"
+			} else if payload.Before.Deleted_at != nil && payload.After.Deleted_at == nil {
+				eventType = "product.restored"
+			}
+		}
+	case "d":
+		eventType, row = "product.purged", payload.Before
+	default:
+		return
+	}
+
+	// a product change only goes to its seller, like product.activity
+	if row == nil || row.Seller_id == "" {
+		return
+	}
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		respErr := errors.New("failed to marshal a json")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	usecase.Enqueue(ctx, eventType, row.Seller_id, data)
+}
+
+func (usecase *WebhookUsecase) RunDispatcher(ctx context.Context) {
+	pollInterval := usecase.Koanf.Duration("WEBHOOK_POLL_INTERVAL")
+	if pollInterval <= 0 {
+		pollInterval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			func() {
+				defer func() {
+					if err := recover(); err != nil {
+						usecase.Log.Error().Msg(fmt.Sprintf("webhook dispatch failed: %v", err))
+					}
+				}()
+
+				usecase.DispatchDue(ctx)
+			}()
+		}
+	}
+}
+
+func (usecase *WebhookUsecase) DispatchDue(ctx context.Context) {
+	now := time.Now()
+	deliveries, endpoints := usecase.WebhookRepository.ClaimDueDeliveries(ctx, now, now.Add(time.Minute), 50)
+
+	// deliveries go out concurrently so one slow endpoint can't outlive the lease of the rest
+	wg := sync.WaitGroup{}
+	for i := range deliveries {
+		wg.Add(1)
+
+		go func(delivery domain.WebhookDelivery, endpoint domain.WebhookEndpoint) {
+			defer wg.Done()
+			defer func() {
+				if err := recover(); err != nil {
+					usecase.Log.Error().Msg(fmt.Sprintf("webhook delivery %s failed: %v", delivery.Id, err))
+				}
+			}()
+
+			usecase.deliver(ctx, delivery, endpoint)
+		}(deliveries[i], endpoints[i])
+	}
+
+	wg.Wait()
+}
+
+func (usecase *WebhookUsecase) deliver(ctx context.Context, delivery domain.WebhookDelivery, endpoint domain.WebhookEndpoint) {
+	start := time.Now()
+	statusCode, err := usecase.send(ctx, delivery, endpoint, start)
+	finished := time.Now()
+
+	delivery.Attempts++
+	delivery.Updated_at = &finished
+
+	attempt := domain.WebhookDeliveryAttempt{
+		Delivery_id: delivery.Id,
+		Attempt:     delivery.Attempts,
+		Status_code: statusCode,
+		Duration_ms: int(finished.Sub(start).Milliseconds()),
+		Created_at:  &finished,
+	}
+
+	maxAttempts := usecase.Koanf.Int("WEBHOOK_MAX_ATTEMPTS")
+	if maxAttempts <= 0 {
+		maxAttempts = 8
+	}
+
+	if err == nil {
+		delivery.Status = "Succeeded"
+		delivery.Next_attempt_at = &finished
+	} else {
+		attempt.Error = err.Error()
+
+		if delivery.Attempts >= maxAttempts {
+			delivery.Status = "Failed"
+			delivery.Next_attempt_at = &finished
+		} else {
+			delivery.Status = "Pending"
+			nextAttemptAt := finished.Add(webhookBackoff(delivery.Attempts))
+			delivery.Next_attempt_at = &nextAttemptAt
+		}
+	}
+
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	usecase.WebhookRepository.RecordAttemptWithTx(ctx, tx, attempt)
+	usecase.WebhookRepository.UpdateDeliveryWithTx(ctx, tx, delivery)
+}
+
+// send posts the payload signed as HMAC-SHA256(secret, timestamp + "." + body)
+// so receivers can reject tampered or replayed requests.
+func (usecase *WebhookUsecase) send(ctx context.Context, delivery domain.WebhookDelivery, endpoint domain.WebhookEndpoint, now time.Time) (int, error) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(endpoint.Secret))
+	mac.Write([]byte(timestamp + "." + delivery.Payload))
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", delivery.Id)
+	req.Header.Set("X-Webhook-Event", delivery.Event_type)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+signature)
+
+	resp, err := usecase.HttpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+var blockedWebhookNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+
+	return network
+}
+
+// isPublicWebhookIP rejects addresses that would let a subscriber reach this
+// service's own network.
+func isPublicWebhookIP(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, network := range blockedWebhookNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkWebhookHost makes sure every address host resolves to is public when
+// an endpoint is registered. controlWebhookDial checks again when sending.
+func checkWebhookHost(ctx context.Context, host string) error {
+	if host == "" {
+		return errors.New("webhook url must have a host")
+	}
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addresses) == 0 {
+		return errors.New("webhook url host cannot be resolved")
+	}
+
+	for _, address := range addresses {
+		if !isPublicWebhookIP(address.IP) {
+			return errors.New("webhook url must not point to a private or local address")
+		}
+	}
+
+	return nil
+}
+
+func controlWebhookDial(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if !isPublicWebhookIP(net.ParseIP(host)) {
+		return errors.New("webhook endpoint resolved to a private or local address " + host)
+	}
+
+	return nil
+}
+
+// webhookBackoff doubles the wait after every failed attempt, starting at 30
+// seconds and capped at 6 hours.
+func webhookBackoff(attempts int) time.Duration {
+	backoff := 30 * time.Second * time.Duration(math.Pow(2, float64(attempts-1)))
+	if backoff > 6*time.Hour || backoff <= 0 {
+		backoff = 6 * time.Hour
+	}
+
+	return backoff
+}
+
+func toWebhookResponse(endpoint domain.WebhookEndpoint) webhook.WebhookResponse {
+	return webhook.WebhookResponse{
+		Id:          endpoint.Id,
+		Url:         endpoint.Url,
+		Active:      endpoint.Active,
+		Event_types: endpoint.Event_types,
+		Created_at:  endpoint.Created_at,
+		Updated_at:  endpoint.Updated_at,
+	}
+}
+
+func toWebhookDeliveryResponse(delivery domain.WebhookDelivery) webhook.WebhookDeliveryResponse {
+	return webhook.WebhookDeliveryResponse{
+		Id:              delivery.Id,
+		Event_type:      delivery.Event_type,
+		Payload:         delivery.Payload,
+		Status:          delivery.Status,
+		Attempts:        delivery.Attempts,
+		Next_attempt_at: delivery.Next_attempt_at,
+		Created_at:      delivery.Created_at,
+		Updated_at:      delivery.Updated_at,
+	}
+}
@@ -31,7 +31,6 @@ func main() {
 	koanf := config.NewKoanf()
 	zerolog := config.NewZeroLog()
 	kafkaProducer := config.NewKafkaProducer(koanf, &zerolog)
-	kafkaConsumer := config.NewKafkaConsumer(koanf, &zerolog)
 	db := config.NewDB(koanf, &zerolog)
 	validator := config.NewValidator()
 
@@ -39,7 +38,6 @@ func main() {
 		Router:        router,
 		DB:            db,
 		KafkaProducer: kafkaProducer,
-		KafkaConsumer: kafkaConsumer,
 		Config:        koanf,
 		Validate:      validator,
 		Log:           &zerolog,
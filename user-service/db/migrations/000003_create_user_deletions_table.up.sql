@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_deletions(
+    id char(36) PRIMARY KEY,
+    user_id char(36) NOT NULL,
+    status varchar(12) NOT NULL DEFAULT 'Pending',
+    attempts int NOT NULL DEFAULT 1,
+    reason text NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL,
+    updated_at timestamp NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_deletions_pending_idx ON user_deletions(user_id) WHERE status = 'Pending';
\ No newline at end of file
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"database/sql"
 	"github.com/IBM/sarama"
 	"github.com/go-playground/validator"
@@ -10,6 +11,7 @@ import (
 	"gocdc/internal/delivery/http"
 	"gocdc/internal/delivery/http/middleware"
 	"gocdc/internal/delivery/http/route"
+	"gocdc/internal/delivery/messaging"
 	"gocdc/internal/repository"
 	"gocdc/internal/usecase"
 )
@@ -18,7 +20,6 @@ type ServerConfig struct {
 	Router        *httprouter.Router
 	DB            *sql.DB
 	KafkaProducer sarama.SyncProducer
-	KafkaConsumer sarama.Consumer
 	Log           *zerolog.Logger
 	Validate      *validator.Validate
 	Config        *koanf.Koanf
@@ -26,9 +27,15 @@ type ServerConfig struct {
 
 func Server(config *ServerConfig) {
 	userRepository := repository.NewUserRepository(config.Log, config.DB)
-	userUsecase := usecase.NewUserUsecase(userRepository, config.KafkaProducer, config.DB, config.Validate, config.Log, config.Config)
+	userDeletionRepository := repository.NewUserDeletionRepository(config.Log, config.DB)
+	userUsecase := usecase.NewUserUsecase(userRepository, userDeletionRepository, config.KafkaProducer, config.DB, config.Validate, config.Log, config.Config)
 	userController := http.NewUserController(userUsecase, config.Log)
 
+	userDeletionConsumer := messaging.NewUserDeletionConsumer(userUsecase, config.Log)
+	messaging.ConsumeTopic(context.Background(), NewKafkaConsumerGroup(config.Config, config.Log, "user-deletion-result", sarama.OffsetOldest), "user-deletion-result", "user.deletion.result", config.KafkaProducer, config.Log, userDeletionConsumer.ConsumeResult)
+	go userUsecase.RunDeletionRetrier(context.Background())
+	go userUsecase.RunPurge(context.Background())
+
 	authMiddleware := middleware.NewAuthMiddleware(config.Router, config.Log, config.Config, userUsecase)
 
 	routeConfig := route.RouteConfig{
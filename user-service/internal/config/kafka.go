@@ -19,14 +19,24 @@ func NewKafkaProducer(config *koanf.Koanf, log *zerolog.Logger) sarama.SyncProdu
 	return producer
 }
 
-func NewKafkaConsumer(config *koanf.Koanf, log *zerolog.Logger) sarama.Consumer {
+// NewKafkaConsumerGroup joins the group KAFKA_CONSUMER_GROUP.name. A group
+// without committed offsets starts at initialOffset.
+func NewKafkaConsumerGroup(config *koanf.Koanf, log *zerolog.Logger, name string, initialOffset int64) sarama.ConsumerGroup {
 	kafka_port := config.String("KAFKA_BROKER_PORT")
 	kafka_array_port := strings.Split(kafka_port, ";")
 
-	consumer, err := sarama.NewConsumer(kafka_array_port, nil)
+	groupPrefix := config.String("KAFKA_CONSUMER_GROUP")
+	if groupPrefix == "" {
+		groupPrefix = "user-service"
+	}
+
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Consumer.Offsets.Initial = initialOffset
+
+	group, err := sarama.NewConsumerGroup(kafka_array_port, groupPrefix+"."+name, saramaConfig)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to create kafka consumer")
+		log.Fatal().Err(err).Msg("Failed to create kafka consumer group " + name)
 	}
 
-	return consumer
+	return group
 }
@@ -19,6 +19,9 @@ func (c *RouteConfig) SetupRoute() {
 	c.Router.POST("/login", c.UserController.Login)
 	c.Router.PATCH("/user", c.AuthMiddleware.ServeHTTP(c.UserController.Update))
 	c.Router.DELETE("/user", c.AuthMiddleware.ServeHTTP(c.UserController.Delete))
+	c.Router.POST("/user/restore", c.UserController.Restore)
+	c.Router.GET("/user/deletion/:sagaID", c.AuthMiddleware.ServeHTTP(c.UserController.FindDeletionStatus))
+	c.Router.GET("/user/seller/:sellerID", c.UserController.FindSeller)
 	c.Router.GET("/user/existence", c.AuthMiddleware.ServeHTTP(c.UserController.CheckUserExistence))
 	c.Router.GET("/user/nameaddress", c.AuthMiddleware.ServeHTTP(c.UserController.FindUserNameAddress))
 	c.Router.GET("/user/email", c.AuthMiddleware.ServeHTTP(c.UserController.FindUserEmail))
@@ -87,6 +87,56 @@ func (controller UserController) Login(writer http.ResponseWriter, request *http
 	helper.WriteToResponseBody(writer, webResponse)
 }
 
+func (controller UserController) Restore(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	userRestoreRequest := user.UserRestoreRequest{}
+	helper.ReadFromRequestBody(request, &userRestoreRequest)
+
+	err := controller.UserUsecase.Restore(request.Context(), userRestoreRequest)
+	if err != nil {
+		if err.Error() == "wrong email or password" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusNotFound)
+			webResponse := web.WebResponse{
+				Code:   http.StatusNotFound,
+				Status: "Not Found",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+		if err.Error() == "name or email are already exist" {
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusConflict)
+			webResponse := web.WebResponse{
+				Code:   http.StatusConflict,
+				Status: "Conflict",
+				Data:   err.Error(),
+			}
+
+			helper.WriteToResponseBody(writer, webResponse)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusBadRequest)
+		webResponse := web.WebResponse{
+			Code:   http.StatusBadRequest,
+			Status: "Bad Request",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
 func (controller UserController) Update(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
 	userUUID, _ := request.Context().Value("user_uuid").(string)
 
@@ -118,7 +168,38 @@ func (controller UserController) Update(writer http.ResponseWriter, request *htt
 func (controller UserController) Delete(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
 	userUUID, _ := request.Context().Value("user_uuid").(string)
 
-	err := controller.UserUsecase.Delete(request.Context(), userUUID)
+	deletionResponse, err := controller.UserUsecase.Delete(request.Context(), userUUID)
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusAccepted)
+
+	webResponse := web.WebResponse{
+		Code:   http.StatusAccepted,
+		Status: "Accepted",
+		Data:   deletionResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
+func (controller UserController) FindDeletionStatus(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	userUUID, _ := request.Context().Value("user_uuid").(string)
+	sagaID := params.ByName("sagaID")
+
+	deletionResponse, err := controller.UserUsecase.FindDeletionStatus(request.Context(), userUUID, sagaID)
 	if err != nil {
 		writer.Header().Set("Content-Type", "application/json")
 		writer.WriteHeader(http.StatusNotFound)
@@ -136,6 +217,7 @@ func (controller UserController) Delete(writer http.ResponseWriter, request *htt
 	webResponse := web.WebResponse{
 		Code:   200,
 		Status: "OK",
+		Data:   deletionResponse,
 	}
 
 	helper.WriteToResponseBody(writer, webResponse)
@@ -195,6 +277,33 @@ func (controller UserController) CheckUserExistence(writer http.ResponseWriter,
 	helper.WriteToResponseBody(writer, webResponse)
 }
 
+func (controller UserController) FindSeller(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	sellerID := params.ByName("sellerID")
+
+	sellerResponse, err := controller.UserUsecase.FindSeller(request.Context(), sellerID)
+	if err != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusNotFound)
+
+		webResponse := web.WebResponse{
+			Code:   http.StatusNotFound,
+			Status: "Not Found",
+			Data:   err.Error(),
+		}
+
+		helper.WriteToResponseBody(writer, webResponse)
+		return
+	}
+
+	webResponse := web.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   sellerResponse,
+	}
+
+	helper.WriteToResponseBody(writer, webResponse)
+}
+
 func (controller UserController) FindUserNameAddress(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
 	userUUID, _ := request.Context().Value("user_uuid").(string)
 
@@ -0,0 +1,159 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+const consumerMaxAttempts = 5
+
+type ConsumerHandler func(message *sarama.ConsumerMessage) error
+
+// ConsumeTopic feeds every message of topic to handler as a member of group.
+// The offset of a message is committed once it is handled, so a restart picks
+// up where the group stopped. A handler that returns an error or panics is
+// retried with backoff, a message that keeps failing is copied to <topic>.dlq,
+// with name and the error in its headers, and then skipped.
+func ConsumeTopic(ctx context.Context, group sarama.ConsumerGroup, name string, topic string, producer sarama.SyncProducer, log *zerolog.Logger, handler ConsumerHandler) {
+	groupHandler := consumerGroupHandler{
+		Name:     name,
+		Producer: producer,
+		Log:      log,
+		Handler:  handler,
+	}
+
+	go func() {
+		defer group.Close()
+
+		for {
+			// returns on every rebalance, the group is joined again
+			err := group.Consume(ctx, []string{topic}, groupHandler)
+			if err != nil {
+				log.Error().Err(err).Msg("Kafka consumer group " + name + " failed on topic " + topic)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			if err != nil {
+				time.Sleep(consumerBackoff(1))
+			}
+		}
+	}()
+
+	log.Info().Msg("Consuming topic " + topic + " as " + name)
+}
+
+type consumerGroupHandler struct {
+	Name     string
+	Producer sarama.SyncProducer
+	Log      *zerolog.Logger
+	Handler  ConsumerHandler
+}
+
+func (groupHandler consumerGroupHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (groupHandler consumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (groupHandler consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for message := range claim.Messages() {
+		err := groupHandler.handle(session.Context(), message)
+		if err != nil {
+			// the partition was revoked mid retry, its next owner handles the message
+			return nil
+		}
+
+		session.MarkMessage(message, "")
+	}
+
+	return nil
+}
+
+// handle only returns an error when ctx ends before the message is handled
+// or dead-lettered.
+func (groupHandler consumerGroupHandler) handle(ctx context.Context, message *sarama.ConsumerMessage) error {
+	for attempt := 1; ; attempt++ {
+		err := handleMessage(message, groupHandler.Handler)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= consumerMaxAttempts {
+			return groupHandler.deadLetter(ctx, message, err)
+		}
+
+		groupHandler.Log.Warn().Err(err).Msg(fmt.Sprintf("Failed to handle message from %s partition %d offset %d as %s, attempt %d", message.Topic, message.Partition, message.Offset, groupHandler.Name, attempt))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(consumerBackoff(attempt)):
+		}
+	}
+}
+
+// deadLetter keeps trying until the message is in the DLQ, it is never
+// skipped without a copy.
+func (groupHandler consumerGroupHandler) deadLetter(ctx context.Context, message *sarama.ConsumerMessage, reason error) error {
+	groupHandler.Log.Error().Err(reason).Msg(fmt.Sprintf("Dead-lettering message from %s partition %d offset %d as %s", message.Topic, message.Partition, message.Offset, groupHandler.Name))
+
+	for attempt := 1; ; attempt++ {
+		_, _, err := groupHandler.Producer.SendMessage(&sarama.ProducerMessage{
+			Topic: message.Topic + ".dlq",
+			Key:   sarama.ByteEncoder(message.Key),
+			Value: sarama.ByteEncoder(message.Value),
+			Headers: []sarama.RecordHeader{
+				{Key: []byte("consumer"), Value: []byte(groupHandler.Name)},
+				{Key: []byte("error"), Value: []byte(reason.Error())},
+				{Key: []byte("partition"), Value: []byte(fmt.Sprint(message.Partition))},
+				{Key: []byte("offset"), Value: []byte(fmt.Sprint(message.Offset))},
+			},
+		})
+
+		if err == nil {
+			return nil
+		}
+
+		groupHandler.Log.Error().Err(err).Msg("failed to produce an event to kafka broker")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(consumerBackoff(attempt)):
+		}
+	}
+}
+
+func handleMessage(message *sarama.ConsumerMessage, handler ConsumerHandler) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("%v", recovered)
+		}
+	}()
+
+	return handler(message)
+}
+
+// consumerBackoff waits 1s before the first retry, doubling up to 30s.
+func consumerBackoff(attempt int) time.Duration {
+	backoff := time.Second
+	for i := 1; i < attempt && backoff < 30*time.Second; i++ {
+		backoff *= 2
+	}
+
+	if backoff > 30*time.Second {
+		backoff = 30 * time.Second
+	}
+
+	return backoff
+}
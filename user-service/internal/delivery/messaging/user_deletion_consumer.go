@@ -0,0 +1,33 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/IBM/sarama"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/web/user"
+	"gocdc/internal/usecase"
+)
+
+type UserDeletionConsumer struct {
+	UserUsecase *usecase.UserUsecase
+	Log         *zerolog.Logger
+}
+
+func NewUserDeletionConsumer(userUsecase *usecase.UserUsecase, zerolog *zerolog.Logger) *UserDeletionConsumer {
+	return &UserDeletionConsumer{
+		UserUsecase: userUsecase,
+		Log:         zerolog,
+	}
+}
+
+func (consumer UserDeletionConsumer) ConsumeResult(message *sarama.ConsumerMessage) error {
+	resultEvent := user.UserDeletionResultEvent{}
+	err := json.Unmarshal(message.Value, &resultEvent)
+	if err != nil {
+		consumer.Log.Warn().Err(err).Msg("failed to unmarshal user deletion result")
+		return err
+	}
+
+	return consumer.UserUsecase.HandleDeletionResult(context.Background(), resultEvent)
+}
@@ -12,6 +12,7 @@ type User struct {
 	PhoneNumber     string
 	Created_at      *time.Time
 	Updated_at      *time.Time
+	Deleted_at      *time.Time
 }
 
 type RefreshToken struct {
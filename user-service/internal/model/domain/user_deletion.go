@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+type UserDeletion struct {
+	Id         string
+	User_id    string
+	Status     string
+	Attempts   int
+	Reason     string
+	Created_at *time.Time
+	Updated_at *time.Time
+}
@@ -19,3 +19,17 @@ type VerificationEvent struct {
 	Id              string `json:"id"`
 	Profile_picture string `json:"profile_picture"`
 }
+
+type UserDeletedEvent struct {
+	Saga_id    string     `json:"saga_id"`
+	Id         string     `json:"id"`
+	Created_at *time.Time `json:"created_at"`
+}
+
+type UserDeletionResultEvent struct {
+	Saga_id    string     `json:"saga_id"`
+	Id         string     `json:"id"`
+	Status     string     `json:"status"`
+	Reason     string     `json:"reason"`
+	Created_at *time.Time `json:"created_at"`
+}
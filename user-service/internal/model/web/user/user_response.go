@@ -17,6 +17,12 @@ type UserNameAddressResponse struct {
 	Address string `json:"address"`
 }
 
+// SellerResponse is the public part of a user, shown next to their products.
+type SellerResponse struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
 type UserEmailResponse struct {
 	Email string `json:"email"`
 }
@@ -24,3 +30,12 @@ type UserEmailResponse struct {
 type UserExistenceResponse struct {
 	Status string `json:"status"`
 }
+
+type UserDeletionResponse struct {
+	Saga_id    string     `json:"saga_id"`
+	Status     string     `json:"status"`
+	Attempts   int        `json:"attempts"`
+	Reason     string     `json:"reason"`
+	Created_at *time.Time `json:"created_at"`
+	Updated_at *time.Time `json:"updated_at"`
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rs/zerolog"
+	"gocdc/internal/model/domain"
+	"time"
+)
+
+type UserDeletionRepository struct {
+	Log *zerolog.Logger
+	DB  *sql.DB
+}
+
+func NewUserDeletionRepository(zerolog *zerolog.Logger, db *sql.DB) *UserDeletionRepository {
+	return &UserDeletionRepository{
+		Log: zerolog,
+		DB:  db,
+	}
+}
+
+func (repository *UserDeletionRepository) CreateWithTx(ctx context.Context, tx *sql.Tx, deletion domain.UserDeletion) {
+	query := "INSERT INTO user_deletions (id,user_id,status,attempts,reason,created_at,updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7)"
+	_, err := tx.ExecContext(ctx, query, deletion.Id, deletion.User_id, deletion.Status, deletion.Attempts, deletion.Reason, deletion.Created_at, deletion.Updated_at)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *UserDeletionRepository) FindPendingByUserIdWithTx(ctx context.Context, tx *sql.Tx, userUUID string) (domain.UserDeletion, error) {
+	query := "SELECT id,user_id,status,attempts,reason,created_at,updated_at FROM user_deletions WHERE user_id=$1 AND status='Pending' FOR UPDATE"
+	row, err := tx.QueryContext(ctx, query, userUUID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	deletion := domain.UserDeletion{}
+
+	if row.Next() {
+		err = row.Scan(&deletion.Id, &deletion.User_id, &deletion.Status, &deletion.Attempts, &deletion.Reason, &deletion.Created_at, &deletion.Updated_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return deletion, nil
+	} else {
+		return deletion, errors.New("user deletion not found")
+	}
+}
+
+func (repository *UserDeletionRepository) FindCompletedByUserIdWithTx(ctx context.Context, tx *sql.Tx, userUUID string) (domain.UserDeletion, error) {
+	query := "SELECT id,user_id,status,attempts,reason,created_at,updated_at FROM user_deletions WHERE user_id=$1 AND status='Completed' ORDER BY updated_at DESC LIMIT 1 FOR UPDATE"
+	row, err := tx.QueryContext(ctx, query, userUUID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	deletion := domain.UserDeletion{}
+
+	if row.Next() {
+		err = row.Scan(&deletion.Id, &deletion.User_id, &deletion.Status, &deletion.Attempts, &deletion.Reason, &deletion.Created_at, &deletion.Updated_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return deletion, nil
+	} else {
+		return deletion, errors.New("user deletion not found")
+	}
+}
+
+func (repository *UserDeletionRepository) FindById(ctx context.Context, sagaID string) (domain.UserDeletion, error) {
+	query := "SELECT id,user_id,status,attempts,reason,created_at,updated_at FROM user_deletions WHERE id=$1"
+	row, err := repository.DB.QueryContext(ctx, query, sagaID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	deletion := domain.UserDeletion{}
+
+	if row.Next() {
+		err = row.Scan(&deletion.Id, &deletion.User_id, &deletion.Status, &deletion.Attempts, &deletion.Reason, &deletion.Created_at, &deletion.Updated_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return deletion, nil
+	} else {
+		return deletion, errors.New("user deletion not found")
+	}
+}
+
+func (repository *UserDeletionRepository) FindStalePending(ctx context.Context, olderThan time.Time) []domain.UserDeletion {
+	query := "SELECT id,user_id,status,attempts,reason,created_at,updated_at FROM user_deletions WHERE status='Pending' AND updated_at < $1"
+	row, err := repository.DB.QueryContext(ctx, query, olderThan)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	deletions := []domain.UserDeletion{}
+
+	for row.Next() {
+		deletion := domain.UserDeletion{}
+		err = row.Scan(&deletion.Id, &deletion.User_id, &deletion.Status, &deletion.Attempts, &deletion.Reason, &deletion.Created_at, &deletion.Updated_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		deletions = append(deletions, deletion)
+	}
+
+	return deletions
+}
+
+func (repository *UserDeletionRepository) IncrementAttemptWithTx(ctx context.Context, tx *sql.Tx, sagaID string, updatedAt *time.Time) {
+	query := "UPDATE user_deletions SET attempts = attempts + 1, updated_at = $1 WHERE id = $2"
+	_, err := tx.ExecContext(ctx, query, updatedAt, sagaID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *UserDeletionRepository) IncrementAttempt(ctx context.Context, sagaID string, updatedAt *time.Time) {
+	query := "UPDATE user_deletions SET attempts = attempts + 1, updated_at = $1 WHERE id = $2"
+	_, err := repository.DB.ExecContext(ctx, query, updatedAt, sagaID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *UserDeletionRepository) UpdateStatusWithTx(ctx context.Context, tx *sql.Tx, sagaID string, status string, reason string, updatedAt *time.Time) {
+	query := "UPDATE user_deletions SET status = $1, reason = $2, updated_at = $3 WHERE id = $4"
+	_, err := tx.ExecContext(ctx, query, status, reason, updatedAt, sagaID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (repository *UserDeletionRepository) UpdateStatus(ctx context.Context, sagaID string, status string, reason string, updatedAt *time.Time) {
+	query := "UPDATE user_deletions SET status = $1, reason = $2, updated_at = $3 WHERE id = $4"
+	_, err := repository.DB.ExecContext(ctx, query, status, reason, updatedAt, sagaID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
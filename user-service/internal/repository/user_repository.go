@@ -8,6 +8,7 @@ import (
 	"github.com/rs/zerolog"
 	"gocdc/internal/model/domain"
 	"gocdc/internal/model/web/user"
+	"time"
 )
 
 type UserRepository struct {
@@ -69,7 +70,7 @@ func (repository *UserRepository) FindLatestRefreshToken(ctx context.Context, tx
 }
 
 func (repository *UserRepository) LoginWithTx(ctx context.Context, tx *sql.Tx, email string) (domain.User, error) {
-	query := "SELECT id,email,password FROM users WHERE email=$1"
+	query := "SELECT id,email,password FROM users WHERE email=$1 AND deleted_at IS NULL"
 	row, err := tx.QueryContext(ctx, query, email)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
@@ -126,7 +127,7 @@ func (repository *UserRepository) Update(ctx context.Context, user domain.User)
 	args = append(args, user.Updated_at)
 	argCounter++
 
-	query += fmt.Sprintf("WHERE id = $%d", argCounter)
+	query += fmt.Sprintf("WHERE id = $%d AND deleted_at IS NULL", argCounter)
 	args = append(args, user.Id)
 
 	_, err := repository.DB.ExecContext(ctx, query, args...)
@@ -136,17 +137,72 @@ func (repository *UserRepository) Update(ctx context.Context, user domain.User)
 	}
 }
 
-func (repository *UserRepository) Delete(ctx context.Context, userUUID string) {
-	query := "DELETE FROM users WHERE id=$1"
-	_, err := repository.DB.ExecContext(ctx, query, userUUID)
+// SoftDeleteWithTx only marks the user as deleted, the row is kept for
+// restore until the purge removes it.
+func (repository *UserRepository) SoftDeleteWithTx(ctx context.Context, tx *sql.Tx, userUUID string, deletedAt *time.Time) {
+	query := "UPDATE users SET deleted_at = $1, updated_at = $1 WHERE id = $2 AND deleted_at IS NULL"
+	_, err := tx.ExecContext(ctx, query, deletedAt, userUUID)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
 		repository.Log.Panic().Err(err).Msg(respErr.Error())
 	}
 }
 
+// FindDeletedByEmailWithTx locks the most recently deleted account that used
+// email.
+func (repository *UserRepository) FindDeletedByEmailWithTx(ctx context.Context, tx *sql.Tx, email string) (domain.User, error) {
+	query := "SELECT id,name,email,password,deleted_at FROM users WHERE email=$1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT 1 FOR UPDATE"
+	row, err := tx.QueryContext(ctx, query, email)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	user := domain.User{}
+	if row.Next() {
+		err = row.Scan(&user.Id, &user.Name, &user.Email, &user.Password, &user.Deleted_at)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+		return user, nil
+	} else {
+		return user, errors.New("wrong email or password")
+	}
+}
+
+func (repository *UserRepository) RestoreWithTx(ctx context.Context, tx *sql.Tx, userUUID string, updatedAt *time.Time) {
+	query := "UPDATE users SET deleted_at = NULL, updated_at = $1 WHERE id = $2"
+	_, err := tx.ExecContext(ctx, query, updatedAt, userUUID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+// Purge hard deletes users deleted before deletedBefore, their refresh tokens
+// go with them.
+func (repository *UserRepository) Purge(ctx context.Context, deletedBefore *time.Time) int64 {
+	query := "DELETE FROM users WHERE deleted_at < $1"
+	result, err := repository.DB.ExecContext(ctx, query, deletedBefore)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		respErr := errors.New("failed to get affected rows")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	return purged
+}
+
 func (repository *UserRepository) FindUserInfo(ctx context.Context, userUUID string) (user.UserResponse, error) {
-	query := "SELECT id,name,email,address,phonenumber,created_at,updated_at FROM users WHERE id=$1"
+	query := "SELECT id,name,email,address,phonenumber,created_at,updated_at FROM users WHERE id=$1 AND deleted_at IS NULL"
 	row, err := repository.DB.QueryContext(ctx, query, userUUID)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
@@ -171,7 +227,7 @@ func (repository *UserRepository) FindUserInfo(ctx context.Context, userUUID str
 }
 
 func (repository *UserRepository) FindUserInfoWithTx(ctx context.Context, tx *sql.Tx, userUUID string) (user.UserResponse, error) {
-	query := "SELECT name,address FROM users WHERE id=$1"
+	query := "SELECT name,address FROM users WHERE id=$1 AND deleted_at IS NULL"
 	row, err := tx.QueryContext(ctx, query, userUUID)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
@@ -196,7 +252,7 @@ func (repository *UserRepository) FindUserInfoWithTx(ctx context.Context, tx *sq
 }
 
 func (repository *UserRepository) FindUserNameAddress(ctx context.Context, userUUID string) (user.UserNameAddressResponse, error) {
-	query := "SELECT name,address FROM users WHERE id=$1"
+	query := "SELECT name,address FROM users WHERE id=$1 AND deleted_at IS NULL"
 	row, err := repository.DB.QueryContext(ctx, query, userUUID)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
@@ -220,8 +276,33 @@ func (repository *UserRepository) FindUserNameAddress(ctx context.Context, userU
 	}
 }
 
+func (repository *UserRepository) FindSeller(ctx context.Context, userUUID string) (user.SellerResponse, error) {
+	query := "SELECT id,name FROM users WHERE id=$1 AND deleted_at IS NULL"
+	row, err := repository.DB.QueryContext(ctx, query, userUUID)
+	if err != nil {
+		respErr := errors.New("failed to query into database")
+		repository.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer row.Close()
+
+	seller := user.SellerResponse{}
+
+	if row.Next() {
+		err = row.Scan(&seller.Id, &seller.Name)
+		if err != nil {
+			respErr := errors.New("failed to scan query result")
+			repository.Log.Panic().Err(err).Msg(respErr.Error())
+		}
+
+		return seller, nil
+	} else {
+		return seller, errors.New("user not found")
+	}
+}
+
 func (repository *UserRepository) FindUserEmail(ctx context.Context, userUUID string) (*string, error) {
-	query := "SELECT email FROM users WHERE id=$1"
+	query := "SELECT email FROM users WHERE id=$1 AND deleted_at IS NULL"
 	row, err := repository.DB.QueryContext(ctx, query, userUUID)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
@@ -245,7 +326,7 @@ func (repository *UserRepository) FindUserEmail(ctx context.Context, userUUID st
 }
 
 func (repository *UserRepository) CheckUserExistence(ctx context.Context, userUUID string) (string, error) {
-	query := "SELECT name FROM users WHERE id=$1"
+	query := "SELECT name FROM users WHERE id=$1 AND deleted_at IS NULL"
 	row, err := repository.DB.QueryContext(ctx, query, userUUID)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
@@ -262,7 +343,7 @@ func (repository *UserRepository) CheckUserExistence(ctx context.Context, userUU
 }
 
 func (repository *UserRepository) CheckUserExistenceWithTx(ctx context.Context, tx *sql.Tx, userUUID string) error {
-	query := "SELECT name FROM users WHERE id=$1"
+	query := "SELECT name FROM users WHERE id=$1 AND deleted_at IS NULL"
 	row, err := tx.QueryContext(ctx, query, userUUID)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
@@ -279,7 +360,7 @@ func (repository *UserRepository) CheckUserExistenceWithTx(ctx context.Context,
 }
 
 func (repository *UserRepository) CheckCredentialUniqueWithTx(ctx context.Context, tx *sql.Tx, user domain.User) error {
-	query := "SELECT name,email FROM users WHERE name=$1 OR email=$2"
+	query := "SELECT name,email FROM users WHERE (name=$1 OR email=$2) AND deleted_at IS NULL"
 	row, err := tx.QueryContext(ctx, query, user.Name, user.Email)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
@@ -296,7 +377,7 @@ func (repository *UserRepository) CheckCredentialUniqueWithTx(ctx context.Contex
 }
 
 func (repository *UserRepository) FindUserEmailByUUID(ctx context.Context, tx *sql.Tx, userUUID string) (*string, error) {
-	query := "SELECT email FROM users WHERE id=$1"
+	query := "SELECT email FROM users WHERE id=$1 AND deleted_at IS NULL"
 	row, err := tx.QueryContext(ctx, query, userUUID)
 	if err != nil {
 		respErr := errors.New("failed to query into database")
@@ -25,22 +25,24 @@ import (
 )
 
 type UserUsecase struct {
-	UserRepository *repository.UserRepository
-	KafkaWriter    sarama.SyncProducer
-	DB             *sql.DB
-	Validator      *validator.Validate
-	Log            *zerolog.Logger
-	Config         *koanf.Koanf
+	UserRepository         *repository.UserRepository
+	UserDeletionRepository *repository.UserDeletionRepository
+	KafkaWriter            sarama.SyncProducer
+	DB                     *sql.DB
+	Validator              *validator.Validate
+	Log                    *zerolog.Logger
+	Config                 *koanf.Koanf
 }
 
-func NewUserUsecase(userRepository *repository.UserRepository, kafkaWriter sarama.SyncProducer, db *sql.DB, validator *validator.Validate, zerolog *zerolog.Logger, koanf *koanf.Koanf) *UserUsecase {
+func NewUserUsecase(userRepository *repository.UserRepository, userDeletionRepository *repository.UserDeletionRepository, kafkaWriter sarama.SyncProducer, db *sql.DB, validator *validator.Validate, zerolog *zerolog.Logger, koanf *koanf.Koanf) *UserUsecase {
 	return &UserUsecase{
-		UserRepository: userRepository,
-		KafkaWriter:    kafkaWriter,
-		DB:             db,
-		Validator:      validator,
-		Log:            zerolog,
-		Config:         koanf,
+		UserRepository:         userRepository,
+		UserDeletionRepository: userDeletionRepository,
+		KafkaWriter:            kafkaWriter,
+		DB:                     db,
+		Validator:              validator,
+		Log:                    zerolog,
+		Config:                 koanf,
 	}
 }
 
@@ -321,18 +323,340 @@ func (usecase *UserUsecase) Update(ctx context.Context, request user.UserUpdateR
 	return nil
 }
 
-func (usecase *UserUsecase) Delete(ctx context.Context, userUUID string) error {
-	_, err := usecase.UserRepository.CheckUserExistence(ctx, userUUID)
+// Delete starts the user deletion saga. The user is only marked deleted once
+// product-service reports that the seller's products have been archived. The
+// saga is published once it is committed, a publish that fails is repeated by
+// RunDeletionRetrier.
+func (usecase *UserUsecase) Delete(ctx context.Context, userUUID string) (user.UserDeletionResponse, error) {
+	deletion, err := usecase.startDeletion(ctx, userUUID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return user.UserDeletionResponse{}, err
+	}
+
+	usecase.PublishUserDeleted(deletion, "user.deleted")
+
+	deletionResponse := user.UserDeletionResponse{
+		Saga_id:    deletion.Id,
+		Status:     deletion.Status,
+		Attempts:   deletion.Attempts,
+		Reason:     deletion.Reason,
+		Created_at: deletion.Created_at,
+		Updated_at: deletion.Updated_at,
+	}
+
+	return deletionResponse, nil
+}
+
+func (usecase *UserUsecase) startDeletion(ctx context.Context, userUUID string) (domain.UserDeletion, error) {
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	err = usecase.UserRepository.CheckUserExistenceWithTx(ctx, tx, userUUID)
+	if err != nil {
+		return domain.UserDeletion{}, err
+	}
+
+	now := time.Now()
+
+	deletion, err := usecase.UserDeletionRepository.FindPendingByUserIdWithTx(ctx, tx, userUUID)
+	if err != nil {
+		deletion = domain.UserDeletion{
+			Id:         googleuuid.New().String(),
+			User_id:    userUUID,
+			Status:     "Pending",
+			Attempts:   1,
+			Created_at: &now,
+			Updated_at: &now,
+		}
+
+		usecase.UserDeletionRepository.CreateWithTx(ctx, tx, deletion)
+	} else {
+		// deletion already in flight, publish the same saga again so the request is safe to repeat
+		usecase.UserDeletionRepository.IncrementAttemptWithTx(ctx, tx, deletion.Id, &now)
+		deletion.Attempts++
+		deletion.Updated_at = &now
+	}
+
+	return deletion, nil
+}
+
+func (usecase *UserUsecase) PublishUserDeleted(deletion domain.UserDeletion, topic string) {
+	now := time.Now()
+
+	userDeletedEvent := user.UserDeletedEvent{
+		Saga_id:    deletion.Id,
+		Id:         deletion.User_id,
+		Created_at: &now,
+	}
+
+	messageJSON, err := json.Marshal(userDeletedEvent)
+	if err != nil {
+		respErr := errors.New("failed to marshal a json")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	_, _, err = usecase.KafkaWriter.SendMessage(&sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.StringEncoder(deletion.User_id),
+		Value: sarama.ByteEncoder(messageJSON),
+	})
+
+	if err != nil {
+		respErr := errors.New("failed to produce an event to kafka broker")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+}
+
+func (usecase *UserUsecase) HandleDeletionResult(ctx context.Context, event user.UserDeletionResultEvent) error {
+	deletion, err := usecase.UserDeletionRepository.FindById(ctx, event.Saga_id)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return err
+	}
+
+	// results can be delivered more than once, only a pending saga moves forward
+	if deletion.Status != "Pending" {
+		usecase.Log.Debug().Msg("User deletion " + deletion.Id + " already " + deletion.Status)
+		return nil
+	}
+
+	now := time.Now()
+
+	if event.Status != "Completed" {
+		usecase.UserDeletionRepository.UpdateStatus(ctx, deletion.Id, "Failed", event.Reason, &now)
+		usecase.Log.Warn().Msg("User deletion " + deletion.Id + " failed: " + event.Reason)
+		return nil
+	}
+
+	err = usecase.completeDeletion(ctx, deletion)
+	if err != nil {
+		usecase.Log.Error().Err(err).Msg("failed to delete user, compensating product archival")
+		usecase.PublishUserDeleted(deletion, "user.deletion.compensate")
+		usecase.UserDeletionRepository.UpdateStatus(ctx, deletion.Id, "Compensated", err.Error(), &now)
+		return err
+	}
+
+	return nil
+}
+
+func (usecase *UserUsecase) completeDeletion(ctx context.Context, deletion domain.UserDeletion) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("%v", recovered)
+		}
+	}()
+
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	now := time.Now()
+
+	usecase.UserRepository.SoftDeleteWithTx(ctx, tx, deletion.User_id, &now)
+	usecase.UserRepository.UpdateRefreshToken(ctx, tx, "Revoke", deletion.User_id)
+	usecase.UserDeletionRepository.UpdateStatusWithTx(ctx, tx, deletion.Id, "Completed", "", &now)
+
+	return nil
+}
+
+// Restore brings back an account deleted within USER_RESTORE_GRACE_PERIOD.
+// The completed deletion saga is compensated, so product-service puts the
+// seller's archived products back.
+func (usecase *UserUsecase) Restore(ctx context.Context, request user.UserRestoreRequest) error {
+	err := usecase.Validator.Struct(request)
+	if err != nil {
+		respErr := errors.New("invalid request body")
+		usecase.Log.Warn().Err(respErr).Msg(err.Error())
+		return respErr
+	}
+
+	deletion, err := usecase.restore(ctx, request)
 	if err != nil {
 		usecase.Log.Warn().Msg(err.Error())
 		return err
 	}
 
-	usecase.UserRepository.Delete(ctx, userUUID)
+	if deletion.Id != "" {
+		usecase.PublishUserDeleted(deletion, "user.deletion.compensate")
+	}
 
 	return nil
 }
 
+func (usecase *UserUsecase) restore(ctx context.Context, request user.UserRestoreRequest) (domain.UserDeletion, error) {
+	gracePeriod := usecase.Config.Duration("USER_RESTORE_GRACE_PERIOD")
+	if gracePeriod <= 0 {
+		gracePeriod = 30 * 24 * time.Hour
+	}
+
+	tx, err := usecase.DB.Begin()
+	if err != nil {
+		respErr := errors.New("failed to start transaction")
+		usecase.Log.Panic().Err(err).Msg(respErr.Error())
+	}
+
+	defer helper.CommitOrRollback(tx)
+
+	deletedUser, err := usecase.UserRepository.FindDeletedByEmailWithTx(ctx, tx, request.Email)
+	if err != nil {
+		return domain.UserDeletion{}, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(deletedUser.Password), []byte(request.Password))
+	if err != nil {
+		return domain.UserDeletion{}, errors.New("wrong email or password")
+	}
+
+	now := time.Now()
+
+	if deletedUser.Deleted_at.Before(now.Add(-gracePeriod)) {
+		return domain.UserDeletion{}, errors.New("restore period has ended")
+	}
+
+	// the name or email may have been taken by a new account since
+	err = usecase.UserRepository.CheckCredentialUniqueWithTx(ctx, tx, deletedUser)
+	if err != nil {
+		return domain.UserDeletion{}, err
+	}
+
+	usecase.UserRepository.RestoreWithTx(ctx, tx, deletedUser.Id, &now)
+
+	deletion, err := usecase.UserDeletionRepository.FindCompletedByUserIdWithTx(ctx, tx, deletedUser.Id)
+	if err != nil {
+		return domain.UserDeletion{}, nil
+	}
+
+	usecase.UserDeletionRepository.UpdateStatusWithTx(ctx, tx, deletion.Id, "Restored", "", &now)
+
+	return deletion, nil
+}
+
+// RunPurge hard deletes users that were soft deleted longer than
+// USER_PURGE_RETENTION ago. The retention never ends before the restore grace
+// period does.
+func (usecase *UserUsecase) RunPurge(ctx context.Context) {
+	interval := usecase.Config.Duration("USER_PURGE_INTERVAL")
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	gracePeriod := usecase.Config.Duration("USER_RESTORE_GRACE_PERIOD")
+	if gracePeriod <= 0 {
+		gracePeriod = 30 * 24 * time.Hour
+	}
+
+	retention := usecase.Config.Duration("USER_PURGE_RETENTION")
+	if retention < gracePeriod {
+		retention = gracePeriod
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					usecase.Log.Error().Msg(fmt.Sprintf("user purge failed: %v", err))
+				}
+			}()
+
+			deletedBefore := time.Now().Add(-retention)
+			purged := usecase.UserRepository.Purge(ctx, &deletedBefore)
+			if purged > 0 {
+				usecase.Log.Info().Msg(fmt.Sprintf("purged %d deleted users", purged))
+			}
+		}()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RetryPendingDeletions republishes sagas that have not received a result in
+// time. product-service treats a repeated saga id as a no-op. A saga that
+// reaches the retry limit is compensated, product-service may have archived
+// the seller's products without its result ever arriving.
+func (usecase *UserUsecase) RetryPendingDeletions(ctx context.Context) {
+	retryInterval := usecase.Config.Duration("USER_DELETION_RETRY_INTERVAL")
+	maxAttempts := usecase.Config.Int("USER_DELETION_MAX_ATTEMPTS")
+
+	now := time.Now()
+	deletions := usecase.UserDeletionRepository.FindStalePending(ctx, now.Add(-retryInterval))
+
+	for _, deletion := range deletions {
+		if deletion.Attempts >= maxAttempts {
+			usecase.PublishUserDeleted(deletion, "user.deletion.compensate")
+			usecase.UserDeletionRepository.UpdateStatus(ctx, deletion.Id, "Failed", "retry limit reached", &now)
+			usecase.Log.Warn().Msg("User deletion " + deletion.Id + " reached retry limit")
+			continue
+		}
+
+		usecase.UserDeletionRepository.IncrementAttempt(ctx, deletion.Id, &now)
+		usecase.PublishUserDeleted(deletion, "user.deleted")
+	}
+}
+
+func (usecase *UserUsecase) RunDeletionRetrier(ctx context.Context) {
+	ticker := time.NewTicker(usecase.Config.Duration("USER_DELETION_RETRY_INTERVAL"))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			func() {
+				defer func() {
+					if err := recover(); err != nil {
+						usecase.Log.Error().Msg(fmt.Sprintf("user deletion retry failed: %v", err))
+					}
+				}()
+
+				usecase.RetryPendingDeletions(ctx)
+			}()
+		}
+	}
+}
+
+// FindDeletionStatus reads a deletion saga of userUUID. Sagas of other users
+// are reported as not found.
+func (usecase *UserUsecase) FindDeletionStatus(ctx context.Context, userUUID string, sagaID string) (user.UserDeletionResponse, error) {
+	deletion, err := usecase.UserDeletionRepository.FindById(ctx, sagaID)
+	if err == nil && deletion.User_id != userUUID {
+		err = errors.New("user deletion not found")
+	}
+
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return user.UserDeletionResponse{}, err
+	}
+
+	deletionResponse := user.UserDeletionResponse{
+		Saga_id:    deletion.Id,
+		Status:     deletion.Status,
+		Attempts:   deletion.Attempts,
+		Reason:     deletion.Reason,
+		Created_at: deletion.Created_at,
+		Updated_at: deletion.Updated_at,
+	}
+
+	return deletionResponse, nil
+}
+
 func (usecase *UserUsecase) TokenRenewal(ctx context.Context, request user.RenewalTokenRequest) (web.TokenResponse, error) {
 	err := usecase.Validator.Struct(request)
 	if err != nil {
@@ -486,6 +810,18 @@ func (usecase *UserUsecase) FindUserNameAddress(ctx context.Context, userUUID st
 	return user, nil
 }
 
+// FindSeller returns the public name of a user, for product-service and
+// anyone else listing their products.
+func (usecase *UserUsecase) FindSeller(ctx context.Context, userUUID string) (user.SellerResponse, error) {
+	seller, err := usecase.UserRepository.FindSeller(ctx, userUUID)
+	if err != nil {
+		usecase.Log.Warn().Msg(err.Error())
+		return seller, err
+	}
+
+	return seller, nil
+}
+
 func (usecase *UserUsecase) FindUserEmail(ctx context.Context, userUUID string) (string, error) {
 	user, err := usecase.UserRepository.FindUserEmail(ctx, userUUID)
 	if err != nil {